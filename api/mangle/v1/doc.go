@@ -0,0 +1,5 @@
+// Package manglev1 holds the gRPC API of mangle-service, generated from
+// mangle.proto. Regenerate it after changing the proto file with go generate.
+package manglev1
+
+//go:generate protoc -I ../.. --go_out=../.. --go_opt=paths=source_relative --go-grpc_out=../.. --go-grpc_opt=paths=source_relative mangle/v1/mangle.proto
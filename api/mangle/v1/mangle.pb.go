@@ -0,0 +1,710 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: mangle/v1/mangle.proto
+
+package manglev1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type QueryRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The Mangle program. Its last clause is the query atom; the clauses
+	// before it are rules.
+	Query string `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
+	// Restricts the log facts to a time window, if set.
+	TimeRange *TimeRange `protobuf:"bytes,2,opt,name=time_range,json=timeRange,proto3" json:"time_range,omitempty"`
+	// The log sources to read. If empty, every source the caller may use is read.
+	Sources []string `protobuf:"bytes,3,rep,name=sources,proto3" json:"sources,omitempty"`
+	// Identifies a saved or recurring query in metrics. It does not change
+	// what the query does.
+	Name          string `protobuf:"bytes,4,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueryRequest) Reset() {
+	*x = QueryRequest{}
+	mi := &file_mangle_v1_mangle_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryRequest) ProtoMessage() {}
+
+func (x *QueryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_mangle_v1_mangle_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryRequest.ProtoReflect.Descriptor instead.
+func (*QueryRequest) Descriptor() ([]byte, []int) {
+	return file_mangle_v1_mangle_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *QueryRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *QueryRequest) GetTimeRange() *TimeRange {
+	if x != nil {
+		return x.TimeRange
+	}
+	return nil
+}
+
+func (x *QueryRequest) GetSources() []string {
+	if x != nil {
+		return x.Sources
+	}
+	return nil
+}
+
+func (x *QueryRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+// TimeRange is the half-open window [from, to). A missing bound is open.
+type TimeRange struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	From          *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"`
+	To            *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=to,proto3" json:"to,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TimeRange) Reset() {
+	*x = TimeRange{}
+	mi := &file_mangle_v1_mangle_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TimeRange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TimeRange) ProtoMessage() {}
+
+func (x *TimeRange) ProtoReflect() protoreflect.Message {
+	mi := &file_mangle_v1_mangle_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TimeRange.ProtoReflect.Descriptor instead.
+func (*TimeRange) Descriptor() ([]byte, []int) {
+	return file_mangle_v1_mangle_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *TimeRange) GetFrom() *timestamppb.Timestamp {
+	if x != nil {
+		return x.From
+	}
+	return nil
+}
+
+func (x *TimeRange) GetTo() *timestamppb.Timestamp {
+	if x != nil {
+		return x.To
+	}
+	return nil
+}
+
+type QueryResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The query's variables in the order they appear in the query atom.
+	Columns []string `protobuf:"bytes,1,rep,name=columns,proto3" json:"columns,omitempty"`
+	// The values bound to the query's variables, one object per result.
+	Results       []*structpb.Struct `protobuf:"bytes,2,rep,name=results,proto3" json:"results,omitempty"`
+	Count         int64              `protobuf:"varint,3,opt,name=count,proto3" json:"count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueryResponse) Reset() {
+	*x = QueryResponse{}
+	mi := &file_mangle_v1_mangle_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryResponse) ProtoMessage() {}
+
+func (x *QueryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_mangle_v1_mangle_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryResponse.ProtoReflect.Descriptor instead.
+func (*QueryResponse) Descriptor() ([]byte, []int) {
+	return file_mangle_v1_mangle_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *QueryResponse) GetColumns() []string {
+	if x != nil {
+		return x.Columns
+	}
+	return nil
+}
+
+func (x *QueryResponse) GetResults() []*structpb.Struct {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+func (x *QueryResponse) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+type StreamQueryResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Record:
+	//
+	//	*StreamQueryResponse_Result
+	//	*StreamQueryResponse_Summary
+	Record        isStreamQueryResponse_Record `protobuf_oneof:"record"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamQueryResponse) Reset() {
+	*x = StreamQueryResponse{}
+	mi := &file_mangle_v1_mangle_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamQueryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamQueryResponse) ProtoMessage() {}
+
+func (x *StreamQueryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_mangle_v1_mangle_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamQueryResponse.ProtoReflect.Descriptor instead.
+func (*StreamQueryResponse) Descriptor() ([]byte, []int) {
+	return file_mangle_v1_mangle_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *StreamQueryResponse) GetRecord() isStreamQueryResponse_Record {
+	if x != nil {
+		return x.Record
+	}
+	return nil
+}
+
+func (x *StreamQueryResponse) GetResult() *structpb.Struct {
+	if x != nil {
+		if x, ok := x.Record.(*StreamQueryResponse_Result); ok {
+			return x.Result
+		}
+	}
+	return nil
+}
+
+func (x *StreamQueryResponse) GetSummary() *QuerySummary {
+	if x != nil {
+		if x, ok := x.Record.(*StreamQueryResponse_Summary); ok {
+			return x.Summary
+		}
+	}
+	return nil
+}
+
+type isStreamQueryResponse_Record interface {
+	isStreamQueryResponse_Record()
+}
+
+type StreamQueryResponse_Result struct {
+	Result *structpb.Struct `protobuf:"bytes,1,opt,name=result,proto3,oneof"`
+}
+
+type StreamQueryResponse_Summary struct {
+	Summary *QuerySummary `protobuf:"bytes,2,opt,name=summary,proto3,oneof"`
+}
+
+func (*StreamQueryResponse_Result) isStreamQueryResponse_Record() {}
+
+func (*StreamQueryResponse_Summary) isStreamQueryResponse_Record() {}
+
+type QuerySummary struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Columns       []string               `protobuf:"bytes,1,rep,name=columns,proto3" json:"columns,omitempty"`
+	Count         int64                  `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	DurationMs    int64                  `protobuf:"varint,3,opt,name=duration_ms,json=durationMs,proto3" json:"duration_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QuerySummary) Reset() {
+	*x = QuerySummary{}
+	mi := &file_mangle_v1_mangle_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QuerySummary) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QuerySummary) ProtoMessage() {}
+
+func (x *QuerySummary) ProtoReflect() protoreflect.Message {
+	mi := &file_mangle_v1_mangle_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QuerySummary.ProtoReflect.Descriptor instead.
+func (*QuerySummary) Descriptor() ([]byte, []int) {
+	return file_mangle_v1_mangle_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *QuerySummary) GetColumns() []string {
+	if x != nil {
+		return x.Columns
+	}
+	return nil
+}
+
+func (x *QuerySummary) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *QuerySummary) GetDurationMs() int64 {
+	if x != nil {
+		return x.DurationMs
+	}
+	return 0
+}
+
+type ValidateQueryResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Valid bool                   `protobuf:"varint,1,opt,name=valid,proto3" json:"valid,omitempty"`
+	// The columns the query would return, if it is valid.
+	Columns []string `protobuf:"bytes,2,rep,name=columns,proto3" json:"columns,omitempty"`
+	// The log sources the query would read, if it is valid.
+	Sources []string `protobuf:"bytes,3,rep,name=sources,proto3" json:"sources,omitempty"`
+	// Why the query is invalid, with the same codes as failed queries.
+	ErrorCode     string `protobuf:"bytes,4,opt,name=error_code,json=errorCode,proto3" json:"error_code,omitempty"`
+	ErrorMessage  string `protobuf:"bytes,5,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ValidateQueryResponse) Reset() {
+	*x = ValidateQueryResponse{}
+	mi := &file_mangle_v1_mangle_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ValidateQueryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ValidateQueryResponse) ProtoMessage() {}
+
+func (x *ValidateQueryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_mangle_v1_mangle_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ValidateQueryResponse.ProtoReflect.Descriptor instead.
+func (*ValidateQueryResponse) Descriptor() ([]byte, []int) {
+	return file_mangle_v1_mangle_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ValidateQueryResponse) GetValid() bool {
+	if x != nil {
+		return x.Valid
+	}
+	return false
+}
+
+func (x *ValidateQueryResponse) GetColumns() []string {
+	if x != nil {
+		return x.Columns
+	}
+	return nil
+}
+
+func (x *ValidateQueryResponse) GetSources() []string {
+	if x != nil {
+		return x.Sources
+	}
+	return nil
+}
+
+func (x *ValidateQueryResponse) GetErrorCode() string {
+	if x != nil {
+		return x.ErrorCode
+	}
+	return ""
+}
+
+func (x *ValidateQueryResponse) GetErrorMessage() string {
+	if x != nil {
+		return x.ErrorMessage
+	}
+	return ""
+}
+
+type ListRelationshipsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListRelationshipsRequest) Reset() {
+	*x = ListRelationshipsRequest{}
+	mi := &file_mangle_v1_mangle_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListRelationshipsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListRelationshipsRequest) ProtoMessage() {}
+
+func (x *ListRelationshipsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_mangle_v1_mangle_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListRelationshipsRequest.ProtoReflect.Descriptor instead.
+func (*ListRelationshipsRequest) Descriptor() ([]byte, []int) {
+	return file_mangle_v1_mangle_proto_rawDescGZIP(), []int{6}
+}
+
+type ListRelationshipsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Relationships []*ServiceRelationship `protobuf:"bytes,1,rep,name=relationships,proto3" json:"relationships,omitempty"`
+	// Incremented on every change to the relationships.
+	Version       int64 `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListRelationshipsResponse) Reset() {
+	*x = ListRelationshipsResponse{}
+	mi := &file_mangle_v1_mangle_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListRelationshipsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListRelationshipsResponse) ProtoMessage() {}
+
+func (x *ListRelationshipsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_mangle_v1_mangle_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListRelationshipsResponse.ProtoReflect.Descriptor instead.
+func (*ListRelationshipsResponse) Descriptor() ([]byte, []int) {
+	return file_mangle_v1_mangle_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ListRelationshipsResponse) GetRelationships() []*ServiceRelationship {
+	if x != nil {
+		return x.Relationships
+	}
+	return nil
+}
+
+func (x *ListRelationshipsResponse) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type ServiceRelationship struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Service   string                 `protobuf:"bytes,1,opt,name=service,proto3" json:"service,omitempty"`
+	DependsOn []string               `protobuf:"bytes,2,rep,name=depends_on,json=dependsOn,proto3" json:"depends_on,omitempty"`
+	// One of critical, high, medium and low, or empty if unknown.
+	Criticality   string `protobuf:"bytes,3,opt,name=criticality,proto3" json:"criticality,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ServiceRelationship) Reset() {
+	*x = ServiceRelationship{}
+	mi := &file_mangle_v1_mangle_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ServiceRelationship) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ServiceRelationship) ProtoMessage() {}
+
+func (x *ServiceRelationship) ProtoReflect() protoreflect.Message {
+	mi := &file_mangle_v1_mangle_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ServiceRelationship.ProtoReflect.Descriptor instead.
+func (*ServiceRelationship) Descriptor() ([]byte, []int) {
+	return file_mangle_v1_mangle_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ServiceRelationship) GetService() string {
+	if x != nil {
+		return x.Service
+	}
+	return ""
+}
+
+func (x *ServiceRelationship) GetDependsOn() []string {
+	if x != nil {
+		return x.DependsOn
+	}
+	return nil
+}
+
+func (x *ServiceRelationship) GetCriticality() string {
+	if x != nil {
+		return x.Criticality
+	}
+	return ""
+}
+
+var File_mangle_v1_mangle_proto protoreflect.FileDescriptor
+
+const file_mangle_v1_mangle_proto_rawDesc = "" +
+	"\n" +
+	"\x16mangle/v1/mangle.proto\x12\tmangle.v1\x1a\x1cgoogle/protobuf/struct.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\x87\x01\n" +
+	"\fQueryRequest\x12\x14\n" +
+	"\x05query\x18\x01 \x01(\tR\x05query\x123\n" +
+	"\n" +
+	"time_range\x18\x02 \x01(\v2\x14.mangle.v1.TimeRangeR\ttimeRange\x12\x18\n" +
+	"\asources\x18\x03 \x03(\tR\asources\x12\x12\n" +
+	"\x04name\x18\x04 \x01(\tR\x04name\"g\n" +
+	"\tTimeRange\x12.\n" +
+	"\x04from\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x04from\x12*\n" +
+	"\x02to\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x02to\"r\n" +
+	"\rQueryResponse\x12\x18\n" +
+	"\acolumns\x18\x01 \x03(\tR\acolumns\x121\n" +
+	"\aresults\x18\x02 \x03(\v2\x17.google.protobuf.StructR\aresults\x12\x14\n" +
+	"\x05count\x18\x03 \x01(\x03R\x05count\"\x87\x01\n" +
+	"\x13StreamQueryResponse\x121\n" +
+	"\x06result\x18\x01 \x01(\v2\x17.google.protobuf.StructH\x00R\x06result\x123\n" +
+	"\asummary\x18\x02 \x01(\v2\x17.mangle.v1.QuerySummaryH\x00R\asummaryB\b\n" +
+	"\x06record\"_\n" +
+	"\fQuerySummary\x12\x18\n" +
+	"\acolumns\x18\x01 \x03(\tR\acolumns\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x03R\x05count\x12\x1f\n" +
+	"\vduration_ms\x18\x03 \x01(\x03R\n" +
+	"durationMs\"\xa5\x01\n" +
+	"\x15ValidateQueryResponse\x12\x14\n" +
+	"\x05valid\x18\x01 \x01(\bR\x05valid\x12\x18\n" +
+	"\acolumns\x18\x02 \x03(\tR\acolumns\x12\x18\n" +
+	"\asources\x18\x03 \x03(\tR\asources\x12\x1d\n" +
+	"\n" +
+	"error_code\x18\x04 \x01(\tR\terrorCode\x12#\n" +
+	"\rerror_message\x18\x05 \x01(\tR\ferrorMessage\"\x1a\n" +
+	"\x18ListRelationshipsRequest\"{\n" +
+	"\x19ListRelationshipsResponse\x12D\n" +
+	"\rrelationships\x18\x01 \x03(\v2\x1e.mangle.v1.ServiceRelationshipR\rrelationships\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\x03R\aversion\"p\n" +
+	"\x13ServiceRelationship\x12\x18\n" +
+	"\aservice\x18\x01 \x01(\tR\aservice\x12\x1d\n" +
+	"\n" +
+	"depends_on\x18\x02 \x03(\tR\tdependsOn\x12 \n" +
+	"\vcriticality\x18\x03 \x01(\tR\vcriticality2\xc8\x02\n" +
+	"\rMangleService\x12A\n" +
+	"\fExecuteQuery\x12\x17.mangle.v1.QueryRequest\x1a\x18.mangle.v1.QueryResponse\x12H\n" +
+	"\vStreamQuery\x12\x17.mangle.v1.QueryRequest\x1a\x1e.mangle.v1.StreamQueryResponse0\x01\x12J\n" +
+	"\rValidateQuery\x12\x17.mangle.v1.QueryRequest\x1a .mangle.v1.ValidateQueryResponse\x12^\n" +
+	"\x11ListRelationships\x12#.mangle.v1.ListRelationshipsRequest\x1a$.mangle.v1.ListRelationshipsResponseB'Z%mangle-service/api/mangle/v1;manglev1b\x06proto3"
+
+var (
+	file_mangle_v1_mangle_proto_rawDescOnce sync.Once
+	file_mangle_v1_mangle_proto_rawDescData []byte
+)
+
+func file_mangle_v1_mangle_proto_rawDescGZIP() []byte {
+	file_mangle_v1_mangle_proto_rawDescOnce.Do(func() {
+		file_mangle_v1_mangle_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_mangle_v1_mangle_proto_rawDesc), len(file_mangle_v1_mangle_proto_rawDesc)))
+	})
+	return file_mangle_v1_mangle_proto_rawDescData
+}
+
+var file_mangle_v1_mangle_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_mangle_v1_mangle_proto_goTypes = []any{
+	(*QueryRequest)(nil),              // 0: mangle.v1.QueryRequest
+	(*TimeRange)(nil),                 // 1: mangle.v1.TimeRange
+	(*QueryResponse)(nil),             // 2: mangle.v1.QueryResponse
+	(*StreamQueryResponse)(nil),       // 3: mangle.v1.StreamQueryResponse
+	(*QuerySummary)(nil),              // 4: mangle.v1.QuerySummary
+	(*ValidateQueryResponse)(nil),     // 5: mangle.v1.ValidateQueryResponse
+	(*ListRelationshipsRequest)(nil),  // 6: mangle.v1.ListRelationshipsRequest
+	(*ListRelationshipsResponse)(nil), // 7: mangle.v1.ListRelationshipsResponse
+	(*ServiceRelationship)(nil),       // 8: mangle.v1.ServiceRelationship
+	(*timestamppb.Timestamp)(nil),     // 9: google.protobuf.Timestamp
+	(*structpb.Struct)(nil),           // 10: google.protobuf.Struct
+}
+var file_mangle_v1_mangle_proto_depIdxs = []int32{
+	1,  // 0: mangle.v1.QueryRequest.time_range:type_name -> mangle.v1.TimeRange
+	9,  // 1: mangle.v1.TimeRange.from:type_name -> google.protobuf.Timestamp
+	9,  // 2: mangle.v1.TimeRange.to:type_name -> google.protobuf.Timestamp
+	10, // 3: mangle.v1.QueryResponse.results:type_name -> google.protobuf.Struct
+	10, // 4: mangle.v1.StreamQueryResponse.result:type_name -> google.protobuf.Struct
+	4,  // 5: mangle.v1.StreamQueryResponse.summary:type_name -> mangle.v1.QuerySummary
+	8,  // 6: mangle.v1.ListRelationshipsResponse.relationships:type_name -> mangle.v1.ServiceRelationship
+	0,  // 7: mangle.v1.MangleService.ExecuteQuery:input_type -> mangle.v1.QueryRequest
+	0,  // 8: mangle.v1.MangleService.StreamQuery:input_type -> mangle.v1.QueryRequest
+	0,  // 9: mangle.v1.MangleService.ValidateQuery:input_type -> mangle.v1.QueryRequest
+	6,  // 10: mangle.v1.MangleService.ListRelationships:input_type -> mangle.v1.ListRelationshipsRequest
+	2,  // 11: mangle.v1.MangleService.ExecuteQuery:output_type -> mangle.v1.QueryResponse
+	3,  // 12: mangle.v1.MangleService.StreamQuery:output_type -> mangle.v1.StreamQueryResponse
+	5,  // 13: mangle.v1.MangleService.ValidateQuery:output_type -> mangle.v1.ValidateQueryResponse
+	7,  // 14: mangle.v1.MangleService.ListRelationships:output_type -> mangle.v1.ListRelationshipsResponse
+	11, // [11:15] is the sub-list for method output_type
+	7,  // [7:11] is the sub-list for method input_type
+	7,  // [7:7] is the sub-list for extension type_name
+	7,  // [7:7] is the sub-list for extension extendee
+	0,  // [0:7] is the sub-list for field type_name
+}
+
+func init() { file_mangle_v1_mangle_proto_init() }
+func file_mangle_v1_mangle_proto_init() {
+	if File_mangle_v1_mangle_proto != nil {
+		return
+	}
+	file_mangle_v1_mangle_proto_msgTypes[3].OneofWrappers = []any{
+		(*StreamQueryResponse_Result)(nil),
+		(*StreamQueryResponse_Summary)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_mangle_v1_mangle_proto_rawDesc), len(file_mangle_v1_mangle_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   9,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_mangle_v1_mangle_proto_goTypes,
+		DependencyIndexes: file_mangle_v1_mangle_proto_depIdxs,
+		MessageInfos:      file_mangle_v1_mangle_proto_msgTypes,
+	}.Build()
+	File_mangle_v1_mangle_proto = out.File
+	file_mangle_v1_mangle_proto_goTypes = nil
+	file_mangle_v1_mangle_proto_depIdxs = nil
+}
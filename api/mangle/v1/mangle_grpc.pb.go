@@ -0,0 +1,265 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: mangle/v1/mangle.proto
+
+package manglev1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	MangleService_ExecuteQuery_FullMethodName      = "/mangle.v1.MangleService/ExecuteQuery"
+	MangleService_StreamQuery_FullMethodName       = "/mangle.v1.MangleService/StreamQuery"
+	MangleService_ValidateQuery_FullMethodName     = "/mangle.v1.MangleService/ValidateQuery"
+	MangleService_ListRelationships_FullMethodName = "/mangle.v1.MangleService/ListRelationships"
+)
+
+// MangleServiceClient is the client API for MangleService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// MangleService runs Mangle queries over microservice logs and the service
+// relationship graph.
+//
+// Failed queries return a status whose details include a
+// google.rpc.ErrorInfo with the error code, such as "parse_error", as its
+// reason, and a google.rpc.RetryInfo when the query may be retried later.
+type MangleServiceClient interface {
+	// ExecuteQuery runs a query and returns every result.
+	ExecuteQuery(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (*QueryResponse, error)
+	// StreamQuery runs a query and sends each result as soon as it is found.
+	// The last message is the summary.
+	StreamQuery(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StreamQueryResponse], error)
+	// ValidateQuery checks that a query parses, is permitted and passes
+	// analysis, without fetching logs or evaluating it.
+	ValidateQuery(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (*ValidateQueryResponse, error)
+	// ListRelationships returns every service and its dependencies.
+	ListRelationships(ctx context.Context, in *ListRelationshipsRequest, opts ...grpc.CallOption) (*ListRelationshipsResponse, error)
+}
+
+type mangleServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewMangleServiceClient(cc grpc.ClientConnInterface) MangleServiceClient {
+	return &mangleServiceClient{cc}
+}
+
+func (c *mangleServiceClient) ExecuteQuery(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (*QueryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(QueryResponse)
+	err := c.cc.Invoke(ctx, MangleService_ExecuteQuery_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mangleServiceClient) StreamQuery(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StreamQueryResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &MangleService_ServiceDesc.Streams[0], MangleService_StreamQuery_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[QueryRequest, StreamQueryResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MangleService_StreamQueryClient = grpc.ServerStreamingClient[StreamQueryResponse]
+
+func (c *mangleServiceClient) ValidateQuery(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (*ValidateQueryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ValidateQueryResponse)
+	err := c.cc.Invoke(ctx, MangleService_ValidateQuery_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mangleServiceClient) ListRelationships(ctx context.Context, in *ListRelationshipsRequest, opts ...grpc.CallOption) (*ListRelationshipsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListRelationshipsResponse)
+	err := c.cc.Invoke(ctx, MangleService_ListRelationships_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MangleServiceServer is the server API for MangleService service.
+// All implementations must embed UnimplementedMangleServiceServer
+// for forward compatibility.
+//
+// MangleService runs Mangle queries over microservice logs and the service
+// relationship graph.
+//
+// Failed queries return a status whose details include a
+// google.rpc.ErrorInfo with the error code, such as "parse_error", as its
+// reason, and a google.rpc.RetryInfo when the query may be retried later.
+type MangleServiceServer interface {
+	// ExecuteQuery runs a query and returns every result.
+	ExecuteQuery(context.Context, *QueryRequest) (*QueryResponse, error)
+	// StreamQuery runs a query and sends each result as soon as it is found.
+	// The last message is the summary.
+	StreamQuery(*QueryRequest, grpc.ServerStreamingServer[StreamQueryResponse]) error
+	// ValidateQuery checks that a query parses, is permitted and passes
+	// analysis, without fetching logs or evaluating it.
+	ValidateQuery(context.Context, *QueryRequest) (*ValidateQueryResponse, error)
+	// ListRelationships returns every service and its dependencies.
+	ListRelationships(context.Context, *ListRelationshipsRequest) (*ListRelationshipsResponse, error)
+	mustEmbedUnimplementedMangleServiceServer()
+}
+
+// UnimplementedMangleServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedMangleServiceServer struct{}
+
+func (UnimplementedMangleServiceServer) ExecuteQuery(context.Context, *QueryRequest) (*QueryResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ExecuteQuery not implemented")
+}
+func (UnimplementedMangleServiceServer) StreamQuery(*QueryRequest, grpc.ServerStreamingServer[StreamQueryResponse]) error {
+	return status.Error(codes.Unimplemented, "method StreamQuery not implemented")
+}
+func (UnimplementedMangleServiceServer) ValidateQuery(context.Context, *QueryRequest) (*ValidateQueryResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ValidateQuery not implemented")
+}
+func (UnimplementedMangleServiceServer) ListRelationships(context.Context, *ListRelationshipsRequest) (*ListRelationshipsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListRelationships not implemented")
+}
+func (UnimplementedMangleServiceServer) mustEmbedUnimplementedMangleServiceServer() {}
+func (UnimplementedMangleServiceServer) testEmbeddedByValue()                       {}
+
+// UnsafeMangleServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to MangleServiceServer will
+// result in compilation errors.
+type UnsafeMangleServiceServer interface {
+	mustEmbedUnimplementedMangleServiceServer()
+}
+
+func RegisterMangleServiceServer(s grpc.ServiceRegistrar, srv MangleServiceServer) {
+	// If the following call panics, it indicates UnimplementedMangleServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&MangleService_ServiceDesc, srv)
+}
+
+func _MangleService_ExecuteQuery_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MangleServiceServer).ExecuteQuery(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MangleService_ExecuteQuery_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MangleServiceServer).ExecuteQuery(ctx, req.(*QueryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MangleService_StreamQuery_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(QueryRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MangleServiceServer).StreamQuery(m, &grpc.GenericServerStream[QueryRequest, StreamQueryResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MangleService_StreamQueryServer = grpc.ServerStreamingServer[StreamQueryResponse]
+
+func _MangleService_ValidateQuery_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MangleServiceServer).ValidateQuery(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MangleService_ValidateQuery_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MangleServiceServer).ValidateQuery(ctx, req.(*QueryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MangleService_ListRelationships_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRelationshipsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MangleServiceServer).ListRelationships(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MangleService_ListRelationships_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MangleServiceServer).ListRelationships(ctx, req.(*ListRelationshipsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// MangleService_ServiceDesc is the grpc.ServiceDesc for MangleService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var MangleService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "mangle.v1.MangleService",
+	HandlerType: (*MangleServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ExecuteQuery",
+			Handler:    _MangleService_ExecuteQuery_Handler,
+		},
+		{
+			MethodName: "ValidateQuery",
+			Handler:    _MangleService_ValidateQuery_Handler,
+		},
+		{
+			MethodName: "ListRelationships",
+			Handler:    _MangleService_ListRelationships_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamQuery",
+			Handler:       _MangleService_StreamQuery_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "mangle/v1/mangle.proto",
+}
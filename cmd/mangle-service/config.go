@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"mangle-service/internal/config"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// runConfig implements the "config" command, which prints the effective
+// configuration as YAML with secrets redacted. It accepts the same flags as
+// the server and returns the process exit code.
+func runConfig(args []string, out, errOut io.Writer) int {
+	flags := flag.NewFlagSet("config", flag.ContinueOnError)
+	flags.SetOutput(errOut)
+	cfg, err := config.Load(flags, args, os.LookupEnv)
+	if err != nil {
+		if err != flag.ErrHelp {
+			fmt.Fprintln(errOut, err)
+		}
+		return 2
+	}
+	encoder := yaml.NewEncoder(out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(cfg.Redacted()); err != nil {
+		fmt.Fprintln(errOut, err)
+		return 1
+	}
+	return 0
+}
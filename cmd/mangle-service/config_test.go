@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"mangle-service/internal/config"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func loadConfig(t *testing.T, args []string, env map[string]string) (*config.Config, error) {
+	t.Helper()
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	return config.Load(flags, args, func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	})
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mangle.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+server:
+  port: "7000"
+  grpc_port: "7001"
+logging:
+  level: warn
+sources:
+  elasticsearch:
+    addresses: ["https://es-1:9200", "https://es-2:9200"]
+    index: "logs-*"
+limits:
+  query_timeout: 1m
+  fact_limit: 500
+`), 0o644))
+
+	// 1. Defaults apply where nothing is set.
+	cfg, err := loadConfig(t, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "8080", cfg.Server.Port)
+	assert.Equal(t, "info", cfg.Logging.Level)
+	assert.Equal(t, config.SourceElasticsearch, cfg.Sources.Type)
+	assert.Equal(t, 30*time.Second, cfg.Limits.QueryTimeout)
+
+	// 2. The file overrides the defaults, the environment overrides the file
+	// and flags override the environment.
+	cfg, err = loadConfig(t, []string{"-config-file", path, "-server.port", "9000", "-limits.fact_limit=42"}, map[string]string{
+		"PORT":                  "8000",
+		"QUERY_TIMEOUT":         "5s",
+		"GRPC_PORT":             "",
+		"AUDIT_READER_ROLES":    "auditor, compliance",
+		"ELASTICSEARCH_INDEX":   "app-logs",
+		"ELASTICSEARCH_URL":     "http://ignored:9200",
+		"ELASTICSEARCH_ADDRESS": "http://es-env:9200",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "9000", cfg.Server.Port)
+	assert.Equal(t, "7001", cfg.Server.GRPCPort, "empty variables are ignored")
+	assert.Equal(t, "warn", cfg.Logging.Level)
+	assert.Equal(t, 5*time.Second, cfg.Limits.QueryTimeout)
+	assert.Equal(t, 42, cfg.Limits.FactLimit)
+	assert.Equal(t, []string{"auditor", "compliance"}, cfg.Audit.ReaderRoles)
+	assert.Equal(t, "app-logs", cfg.Sources.Elasticsearch.Index)
+	assert.Equal(t, []string{"http://es-env:9200"}, cfg.Sources.Elasticsearch.Addresses, "the canonical name wins over its alias")
+
+	// 3. The file can also be named in the environment, and the names the
+	// README used to document are still accepted.
+	cfg, err = loadConfig(t, nil, map[string]string{
+		"MANGLE_CONFIG_FILE":        path,
+		"MANGLE_SERVICE_PORT":       "8181",
+		"RELATIONSHIPS_CONFIG_PATH": "config/relationships.yml",
+		"ELASTICSEARCH_URL":         "http://es-url:9200",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "8181", cfg.Server.Port)
+	assert.Equal(t, "config/relationships.yml", cfg.Relationships.ConfigPath)
+	assert.Equal(t, []string{"http://es-url:9200"}, cfg.Sources.Elasticsearch.Addresses)
+	assert.Equal(t, 500, cfg.Limits.FactLimit)
+
+	// 4. The test environment defaults to the mock source and debug logs.
+	cfg, err = loadConfig(t, []string{"-env", "test"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, config.SourceMock, cfg.Sources.Type)
+	assert.Equal(t, "debug", cfg.Logging.Level)
+}
+
+func TestConfigValidation(t *testing.T) {
+	// Every invalid setting is reported at once.
+	_, err := loadConfig(t, []string{"-server.port", "http", "-jobs.workers", "0"}, map[string]string{
+		"LOG_LEVEL":              "verbose",
+		"ELASTICSEARCH_ADDRESS":  "localhost:9200",
+		"ELASTICSEARCH_PASSWORD": "hunter2",
+		"AUDIT_LOG_FORMAT":       "csv",
+		"LOG_FORMAT":             "xml",
+		"JWT_ISSUER":             "https://idp.example.com",
+		"TLS_KEY_FILE":           "tls.key",
+		"TLS_CLIENT_AUTH":        "sometimes",
+		"CLIENT_CERTS_PATH":      "client-certs.yaml",
+	})
+	require.Error(t, err)
+	for _, want := range []string{
+		`server.port: "http" is not a port number`,
+		`logging.level: "verbose" is not a log level`,
+		`sources.elasticsearch.addresses: "localhost:9200" is not an http or https URL`,
+		`sources.elasticsearch.password: requires a username`,
+		`jobs.workers: must be at least 1`,
+		`audit.format: "csv" is not one of`,
+		`logging.format: "xml" is not one of`,
+		`auth: jwt_issuer and jwt_audience require`,
+		`server.tls_key_file: requires server.tls_cert_file`,
+		`server.tls_client_auth: "sometimes" is not one of`,
+		`auth.client_certs_path: requires server.tls_client_ca_file`,
+	} {
+		assert.Contains(t, err.Error(), want)
+	}
+	assert.NotContains(t, err.Error(), "hunter2")
+
+	// Values that do not parse name the variable or flag they came from.
+	_, err = loadConfig(t, []string{"-limits.queue_timeout", "soon"}, map[string]string{"QUERY_MAX_QUEUE": "many"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "QUERY_MAX_QUEUE")
+	assert.Contains(t, err.Error(), "-limits.queue_timeout")
+
+	// Misspelt keys in the file are rejected.
+	path := filepath.Join(t.TempDir(), "mangle.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("server:\n  prot: \"9000\"\n"), 0o644))
+	_, err = loadConfig(t, []string{"-config-file", path}, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "prot")
+}
+
+func TestConfigCommand(t *testing.T) {
+	t.Setenv("JWT_HS256_SECRET", "top-secret")
+	t.Setenv("ELASTICSEARCH_USERNAME", "mangle")
+	t.Setenv("ELASTICSEARCH_PASSWORD", "hunter2")
+
+	var out, errOut bytes.Buffer
+	code := runConfig([]string{"-server.port", "9000"}, &out, &errOut)
+	require.Equal(t, 0, code, errOut.String())
+
+	// Secrets are redacted, everything else is printed as loaded.
+	assert.NotContains(t, out.String(), "top-secret")
+	assert.NotContains(t, out.String(), "hunter2")
+	var printed config.Config
+	require.NoError(t, yaml.Unmarshal(out.Bytes(), &printed))
+	assert.Equal(t, "REDACTED", printed.Auth.JWTSecret)
+	assert.Equal(t, "REDACTED", printed.Sources.Elasticsearch.Password)
+	assert.Empty(t, printed.Sources.Elasticsearch.APIKey, "unset secrets stay empty")
+	assert.Equal(t, "mangle", printed.Sources.Elasticsearch.Username)
+	assert.Equal(t, "9000", printed.Server.Port)
+	assert.Equal(t, 30*time.Second, printed.Limits.QueryTimeout)
+
+	// The printed configuration can be used as a configuration file.
+	path := filepath.Join(t.TempDir(), "mangle.yaml")
+	require.NoError(t, os.WriteFile(path, out.Bytes(), 0o644))
+	reloaded, err := loadConfig(t, []string{"-config-file", path}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, &printed, reloaded)
+
+	// Invalid configurations fail with exit code 2.
+	out.Reset()
+	errOut.Reset()
+	assert.Equal(t, 2, runConfig([]string{"-env", "staging"}, &out, &errOut))
+	assert.Contains(t, errOut.String(), `env: "staging" is not one of`)
+}
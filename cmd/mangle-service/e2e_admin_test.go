@@ -0,0 +1,158 @@
+package main
+
+import (
+	"log/slog"
+	"mangle-service/internal/adapters/auth"
+	"mangle-service/internal/adapters/file"
+	httphandler "mangle-service/internal/adapters/http"
+	"mangle-service/internal/adapters/mock"
+	"mangle-service/internal/config"
+	"mangle-service/internal/core/domain"
+	"mangle-service/internal/core/service"
+	"mangle-service/pkg/client"
+	"mangle-service/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEndToEndAdminIntrospection(t *testing.T) {
+	// 1. Setup: a rule module with a declared predicate, two log sources of
+	// which one is down, a developer and an SRE with the admin role.
+	log := logger.New(slog.LevelDebug)
+	dir := t.TempDir()
+	relationshipsPath := filepath.Join(dir, "relationships.yaml")
+	writeFile(t, relationshipsPath, `
+relationships:
+  - service: "api-gateway"
+    depends_on: ["order-service"]
+`)
+	rulesDir := filepath.Join(dir, "rules")
+	writeFile(t, filepath.Join(rulesDir, "cascading.mg"), `
+Decl failed(Service, Trace)
+  descr [doc("Service answered the request of Trace with a 500.")]
+  bound [/string, /string].
+failed(Service, Trace) :- logs(Trace, Service, 500, _).
+cascading_failure(Upstream, Downstream) :-
+  depends_on(Upstream, Downstream),
+  failed(Upstream, Trace),
+  failed(Downstream, Trace).
+`)
+	writeFile(t, filepath.Join(dir, "api-keys.yaml"), `
+keys:
+  - subject: "alice"
+    key: "alice-key"
+    roles: ["developer"]
+  - subject: "oncall"
+    key: "sre-key"
+    roles: ["sre"]
+`)
+	relationshipService := service.NewRelationshipService(file.NewConfigLoader(), nil)
+	require.NoError(t, relationshipService.LoadRelationships(relationshipsPath))
+	ruleModuleService := service.NewRuleModuleService(file.NewRuleModuleLoader())
+	require.NoError(t, ruleModuleService.LoadModules(rulesDir))
+	authenticator, err := auth.NewAuthenticator(auth.Config{APIKeysPath: filepath.Join(dir, "api-keys.yaml")})
+	require.NoError(t, err)
+	logs := mock.NewMockLogAdapter()
+	security := &unreachableLogAdapter{}
+	security.down.Store(true)
+	queryService := service.NewQueryService(service.NewLogService(logs), relationshipService, log,
+		service.WithLogSource("security", security),
+		service.WithRuleModules(ruleModuleService))
+	cfg := config.Default()
+	cfg.Auth.JWTSecret = "top-secret"
+	httpAdapter := httphandler.NewAdapter(queryService, log, "8080",
+		httphandler.WithAuthenticator(authenticator),
+		httphandler.WithRelationshipService(relationshipService),
+		httphandler.WithHealthService(service.NewHealthService(relationshipService, domain.HealthConfig{},
+			service.WithSourceCheck(domain.DefaultLogSource, logs),
+			service.WithSourceCheck("security", security))),
+		httphandler.WithAdminRoles("sre"),
+		httphandler.WithEffectiveConfig(cfg.Redacted()))
+	server := httptest.NewServer(httpAdapter.GetRouter())
+	defer server.Close()
+	alice, err := client.New(server.URL, client.WithAPIKey("alice-key"), client.WithRetries(0, 0))
+	require.NoError(t, err)
+	oncall, err := client.New(server.URL, client.WithAPIKey("sre-key"), client.WithRetries(0, 0))
+	require.NoError(t, err)
+
+	// 2. Callers without an admin role are refused everywhere.
+	_, err = alice.Program(t.Context())
+	assertAPIError(t, err, http.StatusForbidden)
+	_, err = alice.Sources(t.Context())
+	assertAPIError(t, err, http.StatusForbidden)
+	_, err = alice.RelationshipState(t.Context())
+	assertAPIError(t, err, http.StatusForbidden)
+	_, err = alice.EffectiveConfig(t.Context())
+	assertAPIError(t, err, http.StatusForbidden)
+
+	// 3. The program lists the relationship rules before the module rules,
+	// and the declared and inferred signatures of their predicates.
+	program, err := oncall.Program(t.Context())
+	require.NoError(t, err)
+	require.Len(t, program.Rules, 4)
+	assert.Equal(t, "depends_on(X,Y) :- calls(X,Y).", program.Rules[0])
+	assert.Equal(t, "cascading.failed(Service,Trace) :- logs(Trace,Service,500,_).", program.Rules[2])
+	assert.Equal(t, []string{domain.DefaultLogSource, "security"}, program.Sources)
+	signatures := make(map[string]client.PredicateSignature)
+	for _, sig := range program.Predicates {
+		signatures[sig.Name] = sig
+	}
+	failed := signatures["cascading.failed"]
+	assert.True(t, failed.Declared)
+	assert.Equal(t, domain.PredicateIntensional, failed.Kind)
+	assert.Equal(t, []string{"Service", "Trace"}, failed.Args)
+	assert.Equal(t, [][]string{{"/string", "/string"}}, failed.Bounds)
+	assert.Equal(t, "Service answered the request of Trace with a 500.", failed.Doc)
+	logsPredicate := signatures["logs"]
+	assert.False(t, logsPredicate.Declared)
+	assert.Equal(t, domain.PredicateExtensional, logsPredicate.Kind)
+	assert.Equal(t, 4, logsPredicate.Arity)
+	assert.Equal(t, domain.PredicateExtensional, signatures["calls"].Kind)
+	assert.Equal(t, domain.PredicateIntensional, signatures["depends_on"].Kind)
+
+	// 4. Sources report the outcome of their health checks.
+	sources, err := oncall.Sources(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, []client.SourceState{
+		{Name: domain.DefaultLogSource, Default: true, Status: client.HealthPass},
+		{Name: "security", Status: client.HealthFail, Message: "connection refused"},
+	}, sources)
+
+	// 5. The relationship state shows the version and a failed reload,
+	// which leaves the previous configuration active.
+	writeFile(t, relationshipsPath, `relationships: [`)
+	require.Error(t, relationshipService.Reload())
+	state, err := oncall.RelationshipState(t.Context())
+	require.NoError(t, err)
+	require.Len(t, state.Config.Relationships, 1)
+	assert.Equal(t, "api-gateway", state.Config.Relationships[0].Service)
+	assert.Equal(t, relationshipService.Status().Version, state.Config.Version)
+	assert.False(t, state.UpdatedAt.IsZero())
+	assert.NotEmpty(t, state.ReloadError)
+	assert.False(t, state.ReloadFailedAt.IsZero())
+
+	// 6. The effective configuration is keyed as in the file, with secrets
+	// redacted.
+	effective, err := oncall.EffectiveConfig(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, "8080", effective["server"].(map[string]any)["port"])
+	assert.Equal(t, "30s", effective["limits"].(map[string]any)["query_timeout"])
+	assert.Equal(t, "REDACTED", effective["auth"].(map[string]any)["jwt_hs256_secret"])
+
+	// 7. Without authentication, the admin endpoints are not served at all.
+	open := httptest.NewServer(httphandler.NewAdapter(queryService, log, "8080",
+		httphandler.WithRelationshipService(relationshipService),
+		httphandler.WithEffectiveConfig(cfg.Redacted())).GetRouter())
+	defer open.Close()
+	for _, path := range []string{"/admin/program", "/admin/sources", "/admin/relationships", "/admin/config"} {
+		resp, err := http.Get(open.URL + path)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode, path)
+	}
+}
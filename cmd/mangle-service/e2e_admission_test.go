@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"mangle-service/internal/adapters/file"
+	httphandler "mangle-service/internal/adapters/http"
+	"mangle-service/internal/core/domain"
+	"mangle-service/internal/core/ports"
+	"mangle-service/internal/core/service"
+	"mangle-service/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingLogAdapter holds every fetch until release is closed.
+type blockingLogAdapter struct {
+	release chan struct{}
+}
+
+func (a *blockingLogAdapter) FetchLogs(ctx context.Context, queryCriteria map[string]string) ([]domain.Fact, error) {
+	select {
+	case <-a.release:
+		return nil, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestEndToEndAdmissionControl(t *testing.T) {
+	// 1. Setup
+	log := logger.New(slog.LevelDebug)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "relationships.yaml"), `
+relationships:
+  - service: "api-gateway"
+    depends_on: ["order-service"]
+`)
+	relationshipService := service.NewRelationshipService(file.NewConfigLoader(), nil)
+	require.NoError(t, relationshipService.LoadRelationships(filepath.Join(dir, "relationships.yaml")))
+
+	newServer := func(logAdapter ports.LogDataPort, config domain.AdmissionConfig) (*httptest.Server, *service.AdmissionController) {
+		admission := service.NewAdmissionController(config)
+		queryService := service.NewQueryService(service.NewLogService(logAdapter), relationshipService, log,
+			service.WithAdmissionControl(admission))
+		httpAdapter := httphandler.NewAdapter(queryService, log, "8080", httphandler.WithAdmissionService(admission))
+		server := httptest.NewServer(httpAdapter.GetRouter())
+		t.Cleanup(server.Close)
+		return server, admission
+	}
+	query := `depends_on(Upstream, Downstream).`
+
+	t.Run("rate limit", func(t *testing.T) {
+		server, admission := newServer(&cascadingFailureLogAdapter{}, domain.AdmissionConfig{ClientRate: 0.01, ClientBurst: 2})
+
+		// The burst is admitted, the next query is rejected until the bucket refills.
+		for range 2 {
+			runQuery(t, server.URL, query)
+		}
+		resp, err := postQuery(server.URL, query)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+		retryAfter, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+		require.NoError(t, err)
+		assert.InDelta(t, 100, retryAfter, 1)
+		var body errorBody
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Equal(t, "rate_limited", body.Code)
+
+		// Other clients have buckets of their own.
+		ctx := domain.ContextWithPrincipal(context.Background(), &domain.Principal{Subject: "other"})
+		release, err := admission.Admit(ctx)
+		require.NoError(t, err)
+		release()
+
+		stats := getAdmissionStats(t, server.URL)
+		assert.Equal(t, uint64(3), stats.Admitted)
+		assert.Equal(t, uint64(1), stats.RateLimited)
+		assert.Equal(t, 2, stats.Clients)
+	})
+
+	t.Run("concurrency limit", func(t *testing.T) {
+		logAdapter := &blockingLogAdapter{release: make(chan struct{})}
+		server, admission := newServer(logAdapter, domain.AdmissionConfig{MaxConcurrent: 1, MaxQueue: 1, QueueTimeout: 200 * time.Millisecond})
+
+		// The first query takes the only slot and blocks.
+		first := make(chan int)
+		go func() {
+			resp, err := postQuery(server.URL, query)
+			if err != nil {
+				first <- 0
+				return
+			}
+			resp.Body.Close()
+			first <- resp.StatusCode
+		}()
+		require.Eventually(t, func() bool { return admission.Stats().Running == 1 }, time.Second, 5*time.Millisecond)
+
+		// The second waits in the queue and gives up after the queue timeout.
+		second := make(chan *http.Response)
+		go func() {
+			resp, _ := postQuery(server.URL, query)
+			second <- resp
+		}()
+		require.Eventually(t, func() bool { return admission.Stats().Queued == 1 }, time.Second, 5*time.Millisecond)
+
+		// The third finds the queue full and is rejected at once.
+		resp, err := postQuery(server.URL, query)
+		require.NoError(t, err)
+		assertOverloaded(t, resp, "queue_full")
+
+		resp = <-second
+		require.NotNil(t, resp)
+		assertOverloaded(t, resp, "queue_timeout")
+
+		stats := getAdmissionStats(t, server.URL)
+		assert.Equal(t, 1, stats.Running)
+		assert.Equal(t, 0, stats.Queued)
+		assert.Equal(t, uint64(1), stats.QueueFull)
+		assert.Equal(t, uint64(1), stats.QueueTimeouts)
+
+		// Once the first query finishes, its slot is free again.
+		close(logAdapter.release)
+		assert.Equal(t, http.StatusOK, <-first)
+		runQuery(t, server.URL, query)
+		stats = getAdmissionStats(t, server.URL)
+		assert.Equal(t, 0, stats.Running)
+		assert.Equal(t, uint64(2), stats.Admitted)
+	})
+}
+
+// postQuery sends a query without failing the test, so that it can be used
+// from other goroutines.
+func postQuery(url, query string) (*http.Response, error) {
+	body, err := json.Marshal(domain.QueryRequest{Query: query})
+	if err != nil {
+		return nil, err
+	}
+	return http.Post(url+"/query", "application/json", bytes.NewReader(body))
+}
+
+func assertOverloaded(t *testing.T, resp *http.Response, reason string) {
+	t.Helper()
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, "1", resp.Header.Get("Retry-After"))
+	var body errorBody
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "overloaded", body.Code)
+	assert.Equal(t, reason, body.Details["reason"])
+}
+
+func getAdmissionStats(t *testing.T, url string) domain.AdmissionStats {
+	t.Helper()
+	resp, err := http.Get(url + "/admission")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var stats domain.AdmissionStats
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&stats))
+	return stats
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"log/slog"
+	"mangle-service/internal/adapters/auth"
+	"mangle-service/internal/adapters/file"
+	httphandler "mangle-service/internal/adapters/http"
+	"mangle-service/internal/adapters/mock"
+	"mangle-service/internal/adapters/sqlite"
+	"mangle-service/internal/core/domain"
+	"mangle-service/internal/core/ports"
+	"mangle-service/internal/core/service"
+	"mangle-service/pkg/client"
+	"mangle-service/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEndToEndAuditLog(t *testing.T) {
+	sinks := map[string]func(path string) (ports.AuditSink, error){
+		"jsonl": func(path string) (ports.AuditSink, error) {
+			return file.NewAuditLog(path)
+		},
+		"sqlite": func(path string) (ports.AuditSink, error) {
+			return sqlite.NewAuditStore(path)
+		},
+	}
+	for name, openSink := range sinks {
+		t.Run(name, func(t *testing.T) {
+			testAuditLog(t, openSink)
+		})
+	}
+}
+
+func testAuditLog(t *testing.T, openSink func(path string) (ports.AuditSink, error)) {
+	// 1. Setup: a developer who may only see their own queries and an auditor
+	// who may see everyone's.
+	log := logger.New(slog.LevelDebug)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "relationships.yaml"), `
+relationships:
+  - service: "api-gateway"
+    depends_on: ["order-service"]
+`)
+	writeFile(t, filepath.Join(dir, "api-keys.yaml"), `
+keys:
+  - subject: "alice"
+    key: "alice-key"
+    roles: ["developer"]
+  - subject: "carol"
+    key: "carol-key"
+    roles: ["auditor"]
+`)
+	auditPath := filepath.Join(dir, "audit")
+	sink, err := openSink(auditPath)
+	require.NoError(t, err)
+	relationshipService := service.NewRelationshipService(file.NewConfigLoader(), nil)
+	require.NoError(t, relationshipService.LoadRelationships(filepath.Join(dir, "relationships.yaml")))
+	authenticator, err := auth.NewAuthenticator(auth.Config{APIKeysPath: filepath.Join(dir, "api-keys.yaml")})
+	require.NoError(t, err)
+	queryService := service.NewQueryService(service.NewLogService(mock.NewMockLogAdapter()), relationshipService, log,
+		service.WithLogSource("security", &securityLogAdapter{}),
+		service.WithAuditSink(sink))
+	jobService := service.NewJobService(queryService, log, domain.JobConfig{Workers: 1, MaxQueued: 10, ResultTTL: time.Minute})
+	defer jobService.Close()
+	httpAdapter := httphandler.NewAdapter(queryService, log, "8080",
+		httphandler.WithAuthenticator(authenticator),
+		httphandler.WithJobService(jobService),
+		httphandler.WithAuditService(service.NewAuditService(sink, domain.AuditConfig{ReaderRoles: []string{"auditor"}})))
+	server := httptest.NewServer(httpAdapter.GetRouter())
+	defer server.Close()
+	alice, err := client.New(server.URL, client.WithAPIKey("alice-key"), client.WithRetries(0, 0))
+	require.NoError(t, err)
+	carol, err := client.New(server.URL, client.WithAPIKey("carol-key"), client.WithRetries(0, 0))
+	require.NoError(t, err)
+
+	// 2. Alice runs a query over a time window, a broken query and a job;
+	// Carol runs a query over every source.
+	start := time.Now().UTC()
+	window := &client.TimeRange{
+		From: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC),
+	}
+	failedServices := `failed(S) :- logs(S, 500, _).
+failed(S).`
+	result, err := alice.Query(t.Context(), client.QueryRequest{
+		Name: "failed-services", Query: failedServices, TimeRange: window, Sources: []string{"logs"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, result.Count)
+	_, err = alice.Query(t.Context(), client.QueryRequest{Query: `failed(S :- logs(S).`})
+	assertAPIError(t, err, http.StatusBadRequest)
+	job, err := alice.SubmitJob(t.Context(), client.QueryRequest{Query: `depends_on(X, Y).`})
+	require.NoError(t, err)
+	job, err = alice.WaitJob(t.Context(), job.ID, 10*time.Millisecond)
+	require.NoError(t, err)
+	require.Equal(t, client.JobSucceeded, job.Status)
+	_, err = carol.Query(t.Context(), client.QueryRequest{Query: `depends_on(X, Y).`})
+	require.NoError(t, err)
+
+	// 3. The auditor sees every execution, newest first.
+	all, err := carol.SearchAudit(t.Context(), client.AuditFilter{})
+	require.NoError(t, err)
+	require.Equal(t, 4, all.Count)
+	require.Len(t, all.Records, 4)
+	for i := 1; i < len(all.Records); i++ {
+		assert.False(t, all.Records[i].Time.After(all.Records[i-1].Time), "records are not sorted newest first")
+	}
+	assert.Equal(t, "carol", all.Records[0].Subject)
+
+	// The successful query records who ran what, over which window and
+	// sources, what it read and what it returned.
+	named, err := carol.SearchAudit(t.Context(), client.AuditFilter{Name: "failed-services"})
+	require.NoError(t, err)
+	require.Equal(t, 1, named.Count)
+	record := named.Records[0]
+	assert.NotEmpty(t, record.ID)
+	assert.NotEmpty(t, record.RequestID)
+	assert.Equal(t, domain.ChannelHTTP, record.Channel)
+	assert.Equal(t, "alice", record.Subject)
+	assert.Equal(t, "api_key", record.AuthMethod)
+	assert.Equal(t, "127.0.0.1", record.ClientAddress)
+	assert.Equal(t, failedServices, record.Query)
+	assert.Equal(t, client.QueryRequest{Query: failedServices}.Hash(), record.QueryHash)
+	require.NotNil(t, record.TimeRange)
+	assert.True(t, window.From.Equal(record.TimeRange.From))
+	assert.True(t, window.To.Equal(record.TimeRange.To))
+	assert.Equal(t, []string{"logs"}, record.Sources)
+	assert.Equal(t, 2, record.FactsRead)
+	assert.Equal(t, 1, record.Results)
+	assert.Equal(t, client.AuditSucceeded, record.Outcome)
+	assert.Empty(t, record.ErrorCode)
+	assert.False(t, record.Time.Before(start.Truncate(time.Microsecond)))
+
+	// The broken query is recorded with its error code.
+	failed, err := carol.SearchAudit(t.Context(), client.AuditFilter{Outcome: client.AuditFailed})
+	require.NoError(t, err)
+	require.Equal(t, 1, failed.Count)
+	assert.Equal(t, domain.CodeParseError, failed.Records[0].ErrorCode)
+	assert.Equal(t, 0, failed.Records[0].FactsRead)
+	assert.Equal(t, []string{}, failed.Records[0].Sources)
+
+	// The job keeps the submitter's identity.
+	jobs, err := carol.SearchAudit(t.Context(), client.AuditFilter{Subject: "alice", Source: "security"})
+	require.NoError(t, err)
+	require.Equal(t, 1, jobs.Count)
+	assert.Equal(t, domain.ChannelJob, jobs.Records[0].Channel)
+	assert.Equal(t, []string{"logs", "security"}, jobs.Records[0].Sources)
+	assert.Equal(t, 3, jobs.Records[0].FactsRead)
+
+	byHash, err := carol.SearchAudit(t.Context(), client.AuditFilter{QueryHash: client.QueryRequest{Query: `depends_on(X, Y).`}.Hash(), Limit: 1})
+	require.NoError(t, err)
+	require.Equal(t, 1, byHash.Count)
+	assert.Equal(t, "carol", byHash.Records[0].Subject)
+
+	window2, err := carol.SearchAudit(t.Context(), client.AuditFilter{Since: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	assert.Equal(t, 0, window2.Count)
+	assert.NotNil(t, window2.Records)
+	window2, err = carol.SearchAudit(t.Context(), client.AuditFilter{Since: start.Add(-time.Minute), Until: time.Now().Add(time.Minute)})
+	require.NoError(t, err)
+	assert.Equal(t, 4, window2.Count)
+
+	// 4. Other callers only see their own queries, whatever subject they ask for.
+	own, err := alice.SearchAudit(t.Context(), client.AuditFilter{Subject: "carol"})
+	require.NoError(t, err)
+	assert.Equal(t, 3, own.Count)
+	for _, record := range own.Records {
+		assert.Equal(t, "alice", record.Subject)
+	}
+
+	// 5. Invalid parameters are rejected, and the endpoint needs credentials.
+	for _, query := range []string{"since=yesterday", "limit=0", "outcome=maybe"} {
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/audit?"+query, nil)
+		require.NoError(t, err)
+		req.Header.Set("X-API-Key", "carol-key")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, query)
+	}
+	resp, err := http.Get(server.URL + "/audit")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// 6. The log survives a restart.
+	require.NoError(t, sink.Close())
+	sink, err = openSink(auditPath)
+	require.NoError(t, err)
+	defer sink.Close()
+	auditor := domain.ContextWithPrincipal(t.Context(), &domain.Principal{Subject: "carol", Roles: []string{"auditor"}})
+	reopened, err := service.NewAuditService(sink, domain.AuditConfig{ReaderRoles: []string{"auditor"}}).Search(auditor, domain.AuditFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, all.Records, reopened.Records)
+
+	// 7. Without authentication the log cannot be searched: the endpoint is
+	// not served and the service refuses anonymous callers.
+	_, err = service.NewAuditService(sink, domain.AuditConfig{}).Search(t.Context(), domain.AuditFilter{})
+	var qerr *domain.QueryError
+	require.ErrorAs(t, err, &qerr)
+	assert.Equal(t, domain.CodeForbidden, qerr.Code)
+	open := httptest.NewServer(httphandler.NewAdapter(queryService, log, "8080",
+		httphandler.WithAuditService(service.NewAuditService(sink, domain.AuditConfig{}))).GetRouter())
+	defer open.Close()
+	resp, err = http.Get(open.URL + "/audit")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	manglev1 "mangle-service/api/mangle/v1"
+	"mangle-service/internal/adapters/auth"
+	"mangle-service/internal/adapters/file"
+	grpchandler "mangle-service/internal/adapters/grpc"
+	httphandler "mangle-service/internal/adapters/http"
+	"mangle-service/internal/core/domain"
+	"mangle-service/internal/core/service"
+	"mangle-service/pkg/logger"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/mangle/ast"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// securityLogAdapter serves the facts of a separate security log source.
+type securityLogAdapter struct{}
+
+func (a *securityLogAdapter) FetchLogs(ctx context.Context, queryCriteria map[string]string) ([]domain.Fact, error) {
+	return []domain.Fact{ast.NewAtom("login_failure", ast.String("alice"))}, nil
+}
+
+func TestEndToEndAuthentication(t *testing.T) {
+	// 1. Setup
+	log := logger.New(slog.LevelDebug)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "relationships.yaml"), `
+relationships:
+  - service: "api-gateway"
+    depends_on: ["order-service"]
+`)
+	rulesDir := filepath.Join(dir, "rules")
+	writeFile(t, filepath.Join(rulesDir, "audit.mg"), `suspicious_login(User) :- login_failure(User).`)
+	writeFile(t, filepath.Join(dir, "api-keys.yaml"), fmt.Sprintf(`
+keys:
+  - subject: "dashboard"
+    key: "dev-key"
+    roles: ["developer"]
+  - subject: "oncall-bot"
+    key_sha256: %q
+    roles: ["sre"]
+`, sha256Hex("sre-key")))
+	writeFile(t, filepath.Join(dir, "policy.yaml"), `
+rules:
+  - predicates: ["audit.*"]
+    roles: ["sre"]
+  - sources: ["security"]
+    roles: ["sre", "security"]
+`)
+	const issuer = "https://idp.example.com"
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	writeFile(t, filepath.Join(dir, "jwks.json"), fmt.Sprintf(`{"keys": [{"kty": "RSA", "kid": "key-1", "use": "sig", "n": %q, "e": %q}]}`,
+		base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()),
+		base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes())))
+
+	relationshipService := service.NewRelationshipService(file.NewConfigLoader(), nil)
+	require.NoError(t, relationshipService.LoadRelationships(filepath.Join(dir, "relationships.yaml")))
+	ruleModuleService := service.NewRuleModuleService(file.NewRuleModuleLoader())
+	require.NoError(t, ruleModuleService.LoadModules(rulesDir))
+	policy, err := file.NewAccessPolicyLoader().Load(filepath.Join(dir, "policy.yaml"))
+	require.NoError(t, err)
+	authenticator, err := auth.NewAuthenticator(auth.Config{
+		APIKeysPath: filepath.Join(dir, "api-keys.yaml"),
+		JWTSecret:   "test-secret",
+		JWKSPath:    filepath.Join(dir, "jwks.json"),
+		Issuer:      issuer,
+	})
+	require.NoError(t, err)
+
+	queryService := service.NewQueryService(service.NewLogService(&cascadingFailureLogAdapter{}), relationshipService, log,
+		service.WithRuleModules(ruleModuleService),
+		service.WithLogSource("security", &securityLogAdapter{}),
+		service.WithAccessPolicy(policy))
+	httpAdapter := httphandler.NewAdapter(queryService, log, "8080", httphandler.WithAuthenticator(authenticator))
+
+	server := httptest.NewServer(httpAdapter.GetRouter())
+	defer server.Close()
+
+	hs256 := func(claims jwt.MapClaims) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("test-secret"))
+		require.NoError(t, err)
+		return "Bearer " + token
+	}
+	rs256 := func(claims jwt.MapClaims, kid string) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = kid
+		signed, err := token.SignedString(rsaKey)
+		require.NoError(t, err)
+		return "Bearer " + signed
+	}
+	expires := time.Now().Add(time.Hour).Unix()
+	cascading := domain.QueryRequest{Query: `
+failed(Service, Trace) :- logs(Trace, Service, 500, _).
+cascading_failure(Upstream, Downstream) :- depends_on(Upstream, Downstream), failed(Upstream, Trace), failed(Downstream, Trace).
+cascading_failure(Upstream, Downstream).`}
+	audit := domain.QueryRequest{Query: `audit.suspicious_login(User).`}
+	securityLogs := domain.QueryRequest{Query: `login_failure(User).`, Sources: []string{"security"}}
+
+	// 2. Health checks need no credentials.
+	resp, err := http.Get(server.URL + "/healthz")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// 3. Queries without valid credentials are rejected.
+	for name, credentials := range map[string][2]string{
+		"none":           {},
+		"unknown key":    {"X-API-Key", "wrong-key"},
+		"bad signature":  {"Authorization", "Bearer " + must(jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "x", "exp": expires}).SignedString([]byte("other")))},
+		"expired token":  {"Authorization", hs256(jwt.MapClaims{"sub": "alice", "exp": time.Now().Add(-time.Minute).Unix()})},
+		"no expiry":      {"Authorization", hs256(jwt.MapClaims{"sub": "alice"})},
+		"wrong issuer":   {"Authorization", hs256(jwt.MapClaims{"sub": "alice", "exp": expires, "iss": "https://evil.example.com"})},
+		"unknown key ID": {"Authorization", rs256(jwt.MapClaims{"sub": "x", "exp": expires, "iss": issuer}, "key-2")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			resp := authQuery(t, server.URL, credentials[0], credentials[1], cascading)
+			defer resp.Body.Close()
+			assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+			assert.Equal(t, `Bearer realm="mangle-service"`, resp.Header.Get("WWW-Authenticate"))
+			var body errorBody
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			assert.Equal(t, "unauthenticated", body.Code)
+		})
+	}
+
+	// 4. Unrestricted predicates are open to every authenticated caller.
+	for _, credentials := range [][2]string{
+		{"X-API-Key", "dev-key"},
+		{"Authorization", hs256(jwt.MapClaims{"sub": "alice", "exp": expires, "iss": issuer})},
+		{"Authorization", rs256(jwt.MapClaims{"sub": "bob", "exp": expires, "iss": issuer}, "key-1")},
+	} {
+		resp := authQuery(t, server.URL, credentials[0], credentials[1], cascading)
+		var result domain.QueryResult
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, []domain.LogEntry{{"Upstream": "api-gateway", "Downstream": "order-service"}}, result.Results)
+	}
+
+	// 5. Audit predicates and the security source are reserved for their roles.
+	developer := [2]string{"X-API-Key", "dev-key"}
+	for _, tc := range []struct {
+		req     domain.QueryRequest
+		details map[string]any
+	}{
+		{audit, map[string]any{"predicate": "audit.suspicious_login", "roles": []any{"sre"}}},
+		{domain.QueryRequest{Query: "mine(U) :- audit.suspicious_login(U).\nmine(U)."}, map[string]any{"predicate": "audit.suspicious_login", "roles": []any{"sre"}}},
+		{securityLogs, map[string]any{"source": "security", "roles": []any{"sre", "security"}}},
+	} {
+		resp := authQuery(t, server.URL, developer[0], developer[1], tc.req)
+		var body errorBody
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		resp.Body.Close()
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode, tc.req.Query)
+		assert.Equal(t, "forbidden", body.Code)
+		assert.Equal(t, tc.details, body.Details)
+	}
+	// Without named sources, sources the caller may not read are left out.
+	resp = authQuery(t, server.URL, developer[0], developer[1], domain.QueryRequest{Query: `login_failure(User).`})
+	var result domain.QueryResult
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, result.Results)
+
+	// 6. Callers with the right roles get through, whichever way they authenticated.
+	for _, tc := range []struct {
+		credentials [2]string
+		req         domain.QueryRequest
+	}{
+		{[2]string{"X-API-Key", "sre-key"}, audit},
+		{[2]string{"Authorization", hs256(jwt.MapClaims{"sub": "carol", "exp": expires, "iss": issuer, "roles": "viewer sre"})}, audit},
+		{[2]string{"Authorization", rs256(jwt.MapClaims{"sub": "dave", "exp": expires, "iss": issuer, "roles": []string{"security"}}, "key-1")}, securityLogs},
+	} {
+		resp := authQuery(t, server.URL, tc.credentials[0], tc.credentials[1], tc.req)
+		var result domain.QueryResult
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode, tc.req.Query)
+		assert.Equal(t, []domain.LogEntry{{"User": "alice"}}, result.Results)
+	}
+}
+
+func authQuery(t *testing.T, url, header, value string, req domain.QueryRequest) *http.Response {
+	t.Helper()
+	body, err := json.Marshal(req)
+	require.NoError(t, err)
+	httpReq, err := http.NewRequest(http.MethodPost, url+"/query", bytes.NewReader(body))
+	require.NoError(t, err)
+	httpReq.Header.Set("Content-Type", "application/json")
+	if header != "" {
+		httpReq.Header.Set(header, value)
+	}
+	resp, err := http.DefaultClient.Do(httpReq)
+	require.NoError(t, err)
+	return resp
+}
+
+func must(s string, err error) string {
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestEndToEndRelationshipViewsFollowPolicy(t *testing.T) {
+	// 1. Setup: the relationship predicates are reserved for the sre role.
+	log := logger.New(slog.LevelDebug)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "relationships.yaml"), `
+relationships:
+  - service: "api-gateway"
+    depends_on: ["order-service"]
+`)
+	writeFile(t, filepath.Join(dir, "api-keys.yaml"), `
+keys:
+  - subject: "dashboard"
+    key: "dev-key"
+    roles: ["developer"]
+  - subject: "oncall-bot"
+    key: "sre-key"
+    roles: ["sre"]
+`)
+	writeFile(t, filepath.Join(dir, "policy.yaml"), `
+rules:
+  - predicates: ["depends_on", "calls"]
+    roles: ["sre"]
+`)
+	relationshipService := service.NewRelationshipService(file.NewConfigLoader(), nil)
+	require.NoError(t, relationshipService.LoadRelationships(filepath.Join(dir, "relationships.yaml")))
+	policy, err := file.NewAccessPolicyLoader().Load(filepath.Join(dir, "policy.yaml"))
+	require.NoError(t, err)
+	authenticator, err := auth.NewAuthenticator(auth.Config{APIKeysPath: filepath.Join(dir, "api-keys.yaml")})
+	require.NoError(t, err)
+	queryService := service.NewQueryService(service.NewLogService(&cascadingFailureLogAdapter{}), relationshipService, log,
+		service.WithAccessPolicy(policy))
+	httpAdapter := httphandler.NewAdapter(queryService, log, "8080",
+		httphandler.WithRelationshipService(relationshipService),
+		httphandler.WithGraphService(service.NewGraphService(relationshipService, queryService)),
+		httphandler.WithImpactService(service.NewImpactService(relationshipService, queryService)),
+		httphandler.WithAuthenticator(authenticator))
+	server := httptest.NewServer(httpAdapter.GetRouter())
+	defer server.Close()
+	get := func(path, apiKey string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		require.NoError(t, err)
+		req.Header.Set("X-API-Key", apiKey)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	// 2. The views show the same edges as the predicates, so callers denied
+	// the predicates are denied the views too.
+	for _, path := range []string{"/relationships", "/relationships/api-gateway", "/lint/relationships", "/graph", "/impact/order-service"} {
+		resp := get(path, "dev-key")
+		var body errorBody
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		resp.Body.Close()
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode, path)
+		assert.Equal(t, map[string]any{"predicate": "calls", "roles": []any{"sre"}}, body.Details, path)
+
+		resp = get(path, "sre-key")
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode, path)
+	}
+
+	// 3. The same holds over gRPC.
+	grpcAdapter := grpchandler.NewAdapter(queryService, log, "9090", grpchandler.WithRelationshipService(relationshipService))
+	developer := domain.ContextWithPrincipal(t.Context(), &domain.Principal{Subject: "dashboard", Roles: []string{"developer"}})
+	_, err = grpcAdapter.ListRelationships(developer, &manglev1.ListRelationshipsRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	sre := domain.ContextWithPrincipal(t.Context(), &domain.Principal{Subject: "oncall-bot", Roles: []string{"sre"}})
+	relationships, err := grpcAdapter.ListRelationships(sre, &manglev1.ListRelationshipsRequest{})
+	require.NoError(t, err)
+	assert.Len(t, relationships.Relationships, 1)
+}
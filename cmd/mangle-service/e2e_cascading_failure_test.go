@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 	"log/slog"
@@ -23,7 +24,7 @@ import (
 // cascadingFailureLogAdapter is a mock implementation of LogDataPort for this specific test case.
 type cascadingFailureLogAdapter struct{}
 
-func (a *cascadingFailureLogAdapter) FetchLogs(queryCriteria map[string]string) ([]domain.Fact, error) {
+func (a *cascadingFailureLogAdapter) FetchLogs(ctx context.Context, queryCriteria map[string]string) ([]domain.Fact, error) {
 	return []domain.Fact{
 		// Successful transaction (noise)
 		// logs("trace-abc", "api-gateway", 200, "Request processed successfully")
@@ -82,7 +83,7 @@ relationships:
 	require.NoError(t, err)
 	require.NoError(t, tmpfile.Close())
 
-	relationshipService := service.NewRelationshipService(fileAdapter)
+	relationshipService := service.NewRelationshipService(fileAdapter, nil)
 	err = relationshipService.LoadRelationships(tmpfile.Name())
 	require.NoError(t, err)
 
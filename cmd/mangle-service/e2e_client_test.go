@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"mangle-service/pkg/client"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const clientCascadingQuery = `
+failed(Service, Trace) :- logs(Trace, Service, 500, _).
+cascading_failure(Upstream, Downstream) :- depends_on(Upstream, Downstream), failed(Upstream, Trace), failed(Downstream, Trace).
+cascading_failure(Upstream, Downstream).`
+
+func TestEndToEndGoClient(t *testing.T) {
+	// 1. Setup: the real adapter behind a handler that can inject failures.
+	router := newFullAdapter(t).GetRouter()
+	var requests, failures atomic.Int32
+	var apiKey atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		apiKey.Store(r.Header.Get("X-API-Key"))
+		if failures.Load() > 0 {
+			failures.Add(-1)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"code": "overloaded", "message": "too many queries are running", "details": {"reason": "queue_full"}}`))
+			return
+		}
+		router.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	c, err := client.New(server.URL+"/", client.WithAPIKey("secret"), client.WithRetries(2, time.Millisecond))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// 2. Typed queries, plain and streamed.
+	result, err := c.Query(ctx, client.QueryRequest{Query: clientCascadingQuery})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Upstream", "Downstream"}, result.Columns)
+	assert.Equal(t, []client.LogEntry{{"Upstream": "api-gateway", "Downstream": "order-service"}}, result.Results)
+	assert.Equal(t, "secret", apiKey.Load())
+
+	var streamed []client.LogEntry
+	summary, err := c.StreamQuery(ctx, client.QueryRequest{Query: clientCascadingQuery}, func(entry client.LogEntry) error {
+		streamed = append(streamed, entry)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, result.Results, streamed)
+	assert.Equal(t, 1, summary.Count)
+
+	// 3. The other endpoints.
+	require.NoError(t, c.Health(ctx))
+	config, err := c.Relationships(ctx)
+	require.NoError(t, err)
+	assert.Len(t, config.Relationships, 1)
+	rel, err := c.Service(ctx, "api-gateway")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"order-service"}, rel.DependsOn)
+	impact, err := c.AnalyzeImpact(ctx, client.ImpactRequest{Service: "order-service"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, impact.Count)
+	graph, err := c.Graph(ctx, "depends_on(From, To).")
+	require.NoError(t, err)
+	assert.Len(t, graph.Nodes, 2)
+	modules, err := c.RuleModules(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, modules.Modules)
+
+	// 4. Errors are decoded and not retried unless they are temporary.
+	requests.Store(0)
+	_, err = c.Query(ctx, client.QueryRequest{Query: "cascading_failure(Upstream, "})
+	var apiErr *client.Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	assert.Equal(t, "parse_error", apiErr.Code)
+	assert.NotEmpty(t, apiErr.RequestID)
+	assert.False(t, apiErr.Temporary())
+	assert.Equal(t, int32(1), requests.Load())
+
+	_, err = c.Service(ctx, "unknown-service")
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+
+	// 5. Temporary failures are retried.
+	requests.Store(0)
+	failures.Store(2)
+	result, err = c.Query(ctx, client.QueryRequest{Query: clientCascadingQuery})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Count)
+	assert.Equal(t, int32(3), requests.Load())
+
+	requests.Store(0)
+	failures.Store(5)
+	_, err = c.Query(ctx, client.QueryRequest{Query: clientCascadingQuery})
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, "overloaded", apiErr.Code)
+	assert.Equal(t, "queue_full", apiErr.Details["reason"])
+	assert.True(t, apiErr.Temporary())
+	assert.Equal(t, int32(3), requests.Load())
+	failures.Store(0)
+
+	// 6. Waiting between retries stops when the context is done.
+	slow, err := client.New(server.URL, client.WithRetries(3, time.Hour))
+	require.NoError(t, err)
+	failures.Store(1)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = slow.Query(ctx, client.QueryRequest{Query: clientCascadingQuery})
+	require.ErrorAs(t, err, &apiErr)
+	assert.Less(t, time.Since(start), time.Second)
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = c.Query(canceled, client.QueryRequest{Query: clientCascadingQuery})
+	assert.True(t, errors.Is(err, context.Canceled), err)
+
+	// 7. Submitting a job is only retried when the service refused it: after
+	// a gateway error or a dropped connection it may have been accepted.
+	var attempts atomic.Int32
+	var fail func(http.ResponseWriter)
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		fail(w)
+	}))
+	defer flaky.Close()
+	fc, err := client.New(flaky.URL, client.WithRetries(2, time.Millisecond))
+	require.NoError(t, err)
+	for _, tc := range []struct {
+		name               string
+		fail               func(http.ResponseWriter)
+		queries, submitted int32
+	}{
+		{"refused", func(w http.ResponseWriter) { w.WriteHeader(http.StatusServiceUnavailable) }, 3, 3},
+		{"bad gateway", func(w http.ResponseWriter) { w.WriteHeader(http.StatusBadGateway) }, 3, 1},
+		{"dropped connection", func(w http.ResponseWriter) {
+			conn, _, err := http.NewResponseController(w).Hijack()
+			if err == nil {
+				conn.Close()
+			}
+		}, 3, 1},
+	} {
+		fail = tc.fail
+		attempts.Store(0)
+		_, err = fc.Query(t.Context(), client.QueryRequest{Query: clientCascadingQuery})
+		require.Error(t, err)
+		assert.Equal(t, tc.queries, attempts.Load(), tc.name)
+		attempts.Store(0)
+		_, err = fc.SubmitJob(t.Context(), client.QueryRequest{Query: clientCascadingQuery})
+		require.Error(t, err)
+		assert.Equal(t, tc.submitted, attempts.Load(), tc.name)
+	}
+}
+
+func TestGoClientRejectsInvalidURL(t *testing.T) {
+	_, err := client.New("localhost:8080")
+	assert.Error(t, err)
+}
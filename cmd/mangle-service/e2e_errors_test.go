@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mangle-service/internal/adapters/file"
+	httphandler "mangle-service/internal/adapters/http"
+	"mangle-service/internal/adapters/mock"
+	"mangle-service/internal/core/domain"
+	"mangle-service/internal/core/ports"
+	"mangle-service/internal/core/service"
+	"mangle-service/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/mangle/analysis"
+	"github.com/google/mangle/engine"
+	"github.com/google/mangle/factstore"
+	"github.com/google/mangle/parse"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unavailableLogAdapter fails like an unreachable log store.
+type unavailableLogAdapter struct{}
+
+func (a *unavailableLogAdapter) FetchLogs(ctx context.Context, queryCriteria map[string]string) ([]domain.Fact, error) {
+	return nil, errors.New("dial tcp 10.0.0.1:9200: connect: connection refused")
+}
+
+// slowLogAdapter blocks until the query is given up on.
+type slowLogAdapter struct{}
+
+func (a *slowLogAdapter) FetchLogs(ctx context.Context, queryCriteria map[string]string) ([]domain.Fact, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+type errorBody struct {
+	Code      string         `json:"code"`
+	Message   string         `json:"message"`
+	Details   map[string]any `json:"details"`
+	RequestID string         `json:"request_id"`
+}
+
+func TestEndToEndQueryErrors(t *testing.T) {
+	log := logger.New(slog.LevelDebug)
+	path := filepath.Join(t.TempDir(), "relationships.yaml")
+	writeFile(t, path, `
+relationships:
+  - service: "A"
+    depends_on: ["B"]
+`)
+	relationshipService := service.NewRelationshipService(file.NewConfigLoader(), nil)
+	require.NoError(t, relationshipService.LoadRelationships(path))
+
+	newServer := func(logs ports.LogDataPort, opts ...service.QueryOption) *httptest.Server {
+		queryService := service.NewQueryService(service.NewLogService(logs), relationshipService, log, opts...)
+		httpAdapter := httphandler.NewAdapter(queryService, log, "8080", httphandler.WithRelationshipService(relationshipService))
+		server := httptest.NewServer(httpAdapter.GetRouter())
+		t.Cleanup(server.Close)
+		return server
+	}
+	server := newServer(mock.NewMockLogAdapter(), service.WithFactLimit(100))
+
+	// A chain of 30 edges has 435 paths, more than the fact limit allows.
+	var chain strings.Builder
+	for i := 0; i < 30; i++ {
+		fmt.Fprintf(&chain, "edge(%d, %d).\n", i, i+1)
+	}
+	chain.WriteString("path(X, Y) :- edge(X, Y).\npath(X, Z) :- path(X, Y), edge(Y, Z).\npath(0, Y).")
+
+	tests := []struct {
+		name    string
+		server  *httptest.Server
+		query   string
+		status  int
+		code    string
+		message string
+		details map[string]any
+	}{
+		{
+			name:   "syntax error",
+			server: server,
+			query:  `logs(S, 500`,
+			status: http.StatusBadRequest,
+			code:   "parse_error",
+		},
+		{
+			name:   "rule as last clause",
+			server: server,
+			query:  `errored(S) :- logs(S, 500, _).`,
+			status: http.StatusBadRequest,
+			code:   "invalid_query",
+		},
+		{
+			name:    "unknown predicate",
+			server:  server,
+			query:   `slow(S) :- metrics(S, _). slow(S).`,
+			status:  http.StatusUnprocessableEntity,
+			code:    "analysis_error",
+			message: "metrics",
+		},
+		{
+			name:    "fact budget",
+			server:  server,
+			query:   chain.String(),
+			status:  http.StatusUnprocessableEntity,
+			code:    "budget_exceeded",
+			details: map[string]any{"fact_limit": float64(100)},
+		},
+		{
+			name:    "log store down",
+			server:  newServer(&unavailableLogAdapter{}),
+			query:   `logs(S, 500, _).`,
+			status:  http.StatusBadGateway,
+			code:    "source_unavailable",
+			message: "failed to fetch logs",
+		},
+		{
+			name:    "timeout",
+			server:  newServer(&slowLogAdapter{}, service.WithTimeout(50*time.Millisecond)),
+			query:   `logs(S, 500, _).`,
+			status:  http.StatusGatewayTimeout,
+			code:    "timeout",
+			details: map[string]any{"timeout": "50ms"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := json.Marshal(domain.QueryRequest{Query: tt.query})
+			require.NoError(t, err)
+			req, err := http.NewRequest(http.MethodPost, tt.server.URL+"/query", strings.NewReader(string(body)))
+			require.NoError(t, err)
+			req.Header.Set("X-Request-ID", "req-"+tt.code)
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.status, resp.StatusCode)
+			assert.Equal(t, "req-"+tt.code, resp.Header.Get("X-Request-ID"))
+			var got errorBody
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+			assert.Equal(t, tt.code, got.Code)
+			assert.Equal(t, "req-"+tt.code, got.RequestID)
+			assert.Contains(t, got.Message, tt.message)
+			if tt.details != nil {
+				assert.Equal(t, tt.details, got.Details)
+			}
+		})
+	}
+
+	// Server-side failure details stay in the logs.
+	resp, err := http.Post(newServer(&unavailableLogAdapter{}).URL+"/query", "application/json", strings.NewReader(`{"query": "logs(S, 500, _)."}`))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var got errorBody
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+	assert.NotContains(t, got.Message, "10.0.0.1")
+	assert.NotEmpty(t, got.RequestID, "a request ID is generated when the client sends none")
+
+	// Other endpoints use the same error body.
+	resp, err = http.Get(server.URL + "/relationships/unknown")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	got = errorBody{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+	assert.Equal(t, "not_found", got.Code)
+	assert.Equal(t, resp.Header.Get("X-Request-ID"), got.RequestID)
+}
+
+// TestEngineFactLimitMessage pins the error of the Mangle engine for an
+// exceeded fact limit, which the query service recognizes by its message.
+// If an upgrade changes it, update factLimitMessage in the query service.
+func TestEngineFactLimitMessage(t *testing.T) {
+	var chain strings.Builder
+	for i := range 30 {
+		fmt.Fprintf(&chain, "edge(%d, %d).\n", i, i+1)
+	}
+	chain.WriteString("path(X, Y) :- edge(X, Y).\npath(X, Z) :- path(X, Y), edge(Y, Z).")
+	unit, err := parse.Unit(strings.NewReader(chain.String()))
+	require.NoError(t, err)
+	program, err := analysis.AnalyzeOneUnit(unit, nil)
+	require.NoError(t, err)
+	store := factstore.NewSimpleInMemoryStore()
+	for _, fact := range program.InitialFacts {
+		store.Add(fact)
+	}
+	err = engine.EvalProgram(program, store, engine.WithCreatedFactLimit(100))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "fact size limit reached")
+}
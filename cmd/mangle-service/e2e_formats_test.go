@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"mangle-service/internal/adapters/file"
+	httphandler "mangle-service/internal/adapters/http"
+	"mangle-service/internal/core/domain"
+	"mangle-service/internal/core/service"
+	"mangle-service/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEndToEndQueryFormats(t *testing.T) {
+	// 1. Setup
+	log := logger.New(slog.LevelDebug)
+	path := filepath.Join(t.TempDir(), "relationships.yaml")
+	writeFile(t, path, `
+relationships:
+  - service: "api-gateway"
+    depends_on: ["order-service"]
+`)
+	relationshipService := service.NewRelationshipService(file.NewConfigLoader(), nil)
+	require.NoError(t, relationshipService.LoadRelationships(path))
+	queryService := service.NewQueryService(service.NewLogService(&cascadingFailureLogAdapter{}), relationshipService, log)
+	httpAdapter := httphandler.NewAdapter(queryService, log, "8080")
+
+	server := httptest.NewServer(httpAdapter.GetRouter())
+	defer server.Close()
+
+	// Columns follow the query, not alphabetical order, and the wildcard and
+	// the constant are not columns.
+	const query = `logs(_, Service, Code, "Database connection failed").`
+	post := func(url, accept string) (*http.Response, string) {
+		body, err := json.Marshal(domain.QueryRequest{Query: query})
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(string(body)))
+		require.NoError(t, err)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, string(data)
+	}
+
+	tests := []struct {
+		name        string
+		url         string
+		accept      string
+		contentType string
+		body        string
+	}{
+		{
+			name:        "csv by accept header",
+			url:         "/query",
+			accept:      "text/csv",
+			contentType: "text/csv; charset=utf-8",
+			body:        "Service,Code\norder-service,500\n",
+		},
+		{
+			name:        "tsv by format parameter",
+			url:         "/query?format=tsv",
+			accept:      "application/json",
+			contentType: "text/tab-separated-values; charset=utf-8",
+			body:        "Service\tCode\norder-service\t500\n",
+		},
+		{
+			name:        "text table",
+			url:         "/query?format=table",
+			contentType: "text/plain; charset=utf-8",
+			body: "Service        Code\n" +
+				"-------        ----\n" +
+				"order-service  500\n" +
+				"(1 rows)\n",
+		},
+		{
+			name:        "mangle facts",
+			url:         "/query",
+			accept:      "text/x-mangle",
+			contentType: "text/x-mangle; charset=utf-8",
+			body:        `logs("trace-xyz","order-service",500,"Database connection failed").` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := post(server.URL+tt.url, tt.accept)
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, tt.contentType, resp.Header.Get("Content-Type"))
+			assert.Equal(t, tt.body, body)
+		})
+	}
+
+	// JSON results list their columns in query order.
+	_, body := post(server.URL+"/query", "")
+	var result domain.QueryResult
+	require.NoError(t, json.Unmarshal([]byte(body), &result))
+	assert.Equal(t, []string{"Service", "Code"}, result.Columns)
+
+	resp, _ := post(server.URL+"/query?format=xlsx", "")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"mangle-service/internal/adapters/file"
+	"mangle-service/internal/adapters/graphexport"
+	httphandler "mangle-service/internal/adapters/http"
+	"mangle-service/internal/core/domain"
+	"mangle-service/internal/core/service"
+	"mangle-service/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEndToEndGraphExport(t *testing.T) {
+	// 1. Setup - the cascading failure scenario, with one extra service that is not involved.
+	log := logger.New(slog.LevelDebug)
+	path := filepath.Join(t.TempDir(), "relationships.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+relationships:
+  - service: "api-gateway"
+    depends_on: ["order-service", "search-service"]
+`), 0o644))
+
+	relationshipService := service.NewRelationshipService(file.NewConfigLoader(), nil)
+	require.NoError(t, relationshipService.LoadRelationships(path))
+	queryService := service.NewQueryService(service.NewLogService(&cascadingFailureLogAdapter{}), relationshipService, log)
+	graphService := service.NewGraphService(relationshipService, queryService)
+	httpAdapter := httphandler.NewAdapter(queryService, log, "8080", httphandler.WithGraphService(graphService))
+
+	server := httptest.NewServer(httpAdapter.GetRouter())
+	defer server.Close()
+
+	// 2. Highlight the services involved in the failing transaction.
+	query := `involved(Gateway, Service) :- logs(TraceID, Gateway, 500, _), calls(Gateway, Service), logs(TraceID, Service, 500, _). involved(Gateway, Service).`
+	resp, err := http.Get(server.URL + "/graph?format=dot&query=" + url.QueryEscape(query))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	// 3. Assertions
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/vnd.graphviz; charset=utf-8", resp.Header.Get("Content-Type"))
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, `digraph relationships {
+  rankdir=LR;
+  node [shape=box, style=rounded];
+  "api-gateway" [style="rounded,filled", fillcolor="#ffb74d", color="#e65100"];
+  "order-service" [style="rounded,filled", fillcolor="#ffb74d", color="#e65100"];
+  "search-service";
+  "api-gateway" -> "order-service" [color="#e65100", penwidth=2];
+  "api-gateway" -> "search-service";
+}
+`, string(body))
+}
+
+func TestGraphExportDOTQuoting(t *testing.T) {
+	graph := &domain.Graph{
+		Nodes: []domain.GraphNode{{ID: "zahlungsdienst-ü"}, {ID: `say "hi" \ bye`}},
+		Edges: []domain.GraphEdge{{From: "zahlungsdienst-ü", To: `say "hi" \ bye`}},
+	}
+	var b strings.Builder
+	require.NoError(t, graphexport.Render(&b, graph, graphexport.FormatDOT))
+	assert.Contains(t, b.String(), `  "zahlungsdienst-ü" -> "say \"hi\" \\ bye";`)
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	manglev1 "mangle-service/api/mangle/v1"
+	"mangle-service/internal/adapters/auth"
+	"mangle-service/internal/adapters/file"
+	grpchandler "mangle-service/internal/adapters/grpc"
+	"mangle-service/internal/core/service"
+	"mangle-service/pkg/logger"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestEndToEndGRPC(t *testing.T) {
+	// 1. Setup: the gRPC adapter on an in-memory listener.
+	log := logger.New(slog.LevelDebug)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "relationships.yaml"), `
+relationships:
+  - service: "api-gateway"
+    depends_on: ["order-service"]
+    criticality: "critical"
+`)
+	writeFile(t, filepath.Join(dir, "api-keys.yaml"), `
+keys:
+  - subject: "dashboard"
+    key: "dev-key"
+    roles: ["developer"]
+`)
+	relationshipService := service.NewRelationshipService(file.NewConfigLoader(), nil)
+	require.NoError(t, relationshipService.LoadRelationships(filepath.Join(dir, "relationships.yaml")))
+	authenticator, err := auth.NewAuthenticator(auth.Config{APIKeysPath: filepath.Join(dir, "api-keys.yaml")})
+	require.NoError(t, err)
+	queryService := service.NewQueryService(service.NewLogService(&cascadingFailureLogAdapter{}), relationshipService, log)
+	grpcAdapter := grpchandler.NewAdapter(queryService, log, "9090",
+		grpchandler.WithRelationshipService(relationshipService),
+		grpchandler.WithAuthenticator(authenticator))
+
+	lis := bufconn.Listen(1 << 20)
+	go func() {
+		_ = grpcAdapter.Serve(lis)
+	}()
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		assert.NoError(t, grpcAdapter.Stop(ctx))
+	}()
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	client := manglev1.NewMangleServiceClient(conn)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "dev-key")
+	query := &manglev1.QueryRequest{Query: `
+failed(Service, Trace) :- logs(Trace, Service, 500, _).
+cascading_failure(Upstream, Downstream) :- depends_on(Upstream, Downstream), failed(Upstream, Trace), failed(Downstream, Trace).
+cascading_failure(Upstream, Downstream).`}
+
+	// 2. Health checks need no credentials.
+	health, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, health.Status)
+
+	// 3. Other calls do.
+	_, err = client.ExecuteQuery(context.Background(), query)
+	assertGRPCError(t, err, codes.Unauthenticated, "unauthenticated")
+
+	// 4. Unary queries, with the request ID echoed in the headers.
+	var header metadata.MD
+	resp, err := client.ExecuteQuery(metadata.AppendToOutgoingContext(ctx, "x-request-id", "grpc-req-1"), query, grpc.Header(&header))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Upstream", "Downstream"}, resp.Columns)
+	require.Len(t, resp.Results, 1)
+	assert.Equal(t, map[string]any{"Upstream": "api-gateway", "Downstream": "order-service"}, resp.Results[0].AsMap())
+	assert.Equal(t, int64(1), resp.Count)
+	assert.Equal(t, []string{"grpc-req-1"}, header.Get("x-request-id"))
+
+	// 5. Streamed queries end with the summary.
+	stream, err := client.StreamQuery(ctx, query)
+	require.NoError(t, err)
+	var streamed []map[string]any
+	var summary *manglev1.QuerySummary
+	for {
+		msg, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		if result := msg.GetResult(); result != nil {
+			streamed = append(streamed, result.AsMap())
+		}
+		if s := msg.GetSummary(); s != nil {
+			summary = s
+		}
+	}
+	assert.Equal(t, []map[string]any{{"Upstream": "api-gateway", "Downstream": "order-service"}}, streamed)
+	require.NotNil(t, summary)
+	assert.Equal(t, int64(1), summary.Count)
+	assert.Equal(t, []string{"Upstream", "Downstream"}, summary.Columns)
+
+	stream, err = client.StreamQuery(ctx, &manglev1.QueryRequest{Query: "cascading_failure(Upstream, "})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assertGRPCError(t, err, codes.InvalidArgument, "parse_error")
+
+	// 6. Failed queries carry their error code.
+	_, err = client.ExecuteQuery(ctx, &manglev1.QueryRequest{Query: "cascading_failure(Upstream, "})
+	assertGRPCError(t, err, codes.InvalidArgument, "parse_error")
+	_, err = client.ExecuteQuery(ctx, &manglev1.QueryRequest{Query: "failed(S) :- logs(_, S, 500, _)."})
+	assertGRPCError(t, err, codes.InvalidArgument, "invalid_query")
+
+	// 7. Validation runs no query and reports problems in the response.
+	validation, err := client.ValidateQuery(ctx, query)
+	require.NoError(t, err)
+	assert.True(t, validation.Valid)
+	assert.Equal(t, []string{"Upstream", "Downstream"}, validation.Columns)
+	assert.Equal(t, []string{"logs"}, validation.Sources)
+
+	validation, err = client.ValidateQuery(ctx, &manglev1.QueryRequest{Query: "cascading_failure(Upstream, "})
+	require.NoError(t, err)
+	assert.False(t, validation.Valid)
+	assert.Equal(t, "parse_error", validation.ErrorCode)
+
+	validation, err = client.ValidateQuery(ctx, &manglev1.QueryRequest{Query: "failed(S, T) :- logs(T, _, 500, _).\nfailed(S, T)."})
+	require.NoError(t, err)
+	assert.False(t, validation.Valid)
+	assert.Equal(t, "analysis_error", validation.ErrorCode)
+
+	validation, err = client.ValidateQuery(ctx, &manglev1.QueryRequest{Query: "depends_on(A, B).", Sources: []string{"unknown"}})
+	require.NoError(t, err)
+	assert.False(t, validation.Valid)
+	assert.Equal(t, "invalid_query", validation.ErrorCode)
+
+	// 8. Relationships.
+	relationships, err := client.ListRelationships(ctx, &manglev1.ListRelationshipsRequest{})
+	require.NoError(t, err)
+	require.Len(t, relationships.Relationships, 1)
+	assert.Equal(t, "api-gateway", relationships.Relationships[0].Service)
+	assert.Equal(t, []string{"order-service"}, relationships.Relationships[0].DependsOn)
+	assert.Equal(t, "critical", relationships.Relationships[0].Criticality)
+	assert.Equal(t, int64(1), relationships.Version)
+}
+
+// assertGRPCError checks the status code of err and the reason of its
+// google.rpc.ErrorInfo.
+func assertGRPCError(t *testing.T, err error, code codes.Code, reason string) {
+	t.Helper()
+	st, ok := status.FromError(err)
+	require.True(t, ok, "not a status error: %v", err)
+	assert.Equal(t, code, st.Code(), st.Message())
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			assert.Equal(t, reason, info.Reason)
+			assert.Equal(t, "mangle-service", info.Domain)
+			return
+		}
+	}
+	t.Errorf("status has no ErrorInfo: %v", st)
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"mangle-service/internal/adapters/auth"
+	"mangle-service/internal/adapters/file"
+	httphandler "mangle-service/internal/adapters/http"
+	"mangle-service/internal/adapters/mock"
+	"mangle-service/internal/core/domain"
+	"mangle-service/internal/core/service"
+	"mangle-service/pkg/client"
+	"mangle-service/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unreachableLogAdapter is a log source whose backend can be taken down.
+type unreachableLogAdapter struct {
+	down atomic.Bool
+}
+
+func (a *unreachableLogAdapter) FetchLogs(ctx context.Context, queryCriteria map[string]string) ([]domain.Fact, error) {
+	return nil, nil
+}
+
+func (a *unreachableLogAdapter) CheckHealth(ctx context.Context) error {
+	if a.down.Load() {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func TestEndToEndReadiness(t *testing.T) {
+	// 1. Setup: two log sources, one of which can fail, and one query slot
+	// without a wait queue.
+	log := logger.New(slog.LevelDebug)
+	dir := t.TempDir()
+	relationshipsPath := filepath.Join(dir, "relationships.yaml")
+	writeFile(t, relationshipsPath, `
+relationships:
+  - service: "api-gateway"
+    depends_on: ["order-service"]
+`)
+	writeFile(t, filepath.Join(dir, "api-keys.yaml"), `
+keys:
+  - subject: "oncall"
+    key: "sre-key"
+    roles: ["sre"]
+`)
+	relationshipService := service.NewRelationshipService(file.NewConfigLoader(), nil)
+	require.NoError(t, relationshipService.LoadRelationships(relationshipsPath))
+	authenticator, err := auth.NewAuthenticator(auth.Config{APIKeysPath: filepath.Join(dir, "api-keys.yaml")})
+	require.NoError(t, err)
+	logs := mock.NewMockLogAdapter()
+	security := &unreachableLogAdapter{}
+	admission := service.NewAdmissionController(domain.AdmissionConfig{MaxConcurrent: 1})
+	queryService := service.NewQueryService(service.NewLogService(logs), relationshipService, log,
+		service.WithLogSource("security", security),
+		service.WithAdmissionControl(admission))
+	healthService := service.NewHealthService(relationshipService, domain.HealthConfig{},
+		service.WithSourceCheck(domain.DefaultLogSource, logs),
+		service.WithSourceCheck("security", security),
+		service.WithQueueCheck(admission))
+	httpAdapter := httphandler.NewAdapter(queryService, log, "8080",
+		httphandler.WithAuthenticator(authenticator),
+		httphandler.WithHealthService(healthService))
+	server := httptest.NewServer(httpAdapter.GetRouter())
+	defer server.Close()
+	c, err := client.New(server.URL, client.WithAPIKey("sre-key"), client.WithRetries(0, 0))
+	require.NoError(t, err)
+
+	// readiness probes /readyz without credentials, as an orchestrator would.
+	readiness := func() (int, domain.HealthReport) {
+		t.Helper()
+		resp, err := http.Get(server.URL + "/readyz")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var report domain.HealthReport
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+		for _, check := range report.Checks {
+			assert.Empty(t, check.Message, "readiness must not reveal why %s failed", check.Name)
+			assert.Empty(t, check.Details)
+		}
+		return resp.StatusCode, report
+	}
+	checkStatus := func(report *domain.HealthReport, name string) domain.HealthStatus {
+		t.Helper()
+		for _, check := range report.Checks {
+			if check.Name == name {
+				return check.Status
+			}
+		}
+		t.Fatalf("check %s is missing", name)
+		return ""
+	}
+
+	// 2. Everything is up.
+	status, report := readiness()
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, domain.HealthPass, report.Status)
+	var names []string
+	for _, check := range report.Checks {
+		names = append(names, check.Name)
+	}
+	assert.ElementsMatch(t, []string{"relationships", "log_source:logs", "log_source:security", "query_queue"}, names)
+
+	// 3. The detailed report needs credentials.
+	resp, err := http.Get(server.URL + "/health/details")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// 4. A log source that cannot be reached makes the service unready.
+	security.down.Store(true)
+	status, report = readiness()
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, domain.HealthFail, report.Status)
+	assert.Equal(t, domain.HealthFail, checkStatus(&report, "log_source:security"))
+	assert.Equal(t, domain.HealthPass, checkStatus(&report, "log_source:logs"))
+
+	details, err := c.HealthDetails(t.Context())
+	require.NoError(t, err)
+	assert.False(t, details.Ready())
+	for _, check := range details.Checks {
+		if check.Name == "log_source:security" {
+			assert.Equal(t, "connection refused", check.Message)
+		}
+	}
+	security.down.Store(false)
+
+	// 5. So does a failed relationship reload, although the previous
+	// configuration keeps serving queries.
+	writeFile(t, relationshipsPath, `
+relationships:
+  - service: "api-gateway"
+    depends_on: ["api-gateway"]
+`)
+	require.Error(t, relationshipService.Reload())
+	status, report = readiness()
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, domain.HealthFail, checkStatus(&report, "relationships"))
+	details, err = c.HealthDetails(t.Context())
+	require.NoError(t, err)
+	for _, check := range details.Checks {
+		if check.Name == "relationships" {
+			assert.Contains(t, check.Message, "version 1 is still active")
+			assert.Contains(t, check.Details, "reload_failed_at")
+		}
+	}
+	result, err := c.Query(t.Context(), client.QueryRequest{Query: `depends_on("api-gateway", X).`})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Count)
+
+	writeFile(t, relationshipsPath, `
+relationships:
+  - service: "api-gateway"
+    depends_on: ["order-service", "user-service"]
+`)
+	require.NoError(t, relationshipService.Reload())
+	status, _ = readiness()
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, int64(2), relationshipService.Status().Version)
+
+	// 6. A saturated query queue makes the service unready until a slot
+	// frees up.
+	release, err := admission.Admit(t.Context())
+	require.NoError(t, err)
+	status, report = readiness()
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, domain.HealthFail, checkStatus(&report, "query_queue"))
+	release()
+	status, report = readiness()
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, domain.HealthPass, checkStatus(&report, "query_queue"))
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"mangle-service/internal/adapters/file"
+	httphandler "mangle-service/internal/adapters/http"
+	"mangle-service/internal/core/domain"
+	"mangle-service/internal/core/service"
+	"mangle-service/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEndToEndImpactAnalysis(t *testing.T) {
+	// 1. Setup
+	log := logger.New(slog.LevelDebug)
+	path := filepath.Join(t.TempDir(), "relationships.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+relationships:
+  - service: "mobile-bff"
+    depends_on: ["api-gateway"]
+  - service: "api-gateway"
+    criticality: "high"
+    depends_on: ["order-service"]
+  - service: "admin"
+    criticality: "critical"
+    depends_on: ["order-service"]
+  - service: "order-service"
+    depends_on: ["postgres"]
+`), 0o644))
+
+	relationshipService := service.NewRelationshipService(file.NewConfigLoader(), nil)
+	require.NoError(t, relationshipService.LoadRelationships(path))
+	queryService := service.NewQueryService(service.NewLogService(&cascadingFailureLogAdapter{}), relationshipService, log)
+	impactService := service.NewImpactService(relationshipService, queryService)
+	httpAdapter := httphandler.NewAdapter(queryService, log, "8080", httphandler.WithImpactService(impactService))
+
+	server := httptest.NewServer(httpAdapter.GetRouter())
+	defer server.Close()
+
+	// 2. Every upstream service, ranked by distance and criticality.
+	resp, err := http.Get(server.URL + "/impact/order-service")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var result domain.ImpactResult
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, []domain.ImpactedService{
+		{Service: "admin", Distance: 1, Criticality: "critical", Paths: [][]string{{"admin", "order-service"}}},
+		{Service: "api-gateway", Distance: 1, Criticality: "high", Paths: [][]string{{"api-gateway", "order-service"}}},
+		{Service: "mobile-bff", Distance: 2, Paths: [][]string{{"mobile-bff", "api-gateway", "order-service"}}},
+	}, result.Impacted)
+
+	// 3. Only the impacted services that actually logged errors.
+	body, err := json.Marshal(domain.ImpactRequest{
+		Service:     "order-service",
+		ErrorsQuery: `errored(Service) :- logs(_, Service, 500, _). errored(Service).`,
+	})
+	require.NoError(t, err)
+	resp, err = http.Post(server.URL+"/impact", "application/json", bytes.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	result = domain.ImpactResult{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, 1, result.Count)
+	assert.Equal(t, "api-gateway", result.Impacted[0].Service)
+
+	// 4. Unknown services are reported as such.
+	resp, err = http.Get(server.URL + "/impact/unknown-service")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"mangle-service/internal/adapters/file"
+	"mangle-service/internal/adapters/importer"
+	"mangle-service/internal/core/domain"
+	"mangle-service/internal/core/service"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEndToEndImportedRelationships(t *testing.T) {
+	// 1. Setup: the same system described in three different formats.
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "docker-compose.yml"), `
+services:
+  api-gateway:
+    depends_on: ["order-service"]
+  order-service:
+    depends_on:
+      postgres:
+        condition: service_healthy
+  postgres:
+    image: postgres:16
+`)
+	writeFile(t, filepath.Join(dir, "k8s", "order-service.yaml"), `
+apiVersion: v1
+kind: Service
+metadata:
+  name: order-service
+spec:
+  selector:
+    app: orders
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: payment-service
+spec:
+  selector:
+    app: payments
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: orders
+spec:
+  template:
+    metadata:
+      labels:
+        app: orders
+    spec:
+      containers:
+        - name: orders
+          env:
+            - name: PAYMENTS_URL
+              value: http://payment-service.shop.svc.cluster.local:8080/api
+            - name: DOCS_URL
+              value: https://payment-service.example.com
+`)
+	writeFile(t, filepath.Join(dir, "catalog-info.yaml"), `
+apiVersion: backstage.io/v1alpha1
+kind: Component
+metadata:
+  name: payment-service
+spec:
+  dependsOn:
+    - resource:default/ledger-db
+`)
+
+	loader := importer.NewDefaultMultiLoader(file.NewConfigLoader())
+	spec := "compose:" + filepath.Join(dir, "docker-compose.yml") +
+		",k8s:" + filepath.Join(dir, "k8s") +
+		",backstage:" + filepath.Join(dir, "catalog-info.yaml")
+
+	// 2. Load through the relationship service, as main does.
+	relationshipService := service.NewRelationshipService(loader, nil)
+	require.NoError(t, relationshipService.LoadRelationships(spec))
+
+	// 3. Assertions
+	assert.Equal(t, []domain.ServiceRelationship{
+		{Service: "api-gateway", DependsOn: []string{"order-service"}},
+		{Service: "order-service", DependsOn: []string{"postgres", "payment-service"}},
+		{Service: "postgres"},
+		{Service: "payment-service", DependsOn: []string{"ledger-db"}},
+	}, relationshipService.GetRelationships())
+}
+
+func TestMultiLoaderReadsPathsWithColons(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "v2:relationships.yml"), `
+relationships:
+  - service: "api-gateway"
+    depends_on: ["order-service"]
+`)
+	writeFile(t, filepath.Join(dir, "compose:docker-compose.yml"), `
+services:
+  order-service:
+    depends_on: [postgres]
+`)
+	loader := importer.NewDefaultMultiLoader(file.NewConfigLoader())
+	config, err := loader.Load(filepath.Join(dir, "v2:relationships.yml"))
+	require.NoError(t, err)
+	assert.Equal(t, "api-gateway", config.Relationships[0].Service)
+
+	// Only the known kinds are prefixes; anything else is part of the path.
+	_, err = loader.Load("terraform:main.tf")
+	assert.ErrorContains(t, err, "terraform:main.tf")
+	assert.NotContains(t, err.Error(), "kind")
+	t.Chdir(dir)
+	config, err = loader.Load("compose:compose:docker-compose.yml")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"postgres"}, config.Relationships[0].DependsOn)
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"mangle-service/internal/adapters/auth"
+	"mangle-service/internal/adapters/file"
+	httphandler "mangle-service/internal/adapters/http"
+	"mangle-service/internal/core/domain"
+	"mangle-service/internal/core/ports"
+	"mangle-service/internal/core/service"
+	"mangle-service/pkg/client"
+	"mangle-service/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEndToEndQueryJobs(t *testing.T) {
+	// 1. Setup: two callers, one worker and room for one queued job.
+	log := logger.New(slog.LevelDebug)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "relationships.yaml"), `
+relationships:
+  - service: "api-gateway"
+    depends_on: ["order-service"]
+`)
+	writeFile(t, filepath.Join(dir, "api-keys.yaml"), `
+keys:
+  - subject: "alice"
+    key: "alice-key"
+  - subject: "bob"
+    key: "bob-key"
+`)
+	relationshipService := service.NewRelationshipService(file.NewConfigLoader(), nil)
+	require.NoError(t, relationshipService.LoadRelationships(filepath.Join(dir, "relationships.yaml")))
+	authenticator, err := auth.NewAuthenticator(auth.Config{APIKeysPath: filepath.Join(dir, "api-keys.yaml")})
+	require.NoError(t, err)
+
+	newServer := func(logAdapter ports.LogDataPort) string {
+		queryService := service.NewQueryService(service.NewLogService(logAdapter), relationshipService, log)
+		jobService := service.NewJobService(queryService, log, domain.JobConfig{Workers: 1, MaxQueued: 1, ResultTTL: 200 * time.Millisecond})
+		httpAdapter := httphandler.NewAdapter(queryService, log, "8080",
+			httphandler.WithJobService(jobService),
+			httphandler.WithAuthenticator(authenticator))
+		server := httptest.NewServer(httpAdapter.GetRouter())
+		t.Cleanup(server.Close)
+		t.Cleanup(jobService.Close)
+		return server.URL
+	}
+	url := newServer(&cascadingFailureLogAdapter{})
+	alice, err := client.New(url, client.WithAPIKey("alice-key"), client.WithRetries(0, 0))
+	require.NoError(t, err)
+	bob, err := client.New(url, client.WithAPIKey("bob-key"), client.WithRetries(0, 0))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// 2. Submitting answers with the queued job and its URL.
+	body, err := json.Marshal(domain.QueryRequest{Query: clientCascadingQuery})
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, url+"/jobs", bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("X-API-Key", "alice-key")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	var submitted domain.Job
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&submitted))
+	assert.NotEmpty(t, submitted.ID)
+	assert.Equal(t, "/jobs/"+submitted.ID, resp.Header.Get("Location"))
+	assert.Contains(t, []domain.JobStatus{domain.JobQueued, domain.JobRunning}, submitted.Status)
+
+	// 3. The finished job carries the result.
+	job, err := alice.WaitJob(ctx, submitted.ID, 5*time.Millisecond)
+	require.NoError(t, err)
+	require.Equal(t, client.JobSucceeded, job.Status, job.Error)
+	require.NotNil(t, job.Result)
+	assert.Equal(t, []string{"Upstream", "Downstream"}, job.Result.Columns)
+	assert.Equal(t, []client.LogEntry{{"Upstream": "api-gateway", "Downstream": "order-service"}}, job.Result.Results)
+	assert.Equal(t, 1, job.Progress.Results)
+	assert.False(t, job.FinishedAt.IsZero())
+	assert.Equal(t, job.FinishedAt.Add(200*time.Millisecond), job.ExpiresAt)
+
+	// 4. Jobs are private to the caller that submitted them.
+	_, err = bob.Job(ctx, job.ID)
+	assertAPIError(t, err, http.StatusNotFound)
+	_, err = bob.CancelJob(ctx, job.ID)
+	assertAPIError(t, err, http.StatusNotFound)
+
+	// 5. Invalid queries are rejected when they are submitted.
+	_, err = alice.SubmitJob(ctx, client.QueryRequest{Query: "cascading_failure(Upstream, "})
+	assertAPIError(t, err, http.StatusBadRequest)
+
+	// 6. Results expire, and deleting a finished job discards it at once.
+	time.Sleep(250 * time.Millisecond)
+	_, err = alice.Job(ctx, job.ID)
+	assertAPIError(t, err, http.StatusNotFound)
+
+	job, err = alice.SubmitJob(ctx, client.QueryRequest{Query: clientCascadingQuery})
+	require.NoError(t, err)
+	_, err = alice.WaitJob(ctx, job.ID, 5*time.Millisecond)
+	require.NoError(t, err)
+	_, err = alice.CancelJob(ctx, job.ID)
+	require.NoError(t, err)
+	_, err = alice.Job(ctx, job.ID)
+	assertAPIError(t, err, http.StatusNotFound)
+
+	// 7. Workers and the queue are bounded, and jobs can be cancelled while
+	// they are queued or running.
+	blocking := &blockingLogAdapter{release: make(chan struct{})}
+	defer close(blocking.release)
+	alice, err = client.New(newServer(blocking), client.WithAPIKey("alice-key"), client.WithRetries(0, 0))
+	require.NoError(t, err)
+	query := client.QueryRequest{Query: "depends_on(Upstream, Downstream)."}
+	running, err := alice.SubmitJob(ctx, query)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		job, err := alice.Job(ctx, running.ID)
+		return err == nil && job.Status == client.JobRunning
+	}, 5*time.Second, 5*time.Millisecond)
+	queued, err := alice.SubmitJob(ctx, query)
+	require.NoError(t, err)
+	_, err = alice.SubmitJob(ctx, query)
+	apiErr := assertAPIError(t, err, http.StatusServiceUnavailable)
+	assert.Equal(t, "job_queue_full", apiErr.Details["reason"])
+
+	job, err = alice.CancelJob(ctx, queued.ID)
+	require.NoError(t, err)
+	assert.Equal(t, client.JobCanceled, job.Status)
+
+	_, err = alice.CancelJob(ctx, running.ID)
+	require.NoError(t, err)
+	job, err = alice.WaitJob(ctx, running.ID, 5*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, client.JobCanceled, job.Status)
+	assert.Nil(t, job.Result)
+	assert.Nil(t, job.Error)
+}
+
+func assertAPIError(t *testing.T, err error, status int) *client.Error {
+	t.Helper()
+	apiErr, ok := err.(*client.Error)
+	require.True(t, ok, "not an API error: %v", err)
+	assert.Equal(t, status, apiErr.StatusCode, apiErr.Message)
+	return apiErr
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"mangle-service/internal/adapters/auth"
+	"mangle-service/internal/adapters/file"
+	httphandler "mangle-service/internal/adapters/http"
+	"mangle-service/internal/adapters/mock"
+	"mangle-service/internal/core/domain"
+	"mangle-service/internal/core/service"
+	"mangle-service/pkg/client"
+	"mangle-service/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// logBuffer collects the lines written by concurrent handlers.
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// lines decodes the JSON log lines written so far and clears the buffer.
+func (b *logBuffer) lines(t *testing.T) []map[string]any {
+	t.Helper()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var lines []map[string]any
+	scanner := bufio.NewScanner(&b.buf)
+	for scanner.Scan() {
+		var line map[string]any
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &line), scanner.Text())
+		lines = append(lines, line)
+	}
+	b.buf.Reset()
+	return lines
+}
+
+func TestLoggerFormats(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.New(slog.LevelInfo, logger.WithFormat(logger.FormatText), logger.WithOutput(&buf))
+	ctx := logger.ContextWithAttrs(t.Context(), slog.String("request_id", "r1"))
+	ctx = logger.ContextWithAttrs(ctx, slog.String("request_id", "r2"), slog.String("query_hash", "abc"))
+	log.DebugContext(ctx, "dropped")
+	log.InfoContext(ctx, "kept", "count", 3)
+	assert.Equal(t, 1, strings.Count(buf.String(), "\n"))
+	assert.Contains(t, buf.String(), `level=INFO msg=kept count=3 request_id=r2 query_hash=abc`)
+
+	buf.Reset()
+	logger.New(slog.LevelInfo, logger.WithOutput(&buf)).InfoContext(ctx, "kept")
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	assert.Equal(t, "r2", line["request_id"])
+	assert.Equal(t, "abc", line["query_hash"])
+
+	_, err := logger.ParseLevel("verbose")
+	assert.Error(t, err)
+}
+
+func TestEndToEndLogging(t *testing.T) {
+	// 1. Setup: a service logging JSON at info level, a developer and an
+	// operator with the admin role.
+	var out logBuffer
+	level := new(slog.LevelVar)
+	log := logger.New(level, logger.WithOutput(&out))
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "relationships.yaml"), `
+relationships:
+  - service: "api-gateway"
+    depends_on: ["order-service"]
+`)
+	writeFile(t, filepath.Join(dir, "api-keys.yaml"), `
+keys:
+  - subject: "alice"
+    key: "alice-key"
+    roles: ["developer"]
+  - subject: "olga"
+    key: "olga-key"
+    roles: ["operator"]
+`)
+	relationshipService := service.NewRelationshipService(file.NewConfigLoader(), nil)
+	require.NoError(t, relationshipService.LoadRelationships(filepath.Join(dir, "relationships.yaml")))
+	authenticator, err := auth.NewAuthenticator(auth.Config{APIKeysPath: filepath.Join(dir, "api-keys.yaml")})
+	require.NoError(t, err)
+	queryService := service.NewQueryService(service.NewLogService(mock.NewMockLogAdapter()), relationshipService, log)
+	httpAdapter := httphandler.NewAdapter(queryService, log, "8080",
+		httphandler.WithAuthenticator(authenticator),
+		httphandler.WithLogLevel(level),
+		httphandler.WithAdminRoles("operator"))
+	server := httptest.NewServer(httpAdapter.GetRouter())
+	defer server.Close()
+	alice, err := client.New(server.URL, client.WithAPIKey("alice-key"), client.WithRetries(0, 0))
+	require.NoError(t, err)
+	olga, err := client.New(server.URL, client.WithAPIKey("olga-key"), client.WithRetries(0, 0))
+	require.NoError(t, err)
+
+	// 2. Every line logged for a query carries its request ID and query hash,
+	// and the query text is not logged at info level.
+	req := domain.QueryRequest{Query: `depends_on(X, "order-service").`}
+	query := func() string {
+		body, err := json.Marshal(req)
+		require.NoError(t, err)
+		httpReq, err := http.NewRequest(http.MethodPost, server.URL+"/query", bytes.NewReader(body))
+		require.NoError(t, err)
+		httpReq.Header.Set("X-API-Key", "alice-key")
+		resp, err := http.DefaultClient.Do(httpReq)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		return resp.Header.Get("X-Request-ID")
+	}
+	requestID := query()
+	require.NotEmpty(t, requestID)
+	lines := out.lines(t)
+	require.NotEmpty(t, lines)
+	for _, line := range lines {
+		assert.Equal(t, requestID, line["request_id"], "line %v", line)
+		assert.Equal(t, req.Hash(), line["query_hash"], "line %v", line)
+		assert.NotContains(t, line, "query")
+		assert.NotEqual(t, "DEBUG", line["level"])
+	}
+
+	// 3. Only admins may change the level, and only to a known level.
+	_, err = alice.LogLevel(t.Context())
+	assertAPIError(t, err, http.StatusForbidden)
+	current, err := olga.LogLevel(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, "info", current)
+	assertAPIError(t, alice.SetLogLevel(t.Context(), "debug"), http.StatusForbidden)
+	assertAPIError(t, olga.SetLogLevel(t.Context(), "verbose"), http.StatusBadRequest)
+	require.NoError(t, olga.SetLogLevel(t.Context(), "debug"))
+	current, err = olga.LogLevel(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, "debug", current)
+
+	// The change itself is logged with who made it.
+	var changed map[string]any
+	for _, line := range out.lines(t) {
+		if line["msg"] == "changed log level" {
+			changed = line
+		}
+	}
+	require.NotNil(t, changed)
+	assert.Equal(t, "olga", changed["subject"])
+	assert.Equal(t, "DEBUG", changed["to"])
+
+	// 4. At debug level the same query logs its text, still correlated.
+	requestID = query()
+	var debug int
+	for _, line := range out.lines(t) {
+		assert.Equal(t, requestID, line["request_id"], "line %v", line)
+		if line["level"] == "DEBUG" {
+			debug++
+		}
+		if line["msg"] == "query text" {
+			assert.Equal(t, req.Query, line["query"])
+		}
+	}
+	assert.NotZero(t, debug)
+
+	// 5. Without authentication nobody may change the level, so the
+	// endpoints are not served.
+	open := httptest.NewServer(httphandler.NewAdapter(queryService, log, "8080",
+		httphandler.WithLogLevel(level)).GetRouter())
+	defer open.Close()
+	resp, err := http.Get(open.URL + "/admin/log-level")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"mangle-service/internal/adapters/file"
+	httphandler "mangle-service/internal/adapters/http"
+	"mangle-service/internal/adapters/metrics"
+	"mangle-service/internal/adapters/mock"
+	"mangle-service/internal/core/service"
+	"mangle-service/pkg/client"
+	"mangle-service/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// metricValue returns the value of a sample in the Prometheus text format,
+// such as `mangle_queries_in_flight{query="adhoc"}`, or -1 if it is missing.
+func metricValue(t *testing.T, exposition, series string) float64 {
+	t.Helper()
+	for _, line := range strings.Split(exposition, "\n") {
+		value, ok := strings.CutPrefix(line, series+" ")
+		if !ok {
+			continue
+		}
+		f, err := strconv.ParseFloat(value, 64)
+		require.NoError(t, err)
+		return f
+	}
+	return -1
+}
+
+func TestEndToEndMetrics(t *testing.T) {
+	// 1. Setup: two log sources; the default one returns two facts.
+	log := logger.New(slog.LevelDebug)
+	dir := t.TempDir()
+	relationshipsPath := filepath.Join(dir, "relationships.yaml")
+	writeFile(t, relationshipsPath, `
+relationships:
+  - service: "api-gateway"
+    depends_on: ["order-service"]
+  - service: "order-service"
+    depends_on: ["payment-service"]
+`)
+	promMetrics := metrics.NewPrometheusMetrics()
+	relationshipService := service.NewRelationshipService(file.NewConfigLoader(), nil,
+		service.WithRelationshipMetrics(promMetrics))
+	require.NoError(t, relationshipService.LoadRelationships(relationshipsPath))
+	queryService := service.NewQueryService(service.NewLogService(mock.NewMockLogAdapter()), relationshipService, log,
+		service.WithLogSource("security", &securityLogAdapter{}),
+		service.WithMetrics(promMetrics))
+	httpAdapter := httphandler.NewAdapter(queryService, log, "8080",
+		httphandler.WithMetricsHandler(promMetrics.Handler()))
+	server := httptest.NewServer(httpAdapter.GetRouter())
+	defer server.Close()
+	c, err := client.New(server.URL)
+	require.NoError(t, err)
+
+	// 2. Run a named query twice, an ad-hoc query and a broken one, and
+	// reload the relationships once successfully and once not.
+	named := client.QueryRequest{Name: "failed-services", Query: `failed(S) :- logs(S, 500, _).
+failed(S).`, Sources: []string{"logs"}}
+	for range 2 {
+		result, err := c.Query(t.Context(), named)
+		require.NoError(t, err)
+		require.Equal(t, 1, result.Count)
+	}
+	_, err = c.Query(t.Context(), client.QueryRequest{Query: `depends_on(X, Y).`})
+	require.NoError(t, err)
+	_, err = c.Query(t.Context(), client.QueryRequest{Name: "broken", Query: `failed(S :- logs(S).`})
+	assertAPIError(t, err, http.StatusBadRequest)
+
+	require.NoError(t, relationshipService.Reload())
+	writeFile(t, relationshipsPath, `relationships: [`)
+	require.Error(t, relationshipService.Reload())
+
+	// 3. Scrape the metrics.
+	scrape := func() string {
+		t.Helper()
+		resp, err := http.Get(server.URL + "/metrics")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, resp.Header.Get("Content-Type"), "text/plain")
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+	exposition := scrape()
+
+	// Every phase of the named query is timed, labelled with its name.
+	for _, phase := range []string{"parse", "fetch", "analysis", "evaluation", "extraction"} {
+		series := fmt.Sprintf(`mangle_query_phase_duration_seconds_count{phase=%q,query="failed-services"}`, phase)
+		assert.Equal(t, 2.0, metricValue(t, exposition, series), series)
+		series = fmt.Sprintf(`mangle_query_phase_duration_seconds_count{phase=%q,query="adhoc"}`, phase)
+		assert.Equal(t, 1.0, metricValue(t, exposition, series), series)
+	}
+	assert.Equal(t, 2.0, metricValue(t, exposition, `mangle_query_duration_seconds_count{query="failed-services"}`))
+	assert.Equal(t, 0.0, metricValue(t, exposition, `mangle_queries_in_flight{query="failed-services"}`))
+
+	// The broken query failed while parsing, so no phase completed.
+	assert.Equal(t, 1.0, metricValue(t, exposition, `mangle_query_errors_total{code="parse_error",query="broken"}`))
+	assert.Equal(t, -1.0, metricValue(t, exposition, `mangle_query_phase_duration_seconds_count{phase="parse",query="broken"}`))
+
+	// The named query read only the default source; the ad-hoc query read both.
+	assert.Equal(t, 6.0, metricValue(t, exposition, `mangle_log_facts_fetched_total{source="logs"}`))
+	assert.Equal(t, 1.0, metricValue(t, exposition, `mangle_log_facts_fetched_total{source="security"}`))
+	// depends_on derives three facts from two calls facts: two direct, one transitive.
+	assert.Equal(t, 3.0, metricValue(t, exposition, `mangle_facts_derived_total{query="adhoc"}`))
+
+	assert.Equal(t, 1.0, metricValue(t, exposition, `mangle_relationship_reloads_total{result="success"}`))
+	assert.Equal(t, 1.0, metricValue(t, exposition, `mangle_relationship_reloads_total{result="failure"}`))
+
+	// 4. Query names are client-chosen, so only the first hundred become
+	// label values.
+	for i := range 98 {
+		_, err := c.Query(t.Context(), client.QueryRequest{Name: fmt.Sprintf("panel-%d", i), Query: `depends_on(X, Y).`})
+		require.NoError(t, err)
+	}
+	_, err = c.Query(t.Context(), client.QueryRequest{Name: "one-too-many", Query: `depends_on(X, Y).`})
+	require.NoError(t, err)
+	exposition = scrape()
+	assert.Equal(t, 1.0, metricValue(t, exposition, `mangle_query_duration_seconds_count{query="panel-97"}`))
+	assert.Equal(t, -1.0, metricValue(t, exposition, `mangle_query_duration_seconds_count{query="one-too-many"}`))
+	assert.Equal(t, 1.0, metricValue(t, exposition, `mangle_query_duration_seconds_count{query="other"}`))
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"mangle-service/internal/adapters/auth"
+	"mangle-service/internal/adapters/file"
+	httphandler "mangle-service/internal/adapters/http"
+	"mangle-service/internal/adapters/metrics"
+	"mangle-service/internal/config"
+	"mangle-service/internal/core/domain"
+	"mangle-service/internal/core/service"
+	"mangle-service/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+type openAPIDocument struct {
+	Paths      map[string]map[string]any `json:"paths"`
+	Components struct {
+		Schemas map[string]struct {
+			Properties map[string]any `json:"properties"`
+		} `json:"schemas"`
+	} `json:"components"`
+}
+
+// newFullAdapter creates an adapter with every optional endpoint enabled.
+// The endpoints that require authentication accept the API key "secret".
+func newFullAdapter(t *testing.T) *httphandler.Adapter {
+	t.Helper()
+	log := logger.New(slog.LevelDebug)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "relationships.yaml"), `
+relationships:
+  - service: "api-gateway"
+    depends_on: ["order-service"]
+`)
+	writeFile(t, filepath.Join(dir, "api-keys.yaml"), `
+keys:
+  - subject: "tester"
+    key: "secret"
+`)
+	authenticator, err := auth.NewAuthenticator(auth.Config{APIKeysPath: filepath.Join(dir, "api-keys.yaml")})
+	require.NoError(t, err)
+	relationshipService := service.NewRelationshipService(file.NewConfigLoader(), nil)
+	require.NoError(t, relationshipService.LoadRelationships(filepath.Join(dir, "relationships.yaml")))
+	ruleModuleService := service.NewRuleModuleService(file.NewRuleModuleLoader())
+	admission := service.NewAdmissionController(domain.AdmissionConfig{})
+	queryService := service.NewQueryService(service.NewLogService(&cascadingFailureLogAdapter{}), relationshipService, log,
+		service.WithRuleModules(ruleModuleService), service.WithAdmissionControl(admission))
+	auditLog, err := file.NewAuditLog(filepath.Join(dir, "audit.jsonl"))
+	require.NoError(t, err)
+	t.Cleanup(func() { auditLog.Close() })
+	jobService := service.NewJobService(queryService, log, domain.JobConfig{Workers: 1, MaxQueued: 10, ResultTTL: time.Minute})
+	t.Cleanup(jobService.Close)
+	return httphandler.NewAdapter(queryService, log, "8080",
+		httphandler.WithRelationshipService(relationshipService),
+		httphandler.WithGraphService(service.NewGraphService(relationshipService, queryService)),
+		httphandler.WithImpactService(service.NewImpactService(relationshipService, queryService)),
+		httphandler.WithRuleModuleService(ruleModuleService),
+		httphandler.WithAdmissionService(admission),
+		httphandler.WithJobService(jobService),
+		httphandler.WithHealthService(service.NewHealthService(relationshipService, domain.HealthConfig{},
+			service.WithQueueCheck(admission))),
+		httphandler.WithMetricsHandler(metrics.NewPrometheusMetrics().Handler()),
+		httphandler.WithAuditService(service.NewAuditService(auditLog, domain.AuditConfig{})),
+		httphandler.WithLogLevel(new(slog.LevelVar)),
+		httphandler.WithEffectiveConfig(config.Default()),
+		httphandler.WithAuthenticator(authenticator))
+}
+
+func TestOpenAPIDocumentMatchesRoutes(t *testing.T) {
+	httpAdapter := newFullAdapter(t)
+	server := httptest.NewServer(httpAdapter.GetRouter())
+	defer server.Close()
+
+	// 1. The document is served as JSON and YAML.
+	resp, err := http.Get(server.URL + "/openapi.json")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var raw map[string]any
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&raw))
+	data, err := json.Marshal(raw)
+	require.NoError(t, err)
+	var doc openAPIDocument
+	require.NoError(t, json.Unmarshal(data, &doc))
+
+	resp, err = http.Get(server.URL + "/openapi.yaml")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var fromYAML map[string]any
+	require.NoError(t, yaml.NewDecoder(resp.Body).Decode(&fromYAML))
+	assert.Equal(t, raw["paths"], mustRoundTrip(t, fromYAML)["paths"])
+
+	// 2. Every route is documented and every documented operation is served.
+	// Routes without a method check it themselves, so any documented method
+	// on their path counts.
+	var routes, documented []string
+	anyMethod := make(map[string]bool)
+	for _, pattern := range httpAdapter.Routes() {
+		method, path, ok := strings.Cut(pattern, " ")
+		if !ok {
+			anyMethod[pattern] = true
+			routes = append(routes, "* "+pattern)
+			continue
+		}
+		routes = append(routes, method+" "+path)
+	}
+	for path, item := range doc.Paths {
+		methods := 0
+		for method := range item {
+			if method == "parameters" {
+				continue
+			}
+			methods++
+			if anyMethod[path] {
+				documented = append(documented, "* "+path)
+				continue
+			}
+			documented = append(documented, strings.ToUpper(method)+" "+path)
+		}
+		assert.NotZero(t, methods, "path %s has no operations", path)
+	}
+	sort.Strings(routes)
+	sort.Strings(documented)
+	assert.Equal(t, routes, uniqueStrings(documented))
+
+	// 3. Every reference points at a defined component.
+	refs := regexp.MustCompile(`"\$ref":"#/components/(\w+)/(\w+)"`).FindAllStringSubmatch(string(data), -1)
+	require.NotEmpty(t, refs)
+	components := raw["components"].(map[string]any)
+	for _, ref := range refs {
+		section, _ := components[ref[1]].(map[string]any)
+		assert.Contains(t, section, ref[2], "unresolved reference %s/%s", ref[1], ref[2])
+	}
+
+	// 4. Schemas of the domain types list exactly their JSON fields.
+	for name, value := range map[string]any{
+		"QueryRequest":        domain.QueryRequest{},
+		"TimeRange":           domain.TimeRange{},
+		"QueryResult":         domain.QueryResult{},
+		"QuerySummary":        domain.QuerySummary{},
+		"QueryProfile":        domain.QueryProfile{},
+		"PhaseProfile":        domain.PhaseProfile{},
+		"StratumProfile":      domain.StratumProfile{},
+		"Error":               errorBody{},
+		"ServiceRelationship": domain.ServiceRelationship{},
+		"RelationshipConfig":  domain.RelationshipConfig{},
+		"LintIssue":           domain.LintIssue{},
+		"Graph":               domain.Graph{},
+		"GraphNode":           domain.GraphNode{},
+		"GraphEdge":           domain.GraphEdge{},
+		"ImpactRequest":       domain.ImpactRequest{},
+		"ImpactResult":        domain.ImpactResult{},
+		"ImpactedService":     domain.ImpactedService{},
+		"RuleModuleSet":       domain.RuleModuleSet{},
+		"RuleModule":          domain.RuleModule{},
+		"AdmissionStats":      domain.AdmissionStats{},
+		"Job":                 domain.Job{},
+		"JobProgress":         domain.JobProgress{},
+		"JobError":            domain.JobError{},
+		"PredicateCatalog":    domain.PredicateCatalog{},
+		"PredicateInfo":       domain.PredicateInfo{},
+		"HealthReport":        domain.HealthReport{},
+		"HealthCheck":         domain.HealthCheck{},
+		"AuditSearchResult":   domain.AuditSearchResult{},
+		"AuditRecord":         domain.AuditRecord{},
+		"LogLevel":            domain.LogLevel{},
+		"ProgramInfo":         domain.ProgramInfo{},
+		"PredicateSignature":  domain.PredicateSignature{},
+		"SourceState":         domain.SourceState{},
+		"RelationshipState":   domain.RelationshipState{},
+	} {
+		schema, ok := doc.Components.Schemas[name]
+		if !assert.True(t, ok, "schema %s is missing", name) {
+			continue
+		}
+		var properties []string
+		for property := range schema.Properties {
+			properties = append(properties, property)
+		}
+		sort.Strings(properties)
+		assert.Equal(t, jsonFields(reflect.TypeOf(value)), properties, "schema %s", name)
+	}
+}
+
+// jsonFields returns the sorted JSON field names of a struct type.
+func jsonFields(typ reflect.Type) []string {
+	var fields []string
+	for i := range typ.NumField() {
+		field := typ.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, name)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func mustRoundTrip(t *testing.T, v any) map[string]any {
+	t.Helper()
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	var out map[string]any
+	require.NoError(t, json.Unmarshal(data, &out))
+	return out
+}
+
+func uniqueStrings(s []string) []string {
+	var out []string
+	for i, v := range s {
+		if i == 0 || v != s[i-1] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"log/slog"
+	"mangle-service/internal/adapters/file"
+	httphandler "mangle-service/internal/adapters/http"
+	"mangle-service/internal/adapters/mock"
+	"mangle-service/internal/core/domain"
+	"mangle-service/internal/core/service"
+	"mangle-service/pkg/client"
+	"mangle-service/pkg/logger"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEndToEndQueryProfile(t *testing.T) {
+	// 1. Setup: a dependency chain a -> b -> c -> d and a separate e -> f,
+	// and a service that considers every query slow.
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "relationships.yaml"), `
+relationships:
+  - service: "a"
+    depends_on: ["b"]
+  - service: "b"
+    depends_on: ["c"]
+  - service: "c"
+    depends_on: ["d"]
+  - service: "e"
+    depends_on: ["f"]
+`)
+	relationshipService := service.NewRelationshipService(file.NewConfigLoader(), nil)
+	require.NoError(t, relationshipService.LoadRelationships(filepath.Join(dir, "relationships.yaml")))
+	var out logBuffer
+	log := logger.New(slog.LevelInfo, logger.WithOutput(&out))
+	newClient := func(threshold time.Duration) *client.Client {
+		queryService := service.NewQueryService(service.NewLogService(mock.NewMockLogAdapter()), relationshipService, log,
+			service.WithSlowQueryThreshold(threshold))
+		server := httptest.NewServer(httphandler.NewAdapter(queryService, log, "8080").GetRouter())
+		t.Cleanup(server.Close)
+		c, err := client.New(server.URL, client.WithRetries(0, 0))
+		require.NoError(t, err)
+		return c
+	}
+	slow := newClient(time.Nanosecond)
+
+	// The recursive depends_on needs three iterations to reach d from a;
+	// clear negates blocked and so is evaluated in a later stratum.
+	req := client.QueryRequest{Query: `blocked(X) :- depends_on(X, "d").
+clear(X) :- calls(X, _), !blocked(X).
+clear(X).`}
+
+	// 2. Without the debug flag, the result has no profile.
+	result, err := slow.Query(t.Context(), req)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Count)
+	assert.Nil(t, result.Profile)
+
+	// 3. With it, the profile breaks the query down.
+	req.Debug = true
+	result, err = slow.Query(t.Context(), req)
+	require.NoError(t, err)
+	profile := result.Profile
+	require.NotNil(t, profile)
+	var phases []domain.QueryPhase
+	var total float64
+	for _, phase := range profile.Phases {
+		phases = append(phases, phase.Phase)
+		total += phase.DurationMS
+	}
+	assert.Equal(t, []domain.QueryPhase{
+		domain.PhaseParse, domain.PhaseFetch, domain.PhaseAnalysis, domain.PhaseEvaluation, domain.PhaseExtraction,
+	}, phases)
+	assert.LessOrEqual(t, total, profile.DurationMS)
+	assert.Equal(t, 4, profile.Facts["calls"])
+	assert.NotZero(t, profile.Facts["logs"])
+	assert.Equal(t, map[string]int{"depends_on": 7, "blocked": 3, "clear": 1}, profile.Derived)
+
+	stratumOf := func(predicate string) int {
+		for i, stratum := range profile.Strata {
+			if slices.Contains(stratum.Predicates, predicate) {
+				return i
+			}
+		}
+		t.Fatalf("no stratum defines %s", predicate)
+		return -1
+	}
+	assert.Less(t, stratumOf("blocked"), stratumOf("clear"))
+	dependsOn := profile.Strata[stratumOf("depends_on")]
+	assert.Equal(t, 3, dependsOn.Iterations)
+	var derived, iterations int
+	for _, stratum := range profile.Strata {
+		derived += stratum.Derived
+		iterations += stratum.Iterations
+	}
+	assert.Equal(t, 11, derived)
+	assert.Equal(t, profile.Iterations, iterations)
+
+	// Streamed queries return the profile in their summary.
+	summary, err := slow.StreamQuery(t.Context(), req, func(client.LogEntry) error { return nil })
+	require.NoError(t, err)
+	require.NotNil(t, summary.Profile)
+	assert.Equal(t, profile.Derived, summary.Profile.Derived)
+
+	// 4. Queries over the threshold log their profile, failed ones with
+	// their error code.
+	_, err = slow.Query(t.Context(), client.QueryRequest{Query: `clear(X) :- !blocked(X).
+clear(X).`})
+	require.Error(t, err)
+	var logged []map[string]any
+	for _, line := range out.lines(t) {
+		if line["msg"] == "slow query" {
+			logged = append(logged, line)
+		}
+	}
+	require.Len(t, logged, 4)
+	assert.Equal(t, "WARN", logged[0]["level"])
+	loggedProfile := logged[0]["profile"].(map[string]any)
+	assert.Equal(t, float64(profile.Iterations), loggedProfile["iterations"])
+	assert.NotContains(t, logged[0], "query")
+	assert.Equal(t, string(domain.CodeAnalysisError), logged[3]["error_code"])
+
+	// 5. Queries under the threshold are not logged.
+	fast := newClient(time.Hour)
+	_, err = fast.Query(t.Context(), req)
+	require.NoError(t, err)
+	for _, line := range out.lines(t) {
+		assert.NotEqual(t, "slow query", line["msg"])
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"mangle-service/internal/adapters/auth"
+	"mangle-service/internal/adapters/file"
+	httphandler "mangle-service/internal/adapters/http"
+	"mangle-service/internal/adapters/mock"
+	"mangle-service/internal/core/domain"
+	"mangle-service/internal/core/service"
+	"mangle-service/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEndToEndRelationshipManagement(t *testing.T) {
+	// 1. Setup
+	log := logger.New(slog.LevelDebug)
+	dir := t.TempDir()
+	seedPath := filepath.Join(dir, "relationships.yaml")
+	require.NoError(t, os.WriteFile(seedPath, []byte(`
+relationships:
+  - service: "api-gateway"
+    depends_on: ["order-service"]
+`), 0o644))
+	storePath := filepath.Join(dir, "relationships.store.json")
+	writeFile(t, filepath.Join(dir, "api-keys.yaml"), `
+keys:
+  - subject: "ops"
+    key: "ops-key"
+    roles: ["admin"]
+  - subject: "dev"
+    key: "dev-key"
+    roles: ["developer"]
+`)
+	authenticator, err := auth.NewAuthenticator(auth.Config{APIKeysPath: filepath.Join(dir, "api-keys.yaml")})
+	require.NoError(t, err)
+
+	relationshipService := service.NewRelationshipService(file.NewConfigLoader(), file.NewRelationshipStore(storePath))
+	require.NoError(t, relationshipService.LoadRelationships(seedPath))
+
+	queryService := service.NewQueryService(service.NewLogService(mock.NewMockLogAdapter()), relationshipService, log)
+	httpAdapter := httphandler.NewAdapter(queryService, log, "8080",
+		httphandler.WithRelationshipService(relationshipService),
+		httphandler.WithAuthenticator(authenticator),
+		httphandler.WithAdminRoles("admin"))
+
+	server := httptest.NewServer(httpAdapter.GetRouter())
+	defer server.Close()
+
+	// 2. The seeded graph is served with its version as ETag.
+	resp := doRequest(t, http.MethodGet, server.URL+"/relationships", "dev-key", "", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	etag := resp.Header.Get("ETag")
+	assert.Equal(t, `"1"`, etag)
+
+	// 3. Writes without If-Match are rejected.
+	resp = doRequest(t, http.MethodPut, server.URL+"/relationships/order-service", "ops-key", "", `{"depends_on": ["payment-service"]}`)
+	assert.Equal(t, http.StatusPreconditionRequired, resp.StatusCode)
+
+	// 4. A write based on the current version succeeds and bumps the version.
+	resp = doRequest(t, http.MethodPut, server.URL+"/relationships/order-service", "ops-key", etag, `{"depends_on": ["payment-service"]}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `"2"`, resp.Header.Get("ETag"))
+
+	// 5. A write based on a stale version conflicts.
+	resp = doRequest(t, http.MethodPut, server.URL+"/relationships/api-gateway/depends_on/inventory-service", "ops-key", etag, "")
+	assert.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+
+	// 6. The new edge is visible to queries.
+	queryReq, err := json.Marshal(domain.QueryRequest{Query: `depends_on("api-gateway", X).`})
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/query", bytes.NewReader(queryReq))
+	require.NoError(t, err)
+	req.Header.Set("X-API-Key", "dev-key")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var result domain.QueryResult
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.ElementsMatch(t, []domain.LogEntry{{"X": "order-service"}, {"X": "payment-service"}}, result.Results)
+
+	// 7. The change survives a restart from the same store.
+	restarted := service.NewRelationshipService(file.NewConfigLoader(), file.NewRelationshipStore(storePath))
+	require.NoError(t, restarted.LoadRelationships(seedPath))
+	config := restarted.GetConfig()
+	assert.Equal(t, int64(2), config.Version)
+	assert.Len(t, config.Relationships, 2)
+	rel, version, err := restarted.GetService("order-service")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"payment-service"}, rel.DependsOn)
+	assert.Equal(t, int64(2), version)
+
+	// 8. Only admins may change the graph, and without authentication it
+	// cannot be changed over HTTP at all.
+	resp = doRequest(t, http.MethodDelete, server.URL+"/relationships/api-gateway", "dev-key", "*", "")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	open := httptest.NewServer(httphandler.NewAdapter(queryService, log, "8080",
+		httphandler.WithRelationshipService(relationshipService)).GetRouter())
+	defer open.Close()
+	resp = doRequest(t, http.MethodPut, open.URL+"/relationships/api-gateway/depends_on/inventory-service", "", "*", "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp = doRequest(t, http.MethodDelete, open.URL+"/relationships/api-gateway", "", "*", "")
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	resp = doRequest(t, http.MethodGet, open.URL+"/relationships/api-gateway", "", "", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	_, _, err = relationshipService.GetService("api-gateway")
+	require.NoError(t, err)
+}
+
+func doRequest(t *testing.T, method, url, apiKey, ifMatch, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, url, bytes.NewBufferString(body))
+	require.NoError(t, err)
+	if apiKey != "" {
+		req.Header.Set("X-API-Key", apiKey)
+	}
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"mangle-service/internal/adapters/auth"
+	"mangle-service/internal/adapters/file"
+	httphandler "mangle-service/internal/adapters/http"
+	"mangle-service/internal/core/domain"
+	"mangle-service/internal/core/service"
+	"mangle-service/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEndToEndRuleModules(t *testing.T) {
+	// 1. Setup
+	log := logger.New(slog.LevelDebug)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "relationships.yaml"), `
+relationships:
+  - service: "api-gateway"
+    depends_on: ["order-service"]
+`)
+	rulesDir := filepath.Join(dir, "rules")
+	writeFile(t, filepath.Join(rulesDir, "cascading.mg"), `
+failed(Service, Trace) :- logs(Trace, Service, 500, _).
+cascading_failure(Upstream, Downstream) :-
+  depends_on(Upstream, Downstream),
+  failed(Upstream, Trace),
+  failed(Downstream, Trace).
+`)
+	writeFile(t, filepath.Join(rulesDir, "root-cause.mg"), `
+Package triage!
+Use cascading!
+failing_upstream(Service) :- cascading.cascading_failure(Service, _).
+root_cause(Service) :-
+  cascading.cascading_failure(_, Service),
+  !failing_upstream(Service).
+`)
+	// Modules whose inputs are not part of a query must not break it.
+	writeFile(t, filepath.Join(rulesDir, "tracing.mg"), `slow_span(Span) :- span(Span, Duration), :gt(Duration, 1000).`)
+	writeFile(t, filepath.Join(rulesDir, "README.md"), `Not a rule module.`)
+
+	relationshipService := service.NewRelationshipService(file.NewConfigLoader(), nil)
+	require.NoError(t, relationshipService.LoadRelationships(filepath.Join(dir, "relationships.yaml")))
+	ruleModuleService := service.NewRuleModuleService(file.NewRuleModuleLoader())
+	require.NoError(t, ruleModuleService.LoadModules(rulesDir))
+	queryService := service.NewQueryService(service.NewLogService(&cascadingFailureLogAdapter{}), relationshipService, log,
+		service.WithRuleModules(ruleModuleService))
+	httpAdapter := httphandler.NewAdapter(queryService, log, "8080", httphandler.WithRuleModuleService(ruleModuleService))
+
+	server := httptest.NewServer(httpAdapter.GetRouter())
+	defer server.Close()
+	// Reloading changes the rules of every caller, so only admins may do it.
+	writeFile(t, filepath.Join(dir, "api-keys.yaml"), `
+keys:
+  - subject: "ops"
+    key: "ops-key"
+    roles: ["admin"]
+  - subject: "dev"
+    key: "dev-key"
+    roles: ["developer"]
+`)
+	authenticator, err := auth.NewAuthenticator(auth.Config{APIKeysPath: filepath.Join(dir, "api-keys.yaml")})
+	require.NoError(t, err)
+	adminServer := httptest.NewServer(httphandler.NewAdapter(queryService, log, "8080",
+		httphandler.WithRuleModuleService(ruleModuleService),
+		httphandler.WithAuthenticator(authenticator),
+		httphandler.WithAdminRoles("admin")).GetRouter())
+	defer adminServer.Close()
+	reload := func(url, apiKey string) int {
+		req, err := http.NewRequest(http.MethodPost, url+"/rules/reload", nil)
+		require.NoError(t, err)
+		if apiKey != "" {
+			req.Header.Set("X-API-Key", apiKey)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// 2. The loaded modules are listed with their namespaced predicates.
+	resp, err := http.Get(server.URL + "/rules")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var modules domain.RuleModuleSet
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&modules))
+	require.Len(t, modules.Modules, 3)
+	assert.Equal(t, "cascading", modules.Modules[0].Name)
+	assert.Equal(t, []string{"cascading.cascading_failure/2", "cascading.failed/2"}, modules.Modules[0].Predicates)
+	assert.Equal(t, "triage", modules.Modules[1].Name)
+	assert.Equal(t, []string{"triage.failing_upstream/1", "triage.root_cause/1"}, modules.Modules[1].Predicates)
+
+	// 3. Queries can use module predicates, including ones built on other modules.
+	assert.Equal(t, []domain.LogEntry{{"Upstream": "api-gateway", "Downstream": "order-service"}},
+		runQuery(t, server.URL, `cascading.cascading_failure(Upstream, Downstream).`))
+	assert.Equal(t, []domain.LogEntry{{"Service": "order-service"}},
+		runQuery(t, server.URL, `triage.root_cause(Service).`))
+
+	// 4. A broken module is rejected on reload and the previous modules stay active.
+	writeFile(t, filepath.Join(rulesDir, "broken.mg"), `broken(X) :- logs(_, Y, _, _).`)
+	assert.Equal(t, http.StatusUnprocessableEntity, reload(adminServer.URL, "ops-key"))
+	assert.Equal(t, []domain.LogEntry{{"Service": "order-service"}},
+		runQuery(t, server.URL, `triage.root_cause(Service).`))
+
+	// 5. Once fixed, the reload picks up the new module.
+	writeFile(t, filepath.Join(rulesDir, "broken.mg"), `erroring(Service) :- logs(_, Service, 500, _).`)
+	assert.Equal(t, http.StatusOK, reload(adminServer.URL, "ops-key"))
+	assert.ElementsMatch(t, []domain.LogEntry{{"Service": "order-service"}, {"Service": "api-gateway"}},
+		runQuery(t, server.URL, `broken.erroring(Service).`))
+
+	// 6. Other callers may not reload, and without authentication the
+	// endpoint is not served.
+	assert.Equal(t, http.StatusForbidden, reload(adminServer.URL, "dev-key"))
+	assert.Equal(t, http.StatusNotFound, reload(server.URL, ""))
+}
+
+func TestRuleModulesRejectDuplicateNamespaces(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.mg"), "Package shared!\nfoo(1).")
+	writeFile(t, filepath.Join(dir, "b.mg"), "Package shared!\nbar(1).")
+
+	err := service.NewRuleModuleService(file.NewRuleModuleLoader()).LoadModules(dir)
+	assert.ErrorContains(t, err, `module "shared" is already defined`)
+}
+
+func runQuery(t *testing.T, url, query string) []domain.LogEntry {
+	t.Helper()
+	body, err := json.Marshal(domain.QueryRequest{Query: query})
+	require.NoError(t, err)
+	resp, err := http.Post(url+"/query", "application/json", bytes.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var result domain.QueryResult
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	return result.Results
+}
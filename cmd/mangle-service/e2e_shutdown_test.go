@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"mangle-service/internal/adapters/file"
+	httphandler "mangle-service/internal/adapters/http"
+	"mangle-service/internal/core/domain"
+	"mangle-service/internal/core/service"
+	"mangle-service/pkg/client"
+	"mangle-service/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEndToEndGracefulShutdown(t *testing.T) {
+	// 1. Setup: the default source answers once released, the archive never
+	// does, so that its queries only end when they are cancelled.
+	log := logger.New(slog.LevelDebug)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "relationships.yaml"), `
+relationships:
+  - service: "api-gateway"
+    depends_on: ["order-service"]
+`)
+	relationshipService := service.NewRelationshipService(file.NewConfigLoader(), nil)
+	require.NoError(t, relationshipService.LoadRelationships(filepath.Join(dir, "relationships.yaml")))
+	logs := &blockingLogAdapter{release: make(chan struct{})}
+	archive := &blockingLogAdapter{release: make(chan struct{})}
+	drain := service.NewDrainController()
+	queryService := service.NewQueryService(service.NewLogService(logs), relationshipService, log,
+		service.WithLogSource("archive", archive),
+		service.WithDrainController(drain))
+	jobService := service.NewJobService(queryService, log, domain.JobConfig{Workers: 1, MaxQueued: 1, ResultTTL: time.Minute},
+		service.WithJobDrainController(drain))
+	t.Cleanup(jobService.Close)
+	httpAdapter := httphandler.NewAdapter(queryService, log, "8080",
+		httphandler.WithJobService(jobService),
+		httphandler.WithHealthService(service.NewHealthService(relationshipService, domain.HealthConfig{},
+			service.WithShutdownCheck(drain))))
+	server := httptest.NewServer(httpAdapter.GetRouter())
+	defer server.Close()
+	c, err := client.New(server.URL, client.WithRetries(0, 0))
+	require.NoError(t, err)
+
+	query := func(source string) client.QueryRequest {
+		return client.QueryRequest{Query: `depends_on(Upstream, Downstream).`, Sources: []string{source}}
+	}
+	type outcome struct {
+		result *client.QueryResult
+		err    error
+	}
+	run := func(source string) chan outcome {
+		done := make(chan outcome, 1)
+		go func() {
+			result, err := c.Query(context.Background(), query(source))
+			done <- outcome{result, err}
+		}()
+		return done
+	}
+	assertShuttingDown := func(t *testing.T, err error) *client.Error {
+		t.Helper()
+		apiErr := assertAPIError(t, err, http.StatusServiceUnavailable)
+		assert.Equal(t, string(domain.CodeOverloaded), apiErr.Code)
+		assert.Equal(t, "shutting_down", apiErr.Details["reason"])
+		assert.Equal(t, time.Second, apiErr.RetryAfter)
+		return apiErr
+	}
+
+	// 2. A query on each source and a job on the archive are running.
+	report, err := c.HealthDetails(t.Context())
+	require.NoError(t, err)
+	assert.True(t, report.Ready())
+	fromLogs := run(domain.DefaultLogSource)
+	fromArchive := run("archive")
+	job, err := c.SubmitJob(t.Context(), query("archive"))
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return drain.InFlight() == 3 }, time.Second, 5*time.Millisecond)
+
+	// 3. Shutting down makes the service unready first.
+	drain.Shutdown()
+	report, err = c.HealthDetails(t.Context())
+	require.NoError(t, err)
+	assert.False(t, report.Ready())
+	for _, check := range report.Checks {
+		if check.Kind == domain.HealthCheckShutdown {
+			assert.Equal(t, domain.HealthFail, check.Status)
+			assert.Equal(t, float64(3), check.Details["in_flight"])
+		}
+	}
+
+	// 4. Draining refuses new queries and jobs, but lets the running ones
+	// finish.
+	drainCtx, expire := context.WithCancel(t.Context())
+	drained := make(chan int, 1)
+	go func() { drained <- drain.Drain(drainCtx) }()
+	require.Eventually(t, drain.Draining, time.Second, 5*time.Millisecond)
+	_, err = c.Query(t.Context(), query(domain.DefaultLogSource))
+	assertShuttingDown(t, err)
+	_, err = c.SubmitJob(t.Context(), query(domain.DefaultLogSource))
+	assertShuttingDown(t, err)
+
+	close(logs.release)
+	finished := <-fromLogs
+	require.NoError(t, finished.err)
+	assert.Equal(t, 1, finished.result.Count)
+	assert.Equal(t, 2, drain.InFlight())
+	select {
+	case <-drained:
+		t.Fatal("the drain ended with queries in flight")
+	default:
+	}
+
+	// 5. At the deadline, the queries still running are cancelled through
+	// their contexts and fail as retryable.
+	expire()
+	assert.Equal(t, 2, <-drained)
+	assert.Equal(t, 0, drain.InFlight())
+	apiErr := assertShuttingDown(t, (<-fromArchive).err)
+	assert.Contains(t, apiErr.Message, "cancelled because the service is shutting down")
+	failed, err := c.WaitJob(t.Context(), job.ID, 10*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, domain.JobFailed, failed.Status)
+	require.NotNil(t, failed.Error)
+	assert.Equal(t, domain.CodeOverloaded, failed.Error.Code)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"mangle-service/internal/adapters/file"
+	httphandler "mangle-service/internal/adapters/http"
+	"mangle-service/internal/core/domain"
+	"mangle-service/internal/core/service"
+	"mangle-service/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEndToEndStreamingQuery(t *testing.T) {
+	// 1. Setup
+	log := logger.New(slog.LevelDebug)
+	path := filepath.Join(t.TempDir(), "relationships.yaml")
+	writeFile(t, path, `
+relationships:
+  - service: "api-gateway"
+    depends_on: ["order-service"]
+`)
+	relationshipService := service.NewRelationshipService(file.NewConfigLoader(), nil)
+	require.NoError(t, relationshipService.LoadRelationships(path))
+	queryService := service.NewQueryService(service.NewLogService(&cascadingFailureLogAdapter{}), relationshipService, log)
+	httpAdapter := httphandler.NewAdapter(queryService, log, "8080")
+
+	server := httptest.NewServer(httpAdapter.GetRouter())
+	defer server.Close()
+
+	post := func(accept, query string) *http.Response {
+		body, err := json.Marshal(domain.QueryRequest{Query: query})
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/query", strings.NewReader(string(body)))
+		require.NoError(t, err)
+		req.Header.Set("Accept", accept)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	// 2. NDJSON: one record per result, then a summary.
+	resp := post("application/x-ndjson", `logs(Trace, Service, 500, _).`)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/x-ndjson", resp.Header.Get("Content-Type"))
+	var records []map[string]json.RawMessage
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var record map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+	require.Len(t, records, 3)
+	var services []string
+	for _, record := range records[:2] {
+		var entry domain.LogEntry
+		require.NoError(t, json.Unmarshal(record["result"], &entry))
+		assert.Equal(t, "trace-xyz", entry["Trace"])
+		services = append(services, entry["Service"].(string))
+	}
+	assert.ElementsMatch(t, []string{"api-gateway", "order-service"}, services)
+	var summary domain.QuerySummary
+	require.NoError(t, json.Unmarshal(records[2]["summary"], &summary))
+	assert.Equal(t, 2, summary.Count)
+
+	// 3. Server-Sent Events use the record kinds as event types.
+	resp = post("text/event-stream", `logs(Trace, Service, 500, _).`)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	var events []string
+	scanner = bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if event, ok := strings.CutPrefix(scanner.Text(), "event: "); ok {
+			events = append(events, event)
+		}
+	}
+	assert.Equal(t, []string{"result", "result", "summary"}, events)
+
+	// 4. Errors found before the first result keep their status code.
+	resp = post("application/x-ndjson", `logs(Trace`)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	// 5. A plain JSON client is unaffected.
+	resp = post("application/json, */*", `logs(Trace, Service, 500, _).`)
+	defer resp.Body.Close()
+	var result domain.QueryResult
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, 2, result.Count)
+}
+
+func TestStreamQueryStopsEarly(t *testing.T) {
+	log := logger.New(slog.LevelDebug)
+	path := filepath.Join(t.TempDir(), "relationships.yaml")
+	writeFile(t, path, `
+relationships:
+  - service: "api-gateway"
+    depends_on: ["order-service"]
+`)
+	relationshipService := service.NewRelationshipService(file.NewConfigLoader(), nil)
+	require.NoError(t, relationshipService.LoadRelationships(path))
+	queryService := service.NewQueryService(service.NewLogService(&cascadingFailureLogAdapter{}), relationshipService, log)
+	query := domain.QueryRequest{Query: `logs(Trace, Service, Status, Message).`}
+
+	// A failing consumer, such as a closed connection, stops the stream.
+	errClosed := errors.New("connection closed")
+	calls := 0
+	_, err := queryService.StreamQuery(context.Background(), query, func(domain.LogEntry) error {
+		calls++
+		return errClosed
+	})
+	assert.ErrorIs(t, err, errClosed)
+	assert.Equal(t, 1, calls)
+
+	// So does a canceled context.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	calls = 0
+	_, err = queryService.StreamQuery(ctx, query, func(domain.LogEntry) error {
+		calls++
+		cancel()
+		return nil
+	})
+	var qerr *domain.QueryError
+	require.ErrorAs(t, err, &qerr)
+	assert.Equal(t, domain.CodeCanceled, qerr.Code)
+	assert.Equal(t, 1, calls)
+}
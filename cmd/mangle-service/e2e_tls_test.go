@@ -0,0 +1,222 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"log/slog"
+	"mangle-service/internal/adapters/auth"
+	"mangle-service/internal/adapters/certs"
+	"mangle-service/internal/adapters/file"
+	httphandler "mangle-service/internal/adapters/http"
+	"mangle-service/internal/adapters/mock"
+	"mangle-service/internal/core/service"
+	"mangle-service/pkg/client"
+	"mangle-service/pkg/logger"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCA issues certificates for tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue signs template and returns the PEM-encoded certificate and key.
+func (ca *testCA) issue(t *testing.T, template *x509.Certificate) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+	template.SerialNumber = serial
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func (ca *testCA) serverCert(t *testing.T, name string) (certPEM, keyPEM []byte) {
+	return ca.issue(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: name},
+		IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1)},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+}
+
+func (ca *testCA) clientCert(t *testing.T, template *x509.Certificate) tls.Certificate {
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	certPEM, keyPEM := ca.issue(t, template)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+	return cert
+}
+
+func TestEndToEndTLS(t *testing.T) {
+	// 1. Setup: an HTTPS server whose client certificates are optional. The
+	// on-call bot is identified by the SPIFFE ID in its certificate and has
+	// the admin role; alice uses an API key.
+	log := logger.New(slog.LevelDebug)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "relationships.yaml"), `
+relationships:
+  - service: "api-gateway"
+    depends_on: ["order-service"]
+`)
+	writeFile(t, filepath.Join(dir, "api-keys.yaml"), `
+keys:
+  - subject: "alice"
+    key: "alice-key"
+    roles: ["developer"]
+`)
+	writeFile(t, filepath.Join(dir, "client-certs.yaml"), `
+certificates:
+  - uri: "spiffe://example.org/ns/ops/sa/oncall-bot"
+    subject: "oncall-bot"
+    roles: ["sre"]
+  - common_name: "reporting-job"
+    roles: ["developer"]
+`)
+	serverCA := newTestCA(t, "server CA")
+	clientCA := newTestCA(t, "client CA")
+	certFile, keyFile, caFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), filepath.Join(dir, "client-ca.pem")
+	certPEM, keyPEM := serverCA.serverCert(t, "mangle-service v1")
+	writeFile(t, certFile, string(certPEM))
+	writeFile(t, keyFile, string(keyPEM))
+	writeFile(t, caFile, string(clientCA.pem))
+
+	relationshipService := service.NewRelationshipService(file.NewConfigLoader(), nil)
+	require.NoError(t, relationshipService.LoadRelationships(filepath.Join(dir, "relationships.yaml")))
+	authenticator, err := auth.NewAuthenticator(auth.Config{
+		APIKeysPath:     filepath.Join(dir, "api-keys.yaml"),
+		ClientCertsPath: filepath.Join(dir, "client-certs.yaml"),
+	})
+	require.NoError(t, err)
+	reloader, err := certs.NewReloader(certs.Config{
+		CertFile:     certFile,
+		KeyFile:      keyFile,
+		ClientCAFile: caFile,
+		ClientAuth:   certs.ClientAuthOptional,
+	}, log)
+	require.NoError(t, err)
+	queryService := service.NewQueryService(service.NewLogService(mock.NewMockLogAdapter()), relationshipService, log)
+	httpAdapter := httphandler.NewAdapter(queryService, log, "8443",
+		httphandler.WithAuthenticator(authenticator),
+		httphandler.WithLogLevel(new(slog.LevelVar)),
+		httphandler.WithAdminRoles("sre"),
+		httphandler.WithTLSConfig(reloader.TLSConfig()))
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go httpAdapter.Serve(lis)
+	t.Cleanup(func() { httpAdapter.Stop(t.Context()) })
+	serverURL := "https://" + lis.Addr().String()
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(serverCA.pem)
+	newClient := func(opts ...client.Option) *client.Client {
+		c, err := client.New(serverURL, append(opts, client.WithRetries(0, 0))...)
+		require.NoError(t, err)
+		return c
+	}
+	withCert := func(cert tls.Certificate) client.Option {
+		return client.WithTLSConfig(&tls.Config{RootCAs: roots, Certificates: []tls.Certificate{cert}})
+	}
+	query := client.QueryRequest{Query: `depends_on(Upstream, Downstream).`}
+
+	// 2. Without a client certificate, callers authenticate as before.
+	alice := newClient(client.WithTLSConfig(&tls.Config{RootCAs: roots}), client.WithAPIKey("alice-key"))
+	result, err := alice.Query(t.Context(), query)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Count)
+	_, err = alice.LogLevel(t.Context())
+	assertAPIError(t, err, http.StatusForbidden)
+
+	// 3. A certificate identity maps to a subject and roles, here the admin
+	// role; the common name is used if no alternative name matches.
+	bot := newClient(withCert(clientCA.clientCert(t, &x509.Certificate{
+		Subject: pkix.Name{CommonName: "oncall-bot.ops"},
+		URIs:    []*url.URL{{Scheme: "spiffe", Host: "example.org", Path: "/ns/ops/sa/oncall-bot"}},
+	})))
+	_, err = bot.LogLevel(t.Context())
+	require.NoError(t, err)
+	reporting := newClient(withCert(clientCA.clientCert(t, &x509.Certificate{Subject: pkix.Name{CommonName: "reporting-job"}})))
+	_, err = reporting.Query(t.Context(), query)
+	require.NoError(t, err)
+
+	// 4. A valid certificate of an unknown identity is refused, unless the
+	// request carries other credentials, which take precedence.
+	stranger := clientCA.clientCert(t, &x509.Certificate{Subject: pkix.Name{CommonName: "stranger"}})
+	_, err = newClient(withCert(stranger)).Query(t.Context(), query)
+	assertAPIError(t, err, http.StatusUnauthorized)
+	_, err = newClient(withCert(stranger), client.WithAPIKey("alice-key")).Query(t.Context(), query)
+	require.NoError(t, err)
+
+	// 5. Certificates of other CAs fail the handshake.
+	forged := newTestCA(t, "client CA").clientCert(t, &x509.Certificate{Subject: pkix.Name{CommonName: "reporting-job"}})
+	_, err = newClient(withCert(forged)).Query(t.Context(), query)
+	require.Error(t, err)
+	var apiErr *client.Error
+	assert.False(t, errors.As(err, &apiErr), "expected a handshake error, got %v", err)
+
+	// 6. A rotated certificate is picked up by new connections without a
+	// restart; broken files leave the current one in use.
+	servedName := func() string {
+		conn, err := tls.Dial("tcp", lis.Addr().String(), &tls.Config{RootCAs: roots})
+		if err != nil {
+			return err.Error()
+		}
+		defer conn.Close()
+		return conn.ConnectionState().PeerCertificates[0].Subject.CommonName
+	}
+	assert.Equal(t, "mangle-service v1", servedName())
+	certPEM, keyPEM = serverCA.serverCert(t, "mangle-service v2")
+	writeFile(t, keyFile, string(keyPEM))
+	writeFile(t, certFile, string(certPEM))
+	require.Eventually(t, func() bool { return servedName() == "mangle-service v2" }, 5*time.Second, 50*time.Millisecond)
+
+	writeFile(t, certFile, "not a certificate")
+	time.Sleep(1100 * time.Millisecond)
+	assert.Equal(t, "mangle-service v2", servedName())
+	_, err = bot.LogLevel(t.Context())
+	require.NoError(t, err)
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"mangle-service/internal/adapters/elasticsearch"
+	"mangle-service/internal/adapters/file"
+	httphandler "mangle-service/internal/adapters/http"
+	"mangle-service/internal/core/domain"
+	"mangle-service/internal/core/service"
+	"mangle-service/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// fakeElasticsearch answers searches with two log documents and remembers
+// the traceparent header of each request.
+type fakeElasticsearch struct {
+	mu           sync.Mutex
+	traceparents []string
+}
+
+func (f *fakeElasticsearch) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.traceparents = append(f.traceparents, r.Header.Get("traceparent"))
+	f.mu.Unlock()
+	w.Header().Set("X-Elastic-Product", "Elasticsearch")
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte(`{"hits": {"total": {"value": 2, "relation": "eq"}, "hits": [
+		{"_id": "1", "_source": {"service": "order-service", "status": 500}},
+		{"_id": "2", "_source": {"service": "api-gateway", "status": 200}}
+	]}}`))
+}
+
+func spanAttribute(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestEndToEndTracing(t *testing.T) {
+	// 1. Setup: Elasticsearch as the log source and every span recorded in memory.
+	log := logger.New(slog.LevelDebug)
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	es := &fakeElasticsearch{}
+	esServer := httptest.NewServer(es)
+	defer esServer.Close()
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "relationships.yaml"), `
+relationships:
+  - service: "api-gateway"
+    depends_on: ["order-service"]
+`)
+	relationshipService := service.NewRelationshipService(file.NewConfigLoader(), nil)
+	require.NoError(t, relationshipService.LoadRelationships(filepath.Join(dir, "relationships.yaml")))
+	logAdapter := elasticsearch.NewElasticsearchAdapter(
+		elasticsearch.WithAddresses(esServer.URL), elasticsearch.WithTracerProvider(tp))
+	queryService := service.NewQueryService(logAdapter, relationshipService, log,
+		service.WithTracerProvider(tp))
+	httpAdapter := httphandler.NewAdapter(queryService, log, "8080", httphandler.WithTracerProvider(tp))
+	server := httptest.NewServer(httpAdapter.GetRouter())
+	defer server.Close()
+
+	// 2. Run a query as part of a trace the caller started.
+	const (
+		traceID      = "4bf92f3577b34da6a3ce929d0e0e4736"
+		parentSpanID = "00f067aa0ba902b7"
+	)
+	query := `failed(Doc) :- log.field(Doc, "status", "500").
+failed(Doc).`
+	body, err := json.Marshal(domain.QueryRequest{Query: query})
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/query", bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("traceparent", "00-"+traceID+"-"+parentSpanID+"-01")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// 3. Every span belongs to the caller's trace, nested as the query runs.
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		assert.Equal(t, traceID, span.SpanContext().TraceID().String(), span.Name())
+		if _, ok := spans[span.Name()]; !ok {
+			spans[span.Name()] = span
+		}
+	}
+	parentOf := func(child, parent string) {
+		t.Helper()
+		require.Contains(t, spans, child)
+		require.Contains(t, spans, parent)
+		assert.Equal(t, spans[parent].SpanContext().SpanID(), spans[child].Parent().SpanID(), "%s should be a child of %s", child, parent)
+	}
+
+	require.Contains(t, spans, "POST /query")
+	assert.Equal(t, parentSpanID, spans["POST /query"].Parent().SpanID().String())
+	assert.True(t, spans["POST /query"].Parent().IsRemote())
+	assert.Equal(t, "/query", spanAttribute(spans["POST /query"], "http.route").AsString())
+
+	parentOf("mangle.query", "POST /query")
+	for _, phase := range []string{"parse", "fetch", "analysis", "evaluation", "extraction"} {
+		parentOf("mangle.query."+phase, "mangle.query")
+	}
+	parentOf("mangle.log_source.fetch", "mangle.query.fetch")
+
+	// The Elasticsearch request and its HTTP round trip are traced below
+	// the log source call, and the trace continues into the cluster.
+	var search sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Parent().SpanID() == spans["mangle.log_source.fetch"].SpanContext().SpanID() {
+			search = span
+		}
+	}
+	require.NotNil(t, search, "the Elasticsearch request should be traced")
+	assert.Equal(t, "search", search.Name())
+	var roundTrip sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Parent().SpanID() == search.SpanContext().SpanID() {
+			roundTrip = span
+		}
+	}
+	require.NotNil(t, roundTrip, "the HTTP round trip should be traced")
+	assert.Equal(t, trace.SpanKindClient, roundTrip.SpanKind())
+	require.NotEmpty(t, es.traceparents)
+	assert.Contains(t, es.traceparents[0], traceID)
+
+	// Spans carry the query hash and the fact and result counts, but not the query.
+	querySpan := spans["mangle.query"]
+	assert.Equal(t, domain.QueryRequest{Query: query}.Hash(), spanAttribute(querySpan, "mangle.query.hash").AsString())
+	assert.Equal(t, int64(1), spanAttribute(querySpan, "mangle.query.results").AsInt64())
+	for _, span := range recorder.Ended() {
+		for _, kv := range span.Attributes() {
+			assert.NotContains(t, kv.Value.Emit(), "log.field(", "%s leaks the query in %s", span.Name(), kv.Key)
+		}
+	}
+	assert.Equal(t, domain.DefaultLogSource, spanAttribute(spans["mangle.log_source.fetch"], "mangle.log_source").AsString())
+	// Two documents with two fields each.
+	assert.Equal(t, int64(4), spanAttribute(spans["mangle.log_source.fetch"], "mangle.facts.fetched").AsInt64())
+	// failed/1 and depends_on/2 each derive one fact.
+	assert.Equal(t, int64(2), spanAttribute(spans["mangle.query.evaluation"], "mangle.facts.derived").AsInt64())
+
+	// 4. Failed queries are marked with their error code.
+	recorder = tracetest.NewSpanRecorder()
+	tp.RegisterSpanProcessor(recorder)
+	resp, err = http.Post(server.URL+"/query", "application/json", strings.NewReader(`{"query": "failed(Doc :- x."}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	for _, span := range recorder.Ended() {
+		if span.Name() == "mangle.query" {
+			assert.Equal(t, codes.Error, span.Status().Code)
+			assert.Equal(t, "parse_error", spanAttribute(span, "mangle.error.code").AsString())
+		}
+	}
+}
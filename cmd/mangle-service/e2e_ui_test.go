@@ -0,0 +1,144 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"mangle-service/internal/adapters/auth"
+	"mangle-service/internal/adapters/file"
+	httphandler "mangle-service/internal/adapters/http"
+	"mangle-service/internal/adapters/mock"
+	"mangle-service/internal/core/domain"
+	"mangle-service/internal/core/service"
+	"mangle-service/pkg/client"
+	"mangle-service/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEndToEndPlayground(t *testing.T) {
+	// 1. Setup: a described log source, a restricted one and a rule module.
+	log := logger.New(slog.LevelDebug)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "relationships.yaml"), `
+relationships:
+  - service: "api-gateway"
+    depends_on: ["order-service"]
+`)
+	rulesDir := filepath.Join(dir, "rules")
+	writeFile(t, filepath.Join(rulesDir, "audit.mg"), `
+Decl suspicious_login(User) descr [doc("Users with failed logins.")].
+suspicious_login(User) :- login_failure(User).`)
+	writeFile(t, filepath.Join(dir, "api-keys.yaml"), `
+keys:
+  - subject: "dashboard"
+    key: "dev-key"
+    roles: ["developer"]
+  - subject: "oncall"
+    key: "sre-key"
+    roles: ["sre"]
+`)
+	writeFile(t, filepath.Join(dir, "policy.yaml"), `
+rules:
+  - predicates: ["audit.*"]
+    roles: ["sre"]
+  - sources: ["security"]
+    roles: ["sre"]
+`)
+	relationshipService := service.NewRelationshipService(file.NewConfigLoader(), nil)
+	require.NoError(t, relationshipService.LoadRelationships(filepath.Join(dir, "relationships.yaml")))
+	ruleModuleService := service.NewRuleModuleService(file.NewRuleModuleLoader())
+	require.NoError(t, ruleModuleService.LoadModules(rulesDir))
+	policy, err := file.NewAccessPolicyLoader().Load(filepath.Join(dir, "policy.yaml"))
+	require.NoError(t, err)
+	authenticator, err := auth.NewAuthenticator(auth.Config{APIKeysPath: filepath.Join(dir, "api-keys.yaml")})
+	require.NoError(t, err)
+	queryService := service.NewQueryService(service.NewLogService(mock.NewMockLogAdapter()), relationshipService, log,
+		service.WithRuleModules(ruleModuleService),
+		service.WithLogSource("security", &securityLogAdapter{}),
+		service.WithAccessPolicy(policy))
+	httpAdapter := httphandler.NewAdapter(queryService, log, "8080", httphandler.WithAuthenticator(authenticator))
+	server := httptest.NewServer(httpAdapter.GetRouter())
+	defer server.Close()
+
+	// 2. The playground is served without credentials and loads nothing
+	// from other hosts.
+	external := regexp.MustCompile(`(?:src|href)\s*=\s*["']?(?:https?:)?//|@import|url\(\s*["']?(?:https?:)?//|fetch\(\s*["']https?:`)
+	for path, contentType := range map[string]string{
+		"/ui/":          "text/html; charset=utf-8",
+		"/ui/app.js":    "text/javascript; charset=utf-8",
+		"/ui/style.css": "text/css; charset=utf-8",
+	} {
+		resp, err := http.Get(server.URL + path)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode, path)
+		assert.Equal(t, contentType, resp.Header.Get("Content-Type"), path)
+		assert.Contains(t, resp.Header.Get("Content-Security-Policy"), "default-src 'self'", path)
+		assert.NotRegexp(t, external, string(body), path)
+	}
+
+	resp, err := http.Get(server.URL + "/ui/missing.js")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// 3. The predicate catalog needs credentials.
+	resp, err = http.Get(server.URL + "/predicates")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// 4. It lists what the caller may use.
+	developer, err := client.New(server.URL, client.WithAPIKey("dev-key"))
+	require.NoError(t, err)
+	catalog, err := developer.Predicates(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"logs"}, catalog.Sources)
+	assert.Equal(t, []domain.PredicateInfo{
+		{
+			Name:        "logs",
+			Arity:       3,
+			Args:        []string{"Service", "Status", "Message"},
+			Origin:      domain.OriginLogs,
+			Source:      "logs",
+			Description: "A log line of Service with its HTTP status and message.",
+		},
+		{
+			Name:        "calls",
+			Arity:       2,
+			Args:        []string{"Service", "Dependency"},
+			Origin:      domain.OriginRelationships,
+			Description: "Service calls Dependency directly, as declared in the relationship configuration.",
+		},
+		{
+			Name:        "depends_on",
+			Arity:       2,
+			Args:        []string{"Upstream", "Downstream"},
+			Origin:      domain.OriginRelationships,
+			Description: "Upstream calls Downstream directly or through other services.",
+		},
+	}, catalog.Predicates)
+
+	sre, err := client.New(server.URL, client.WithAPIKey("sre-key"))
+	require.NoError(t, err)
+	catalog, err = sre.Predicates(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"logs", "security"}, catalog.Sources)
+	require.Len(t, catalog.Predicates, 4)
+	assert.Equal(t, domain.PredicateInfo{
+		Name:        "audit.suspicious_login",
+		Arity:       1,
+		Args:        []string{"User"},
+		Origin:      domain.OriginModule,
+		Source:      "audit",
+		Description: "Users with failed logins.",
+	}, catalog.Predicates[3])
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"mangle-service/internal/adapters/file"
+	"mangle-service/internal/adapters/graphexport"
+	"mangle-service/internal/adapters/importer"
+	"mangle-service/internal/config"
+	"mangle-service/internal/core/ports"
+	"mangle-service/internal/core/service"
+	"os"
+	"strings"
+)
+
+// runGraph implements the "graph" command, which renders the relationship
+// graph without starting the server. It returns the process exit code.
+func runGraph(args []string, out, errOut io.Writer) int {
+	flags := flag.NewFlagSet("graph", flag.ContinueOnError)
+	flags.SetOutput(errOut)
+	format := flags.String("format", graphexport.FormatDOT, "output format ("+strings.Join(graphexport.Formats, ", ")+")")
+	query := flags.String("query", "", "highlight the services that appear in the results of this Mangle query")
+	configPath := flags.String("config", "", "relationship sources; overrides relationships.config_path")
+	rulesPath := flags.String("rules", "", "rule modules available to the query; overrides rules.modules_path")
+	cfg, err := config.Load(flags, args, os.LookupEnv)
+	if err != nil {
+		if err != flag.ErrHelp {
+			fmt.Fprintln(errOut, err)
+		}
+		return 2
+	}
+	if *configPath == "" {
+		*configPath = cfg.Relationships.ConfigPath
+	}
+	if *rulesPath == "" {
+		*rulesPath = cfg.Rules.ModulesPath
+	}
+
+	// Diagnostics go to errOut so that out only contains the rendered graph.
+	log := slog.New(slog.NewTextHandler(errOut, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+	var store ports.RelationshipStorePort
+	if cfg.Relationships.StorePath != "" {
+		store = file.NewRelationshipStore(cfg.Relationships.StorePath)
+	}
+	relationshipService := service.NewRelationshipService(importer.NewDefaultMultiLoader(file.NewConfigLoader()), store)
+	if err := relationshipService.LoadRelationships(*configPath); err != nil {
+		fmt.Fprintf(errOut, "failed to load relationships: %v\n", err)
+		return 1
+	}
+
+	var graphService *service.GraphService
+	if *query != "" {
+		var opts []service.QueryOption
+		if *rulesPath != "" {
+			ruleModuleService := service.NewRuleModuleService(file.NewRuleModuleLoader())
+			if err := ruleModuleService.LoadModules(*rulesPath); err != nil {
+				fmt.Fprintf(errOut, "failed to load rule modules: %v\n", err)
+				return 1
+			}
+			opts = append(opts, service.WithRuleModules(ruleModuleService))
+		}
+		logService := service.NewLogService(newLogAdapter(cfg.Sources, log))
+		queryService := service.NewQueryService(logService, relationshipService, log, opts...)
+		graphService = service.NewGraphService(relationshipService, queryService)
+	} else {
+		graphService = service.NewGraphService(relationshipService, nil)
+	}
+
+	graph, err := graphService.GetGraph(context.Background(), *query)
+	if err != nil {
+		fmt.Fprintf(errOut, "failed to build graph: %v\n", err)
+		return 1
+	}
+	if err := graphexport.Render(out, graph, *format); err != nil {
+		fmt.Fprintf(errOut, "%v\n", err)
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"mangle-service/internal/adapters/file"
+	"mangle-service/internal/core/domain"
+	"mangle-service/internal/core/service"
+)
+
+// runLint implements the "lint" command, which checks relationship files
+// without starting the server. It returns the process exit code: 0 if the
+// files are valid, 1 if any has errors (or warnings with -strict), 2 on usage errors.
+func runLint(args []string, out io.Writer) int {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	flags.SetOutput(out)
+	strict := flags.Bool("strict", false, "treat warnings as errors")
+	asJSON := flags.Bool("json", false, "print issues as JSON")
+	flags.Usage = func() {
+		fmt.Fprintln(out, "usage: mangle-service lint [-strict] [-json] relationships.yml...")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	loader := file.NewConfigLoader()
+	failed := false
+	report := make(map[string][]domain.LintIssue)
+	for _, path := range flags.Args() {
+		config, err := loader.Load(path)
+		if err != nil {
+			fmt.Fprintf(out, "%s: %v\n", path, err)
+			failed = true
+			continue
+		}
+		issues := service.LintRelationships(config)
+		report[path] = issues
+		for _, issue := range issues {
+			if issue.Severity == domain.LintError || (*strict && issue.Severity == domain.LintWarning) {
+				failed = true
+			}
+			if !*asJSON {
+				fmt.Fprintf(out, "%s:%s\n", path, issue)
+			}
+		}
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			fmt.Fprintf(out, "failed to encode report: %v\n", err)
+			return 2
+		}
+	}
+	if failed {
+		return 1
+	}
+	return 0
+}
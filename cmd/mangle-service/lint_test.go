@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"mangle-service/internal/adapters/file"
+	"mangle-service/internal/core/service"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLintCommand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "relationships.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`relationships:
+  - service: "api-gateway"
+    depends_on:
+      - "order-servce"
+  - service: "order-service"
+    depends_on: ["payment-service", "order-service"]
+  - service: "payment-service"
+    depends_on: ["order-service"]
+`), 0o644))
+
+	var out bytes.Buffer
+	code := runLint([]string{path}, &out)
+
+	assert.Equal(t, 1, code)
+	assert.Contains(t, out.String(), path+`:4: warning: service "api-gateway" depends on unknown service "order-servce", did you mean "order-service"? [unknown-service]`)
+	assert.Contains(t, out.String(), path+`:5: warning: dependency cycle order-service -> payment-service -> order-service`)
+	assert.Contains(t, out.String(), path+`:6: error: service "order-service" depends on itself [self-dependency]`)
+}
+
+func TestLintCommandValidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "relationships.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`relationships:
+  - service: "api-gateway"
+    depends_on: ["order-service"]
+  - service: "order-service"
+`), 0o644))
+
+	var out bytes.Buffer
+	assert.Equal(t, 0, runLint([]string{path}, &out))
+	assert.Empty(t, out.String())
+}
+
+func TestLintActiveConfigAfterStoreRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "relationships.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`relationships:
+  - service: "api-gateway"
+    depends_on:
+      - "order-servce"
+  - service: "order-service"
+`), 0o644))
+	storePath := filepath.Join(dir, "relationships.json")
+
+	// The first start seeds the store from the file, the second reads the
+	// store back; both report the same issues, without lines.
+	var lints [][]string
+	for range 2 {
+		relationships := service.NewRelationshipService(file.NewConfigLoader(), file.NewRelationshipStore(storePath))
+		require.NoError(t, relationships.LoadRelationships(path))
+		var issues []string
+		for _, issue := range relationships.Lint() {
+			assert.Zero(t, issue.Line)
+			issues = append(issues, issue.String())
+		}
+		lints = append(lints, issues)
+	}
+	assert.Equal(t, []string{`warning: service "api-gateway" depends on unknown service "order-servce", did you mean "order-service"? [unknown-service]`}, lints[0])
+	assert.Equal(t, lints[0], lints[1])
+}
+
+func TestLoadIssuesAreLoggedWithLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "relationships.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`relationships:
+  - service: "api-gateway"
+    depends_on:
+      - "order-servce"
+      - "postgres"
+  - service: "order-service"
+`), 0o644))
+	relationships := service.NewRelationshipService(file.NewConfigLoader(), nil)
+	require.NoError(t, relationships.LoadRelationships(path))
+
+	var out bytes.Buffer
+	logLintIssues(slog.New(slog.NewJSONHandler(&out, nil)), relationships.LoadIssues())
+
+	var records []map[string]any
+	for _, line := range bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n")) {
+		var record map[string]any
+		require.NoError(t, json.Unmarshal(line, &record))
+		records = append(records, record)
+	}
+	require.Len(t, records, 2)
+	assert.Equal(t, "WARN", records[0]["level"])
+	assert.Equal(t, "unknown-service", records[0]["code"])
+	assert.Equal(t, float64(4), records[0]["line"])
+	assert.Equal(t, "INFO", records[1]["level"])
+	assert.Equal(t, float64(5), records[1]["line"])
+}
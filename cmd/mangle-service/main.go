@@ -3,60 +3,225 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
+	"io"
 	"log/slog"
+	"mangle-service/internal/adapters/auth"
+	"mangle-service/internal/adapters/certs"
 	"mangle-service/internal/adapters/elasticsearch"
 	"mangle-service/internal/adapters/file"
+	grpchandler "mangle-service/internal/adapters/grpc"
 	httphandler "mangle-service/internal/adapters/http"
+	"mangle-service/internal/adapters/importer"
+	"mangle-service/internal/adapters/metrics"
 	"mangle-service/internal/adapters/mock"
+	"mangle-service/internal/adapters/sqlite"
+	"mangle-service/internal/adapters/tracing"
+	"mangle-service/internal/config"
+	"mangle-service/internal/core/domain"
 	"mangle-service/internal/core/ports"
 	"mangle-service/internal/core/service"
 	"mangle-service/pkg/logger"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
 	"syscall"
 	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
-func main() {
-	// 1. Configuration
-	env := flag.String("env", "prod", "environment (dev, prod, test)")
-	flag.Parse()
+// stopTimeout bounds stopping the servers and flushing traces on shutdown,
+// after the queries have been drained.
+const stopTimeout = 5 * time.Second
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "lint":
+			os.Exit(runLint(os.Args[2:], os.Stdout))
+		case "graph":
+			os.Exit(runGraph(os.Args[2:], os.Stdout, os.Stderr))
+		case "config":
+			os.Exit(runConfig(os.Args[2:], os.Stdout, os.Stderr))
+		}
 	}
-	relationshipConfigPath := os.Getenv("RELATIONSHIP_CONFIG_PATH")
-	if relationshipConfigPath == "" {
-		relationshipConfigPath = "relationships.json"
+
+	// 1. Configuration
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:], os.LookupEnv)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
 	}
 
-	// 2. Logger
-	log := logger.New(slog.LevelDebug)
+	// 2. Logger. The level can be changed at runtime over the admin API.
+	logOutput, err := openLogOutput(cfg.Logging.Output)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer logOutput.Close()
+	logLevel := new(slog.LevelVar)
+	logLevel.Set(cfg.LogLevel())
+	log := logger.New(logLevel, logger.WithFormat(cfg.Logging.Format), logger.WithOutput(logOutput))
+	log.Info("loaded configuration", "env", cfg.Env, "log_source", cfg.Sources.Type)
+	tracingConfig := tracing.Config{Exporter: cfg.Tracing.Exporter, Protocol: cfg.Tracing.Protocol}
 
 	// 3. Adapters
-	var logAdapter ports.LogDataPort
-	if *env == "test" {
-		log.Info("using mock log adapter")
-		logAdapter = mock.NewMockLogAdapter()
-	} else {
-		log.Info("using elasticsearch log adapter")
-		logAdapter = elasticsearch.NewElasticsearchAdapter()
+	var tracerProvider trace.TracerProvider = noop.NewTracerProvider()
+	var shutdownTracing func(context.Context) error
+	if tracingConfig.Enabled() {
+		provider, err := tracing.NewTracerProvider(context.Background(), tracingConfig)
+		if err != nil {
+			log.Error("failed to configure tracing", "error", err)
+			os.Exit(1)
+		}
+		log.Info("exporting traces", "exporter", tracingConfig.Exporter, "protocol", tracingConfig.Protocol)
+		tracerProvider = provider
+		shutdownTracing = provider.Shutdown
+	}
+	otel.SetTracerProvider(tracerProvider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	logAdapter := newLogAdapter(cfg.Sources, log, elasticsearch.WithTracerProvider(tracerProvider))
+	relationshipLoader := importer.NewDefaultMultiLoader(file.NewConfigLoader())
+	relationshipStore := newRelationshipStore(cfg.Relationships.StorePath, log)
+	promMetrics := metrics.NewPrometheusMetrics()
+	auditSink, err := newAuditSink(cfg.Audit, log)
+	if err != nil {
+		log.Error("failed to open audit log", "error", err)
+		os.Exit(1)
 	}
-	fileAdapter := file.NewConfigLoader()
 
 	// 4. Core Services
 	logService := service.NewLogService(logAdapter)
-	relationshipService := service.NewRelationshipService(fileAdapter)
-	if err := relationshipService.LoadRelationships(relationshipConfigPath); err != nil {
+	relationshipService := service.NewRelationshipService(relationshipLoader, relationshipStore,
+		service.WithRelationshipMetrics(promMetrics))
+	if err := relationshipService.LoadRelationships(cfg.Relationships.ConfigPath); err != nil {
 		log.Error("failed to load relationships", "error", err)
 		os.Exit(1)
 	}
-	queryService := service.NewQueryService(logService, relationshipService, log)
+	logLintIssues(log, relationshipService.LoadIssues())
+	ruleModuleService := service.NewRuleModuleService(file.NewRuleModuleLoader())
+	if cfg.Rules.ModulesPath != "" {
+		if err := ruleModuleService.LoadModules(cfg.Rules.ModulesPath); err != nil {
+			log.Error("failed to load rule modules", "error", err)
+			os.Exit(1)
+		}
+		log.Info("loaded rule modules", "path", cfg.Rules.ModulesPath, "modules", len(ruleModuleService.GetModules().Modules))
+	}
+	admissionController := service.NewAdmissionController(domain.AdmissionConfig{
+		MaxConcurrent: cfg.Limits.MaxConcurrent,
+		MaxQueue:      cfg.Limits.MaxQueue,
+		QueueTimeout:  cfg.Limits.QueueTimeout,
+		ClientRate:    cfg.Limits.RateLimit,
+		ClientBurst:   cfg.Limits.RateBurst,
+	})
+	// Shutdown drains every query, including those of jobs.
+	drainController := service.NewDrainController()
+	queryOpts := []service.QueryOption{
+		service.WithRuleModules(ruleModuleService),
+		service.WithAdmissionControl(admissionController),
+		service.WithDrainController(drainController),
+		service.WithTimeout(cfg.Limits.QueryTimeout),
+		service.WithFactLimit(cfg.Limits.FactLimit),
+		service.WithSlowQueryThreshold(cfg.Logging.SlowQuery),
+		service.WithMetrics(promMetrics),
+		service.WithTracerProvider(tracerProvider),
+	}
+	if cfg.Auth.AccessPolicyPath != "" {
+		policy, err := file.NewAccessPolicyLoader().Load(cfg.Auth.AccessPolicyPath)
+		if err != nil {
+			log.Error("failed to load access policy", "error", err)
+			os.Exit(1)
+		}
+		queryOpts = append(queryOpts, service.WithAccessPolicy(policy))
+	}
+	if auditSink != nil {
+		queryOpts = append(queryOpts, service.WithAuditSink(auditSink))
+	}
+	queryService := service.NewQueryService(logService, relationshipService, log, queryOpts...)
+	// Jobs run the same queries under the same limits, but may take longer.
+	jobQueryService := service.NewQueryService(logService, relationshipService, log,
+		append(slices.Clone(queryOpts), service.WithTimeout(cfg.Jobs.Timeout))...)
+	jobService := service.NewJobService(jobQueryService, log, domain.JobConfig{
+		Workers:   cfg.Jobs.Workers,
+		MaxQueued: cfg.Jobs.MaxQueued,
+		ResultTTL: cfg.Jobs.ResultTTL,
+	}, service.WithJobDrainController(drainController))
+	graphService := service.NewGraphService(relationshipService, queryService)
+	impactService := service.NewImpactService(relationshipService, queryService)
+	healthService := service.NewHealthService(relationshipService, domain.HealthConfig{Timeout: cfg.Health.CheckTimeout},
+		service.WithSourceCheck(domain.DefaultLogSource, logAdapter),
+		service.WithQueueCheck(admissionController),
+		service.WithShutdownCheck(drainController))
 
 	// 5. HTTP Server
-	httpAdapter := httphandler.NewAdapter(queryService, log, port)
+	httpOpts := []httphandler.Option{
+		httphandler.WithRelationshipService(relationshipService),
+		httphandler.WithGraphService(graphService),
+		httphandler.WithImpactService(impactService),
+		httphandler.WithRuleModuleService(ruleModuleService),
+		httphandler.WithAdmissionService(admissionController),
+		httphandler.WithJobService(jobService),
+		httphandler.WithHealthService(healthService),
+		httphandler.WithMetricsHandler(promMetrics.Handler()),
+		httphandler.WithLogLevel(logLevel),
+		httphandler.WithAdminRoles(cfg.Auth.AdminRoles...),
+		httphandler.WithEffectiveConfig(cfg.Redacted()),
+	}
+	if auditSink != nil {
+		httpOpts = append(httpOpts, httphandler.WithAuditService(service.NewAuditService(auditSink, domain.AuditConfig{ReaderRoles: cfg.Audit.ReaderRoles})))
+	}
+	if tracingConfig.Enabled() {
+		httpOpts = append(httpOpts, httphandler.WithTracerProvider(tracerProvider))
+	}
+	grpcOpts := []grpchandler.Option{
+		grpchandler.WithRelationshipService(relationshipService),
+	}
+	if cfg.Auth.Enabled() {
+		authenticator, err := auth.NewAuthenticator(auth.Config{
+			APIKeysPath: cfg.Auth.APIKeysPath,
+			JWTSecret:   cfg.Auth.JWTSecret,
+			JWKSPath:    cfg.Auth.JWKSPath,
+			Issuer:      cfg.Auth.Issuer,
+			Audience:    cfg.Auth.Audience,
+			RolesClaim:  cfg.Auth.RolesClaim,
+			// Client certificates only reach the HTTP server.
+			ClientCertsPath: cfg.Auth.ClientCertsPath,
+		})
+		if err != nil {
+			log.Error("failed to configure authentication", "error", err)
+			os.Exit(1)
+		}
+		httpOpts = append(httpOpts, httphandler.WithAuthenticator(authenticator))
+		grpcOpts = append(grpcOpts, grpchandler.WithAuthenticator(authenticator))
+	} else if cfg.Auth.AccessPolicyPath != "" {
+		log.Warn("access policy is set but authentication is disabled; restricted predicates and sources are unavailable to every caller")
+	}
+	if cfg.Server.TLSEnabled() {
+		reloader, err := certs.NewReloader(certs.Config{
+			CertFile:     cfg.Server.TLSCertFile,
+			KeyFile:      cfg.Server.TLSKeyFile,
+			ClientCAFile: cfg.Server.TLSClientCAFile,
+			ClientAuth:   cfg.Server.TLSClientAuth,
+		}, log)
+		if err != nil {
+			log.Error("failed to configure TLS", "error", err)
+			os.Exit(1)
+		}
+		httpOpts = append(httpOpts, httphandler.WithTLSConfig(reloader.TLSConfig()))
+	}
+	httpAdapter := httphandler.NewAdapter(queryService, log, cfg.Server.Port, httpOpts...)
+
+	// The gRPC server is optional and shares the core services with HTTP.
+	var grpcAdapter *grpchandler.Adapter
+	if cfg.Server.GRPCPort != "" {
+		grpcAdapter = grpchandler.NewAdapter(queryService, log, cfg.Server.GRPCPort, grpcOpts...)
+	}
 
 	// 6. Start Server & Graceful Shutdown
 	ctx, cancel := context.WithCancel(context.Background())
@@ -68,21 +233,176 @@ func main() {
 			os.Exit(1)
 		}
 	}()
+	if grpcAdapter != nil {
+		go func() {
+			if err := grpcAdapter.Start(ctx); err != nil {
+				log.Error("failed to start grpc server", "error", err)
+				os.Exit(1)
+			}
+		}()
+	}
+
+	// Reload the relationships and rule modules on SIGHUP, keeping the
+	// previous ones on failure. A failed relationship reload makes the
+	// service unready until a reload succeeds.
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			if err := relationshipService.Reload(); err != nil {
+				log.Error("failed to reload relationships", "error", err)
+			} else {
+				log.Info("reloaded relationships", "version", relationshipService.Status().Version)
+				logLintIssues(log, relationshipService.LoadIssues())
+			}
+			if err := ruleModuleService.Reload(); err != nil {
+				log.Error("failed to reload rule modules", "error", err)
+				continue
+			}
+			log.Info("reloaded rule modules", "modules", len(ruleModuleService.GetModules().Modules))
+		}
+	}()
 
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
-	log.Info("shutting down server...")
+	log.Info("shutting down server...", "delay", cfg.Server.ShutdownDelay, "drain_timeout", cfg.Server.DrainTimeout)
 
-	// Shutdown the server
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer shutdownCancel()
+	// Report unready first so that load balancers stop sending queries,
+	// which are still served meanwhile. A second signal skips the wait.
+	drainController.Shutdown()
+	if grpcAdapter != nil {
+		grpcAdapter.SetNotServing()
+	}
+	select {
+	case <-time.After(cfg.Server.ShutdownDelay):
+	case <-quit:
+	}
+
+	// Refuse new queries and jobs, let running ones finish and cancel those
+	// still running at the deadline.
+	drainCtx, drainCancel := context.WithTimeout(ctx, cfg.Server.DrainTimeout)
+	if canceled := drainController.Drain(drainCtx); canceled > 0 {
+		log.Warn("cancelled queries still running at the drain deadline", "queries", canceled)
+	} else {
+		log.Info("drained running queries")
+	}
+	drainCancel()
 
+	// Stop the servers. Their requests are done or about to be, since no
+	// query is running any more.
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), stopTimeout)
+	defer shutdownCancel()
 	if err := httpAdapter.Stop(shutdownCtx); err != nil {
 		log.Error("failed to gracefully shutdown server", "error", err)
-		os.Exit(1)
+	}
+	if grpcAdapter != nil {
+		if err := grpcAdapter.Stop(shutdownCtx); err != nil {
+			log.Error("failed to gracefully shutdown grpc server", "error", err)
+		}
+	}
+	jobService.Close()
+
+	// Close the sources and stores last, once nothing uses them.
+	if closer, ok := logAdapter.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			log.Error("failed to close log source", "error", err)
+		}
+	}
+	if auditSink != nil {
+		if err := auditSink.Close(); err != nil {
+			log.Error("failed to close audit log", "error", err)
+		}
+	}
+	if shutdownTracing != nil {
+		if err := shutdownTracing(shutdownCtx); err != nil {
+			log.Error("failed to flush traces", "error", err)
+		}
 	}
 
 	log.Info("server shutdown complete")
 }
+
+// newLogAdapter returns the configured default log source. The options
+// apply to the Elasticsearch adapter.
+func newLogAdapter(sources config.SourcesConfig, log *slog.Logger, opts ...elasticsearch.Option) ports.LogDataPort {
+	if sources.Type == config.SourceMock {
+		log.Info("using mock log adapter")
+		return mock.NewMockLogAdapter()
+	}
+	es := sources.Elasticsearch
+	log.Info("using elasticsearch log adapter", "addresses", es.Addresses, "index", es.Index)
+	opts = append([]elasticsearch.Option{
+		elasticsearch.WithAddresses(es.Addresses...),
+		elasticsearch.WithIndex(es.Index),
+	}, opts...)
+	if es.Username != "" {
+		opts = append(opts, elasticsearch.WithBasicAuth(es.Username, es.Password))
+	}
+	if es.APIKey != "" {
+		opts = append(opts, elasticsearch.WithAPIKey(es.APIKey))
+	}
+	return elasticsearch.NewElasticsearchAdapter(opts...)
+}
+
+// openLogOutput opens the log destination: stdout, stderr or a file that
+// log lines are appended to.
+func openLogOutput(output string) (io.WriteCloser, error) {
+	switch output {
+	case config.LogOutputStdout:
+		return nopCloser{os.Stdout}, nil
+	case config.LogOutputStderr:
+		return nopCloser{os.Stderr}, nil
+	}
+	f, err := os.OpenFile(output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file: %w", err)
+	}
+	return f, nil
+}
+
+// nopCloser keeps the standard streams open when the log output is closed.
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+// newRelationshipStore returns the file store at path, or nil if no path is configured.
+func newRelationshipStore(path string, log *slog.Logger) ports.RelationshipStorePort {
+	if path == "" {
+		log.Warn("no relationship store configured, relationship changes will not be persisted")
+		return nil
+	}
+	log.Info("using file relationship store", "path", path)
+	return file.NewRelationshipStore(path)
+}
+
+// logLintIssues logs relationship config issues at the level matching their
+// severity.
+func logLintIssues(log *slog.Logger, issues []domain.LintIssue) {
+	for _, issue := range issues {
+		level := slog.LevelInfo
+		switch issue.Severity {
+		case domain.LintError:
+			level = slog.LevelError
+		case domain.LintWarning:
+			level = slog.LevelWarn
+		}
+		log.Log(context.Background(), level, "relationship config issue", "severity", issue.Severity, "code", issue.Code, "line", issue.Line, "message", issue.Message)
+	}
+}
+
+// newAuditSink opens the configured audit log. It returns nil if no path is configured.
+func newAuditSink(audit config.AuditConfig, log *slog.Logger) (ports.AuditSink, error) {
+	if audit.Path == "" {
+		log.Warn("no audit log configured, query executions will not be recorded")
+		return nil, nil
+	}
+	log.Info("writing audit log", "path", audit.Path, "format", audit.Format)
+	if audit.Format == config.AuditFormatSQLite {
+		return sqlite.NewAuditStore(audit.Path)
+	}
+	return file.NewAuditLog(audit.Path)
+}
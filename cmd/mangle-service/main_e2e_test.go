@@ -39,7 +39,7 @@ relationships:
 	require.NoError(t, err)
 	require.NoError(t, tmpfile.Close())
 
-	relationshipService := service.NewRelationshipService(fileAdapter)
+	relationshipService := service.NewRelationshipService(fileAdapter, nil)
 	err = relationshipService.LoadRelationships(tmpfile.Name())
 	require.NoError(t, err)
 
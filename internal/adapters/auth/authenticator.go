@@ -0,0 +1,302 @@
+// Package auth authenticates callers with API keys, JSON Web Tokens or TLS
+// client certificates. Tokens are verified locally, with a shared HS256
+// secret or with the RS256 public keys from a JWKS file, so no identity
+// provider has to be reachable.
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"mangle-service/internal/core/domain"
+	"mangle-service/internal/core/ports"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	_ ports.Authenticator            = (*Authenticator)(nil)
+	_ ports.CertificateAuthenticator = (*Authenticator)(nil)
+)
+
+// Authentication methods reported in domain.Principal.Method.
+const (
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+	// MethodClientCertificate is used for callers identified by their TLS
+	// client certificate.
+	MethodClientCertificate = "client_certificate"
+)
+
+// defaultRolesClaim is the JWT claim that holds the caller's roles.
+const defaultRolesClaim = "roles"
+
+// Config configures an Authenticator. Every part is optional, but at least
+// one of API keys and JWT verification should be set up.
+type Config struct {
+	// APIKeysPath is a YAML file listing the accepted API keys.
+	APIKeysPath string
+	// JWTSecret enables HS256 tokens signed with this secret.
+	JWTSecret string
+	// JWKSPath is a JWKS file whose RSA keys verify RS256 tokens.
+	JWKSPath string
+	// Issuer and Audience, if set, must match the iss and aud claims.
+	Issuer   string
+	Audience string
+	// RolesClaim names the claim holding the roles, "roles" by default. It may
+	// be a list of strings or a space-separated string.
+	RolesClaim string
+	// ClientCertsPath is a YAML file mapping client certificate identities
+	// to subjects and roles.
+	ClientCertsPath string
+}
+
+// Enabled reports whether the configuration enables any authentication method.
+func (c Config) Enabled() bool {
+	return c.APIKeysPath != "" || c.JWTSecret != "" || c.JWKSPath != "" || c.ClientCertsPath != ""
+}
+
+// apiKeyFile is the format of Config.APIKeysPath. Keys may be given in plain
+// text or, preferably, as the hex-encoded SHA-256 hash of the key.
+type apiKeyFile struct {
+	Keys []struct {
+		Subject   string   `yaml:"subject"`
+		Key       string   `yaml:"key"`
+		KeySHA256 string   `yaml:"key_sha256"`
+		Roles     []string `yaml:"roles"`
+	} `yaml:"keys"`
+}
+
+// clientCertFile is the format of Config.ClientCertsPath. Each entry names
+// one identity of a certificate: its subject common name, or a DNS name, URI
+// (such as a SPIFFE ID) or email address among its subject alternative names.
+type clientCertFile struct {
+	Certificates []struct {
+		CommonName string `yaml:"common_name"`
+		DNSName    string `yaml:"dns_name"`
+		URI        string `yaml:"uri"`
+		Email      string `yaml:"email"`
+		// Subject defaults to the identity.
+		Subject string   `yaml:"subject"`
+		Roles   []string `yaml:"roles"`
+	} `yaml:"certificates"`
+}
+
+// certIdentity is an identity of a client certificate, such as "uri:" and
+// the URI.
+type certIdentity string
+
+// Authenticator verifies API keys, JSON Web Tokens and client certificates.
+type Authenticator struct {
+	apiKeys    map[string]domain.Principal // by hex-encoded SHA-256 of the key
+	certs      map[certIdentity]domain.Principal
+	jwtSecret  []byte
+	jwks       map[string]any // RSA public keys by key ID
+	methods    []string
+	issuer     string
+	audience   string
+	rolesClaim string
+}
+
+// NewAuthenticator creates an Authenticator and loads the files named by config.
+func NewAuthenticator(config Config) (*Authenticator, error) {
+	a := &Authenticator{
+		apiKeys:    make(map[string]domain.Principal),
+		certs:      make(map[certIdentity]domain.Principal),
+		issuer:     config.Issuer,
+		audience:   config.Audience,
+		rolesClaim: config.RolesClaim,
+	}
+	if a.rolesClaim == "" {
+		a.rolesClaim = defaultRolesClaim
+	}
+	if config.APIKeysPath != "" {
+		if err := a.loadAPIKeys(config.APIKeysPath); err != nil {
+			return nil, err
+		}
+	}
+	if config.ClientCertsPath != "" {
+		if err := a.loadClientCerts(config.ClientCertsPath); err != nil {
+			return nil, err
+		}
+	}
+	if config.JWTSecret != "" {
+		a.jwtSecret = []byte(config.JWTSecret)
+		a.methods = append(a.methods, jwt.SigningMethodHS256.Alg())
+	}
+	if config.JWKSPath != "" {
+		keys, err := loadJWKS(config.JWKSPath)
+		if err != nil {
+			return nil, err
+		}
+		a.jwks = keys
+		a.methods = append(a.methods, jwt.SigningMethodRS256.Alg())
+	}
+	return a, nil
+}
+
+func (a *Authenticator) loadAPIKeys(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var file apiKeyFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	for i, key := range file.Keys {
+		hash := strings.ToLower(key.KeySHA256)
+		if key.Key != "" {
+			hash = hashKey(key.Key)
+		}
+		if hash == "" || key.Subject == "" {
+			return fmt.Errorf("%s: key %d needs a subject and a key or key_sha256", path, i+1)
+		}
+		a.apiKeys[hash] = domain.Principal{Subject: key.Subject, Roles: key.Roles, Method: MethodAPIKey}
+	}
+	return nil
+}
+
+func (a *Authenticator) loadClientCerts(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var file clientCertFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	for i, cert := range file.Certificates {
+		var identities []certIdentity
+		var name string
+		for _, id := range []struct{ kind, value string }{
+			{"cn", cert.CommonName}, {"dns", cert.DNSName}, {"uri", cert.URI}, {"email", cert.Email},
+		} {
+			if id.value != "" {
+				identities = append(identities, certIdentity(id.kind+":"+id.value))
+				name = id.value
+			}
+		}
+		if len(identities) != 1 {
+			return fmt.Errorf("%s: certificate %d needs exactly one of common_name, dns_name, uri and email", path, i+1)
+		}
+		subject := cert.Subject
+		if subject == "" {
+			subject = name
+		}
+		a.certs[identities[0]] = domain.Principal{Subject: subject, Roles: cert.Roles, Method: MethodClientCertificate}
+	}
+	return nil
+}
+
+// AuthenticateCertificate returns the caller identified by a verified client
+// certificate. Its URIs, DNS names and email addresses are tried before its
+// common name.
+func (a *Authenticator) AuthenticateCertificate(cert *x509.Certificate) (*domain.Principal, error) {
+	var identities []certIdentity
+	for _, uri := range cert.URIs {
+		identities = append(identities, certIdentity("uri:"+uri.String()))
+	}
+	for _, name := range cert.DNSNames {
+		identities = append(identities, certIdentity("dns:"+name))
+	}
+	for _, email := range cert.EmailAddresses {
+		identities = append(identities, certIdentity("email:"+email))
+	}
+	if cert.Subject.CommonName != "" {
+		identities = append(identities, certIdentity("cn:"+cert.Subject.CommonName))
+	}
+	for _, id := range identities {
+		if principal, ok := a.certs[id]; ok {
+			return &principal, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: unknown client certificate %q", domain.ErrUnauthenticated, cert.Subject.String())
+}
+
+// Authenticate returns the caller identified by the API key or bearer token.
+func (a *Authenticator) Authenticate(apiKey, bearerToken string) (*domain.Principal, error) {
+	switch {
+	case apiKey != "":
+		principal, ok := a.apiKeys[hashKey(apiKey)]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown API key", domain.ErrUnauthenticated)
+		}
+		return &principal, nil
+	case bearerToken != "":
+		return a.authenticateToken(bearerToken)
+	default:
+		return nil, fmt.Errorf("%w: no credentials", domain.ErrUnauthenticated)
+	}
+}
+
+func (a *Authenticator) authenticateToken(token string) (*domain.Principal, error) {
+	if len(a.methods) == 0 {
+		return nil, fmt.Errorf("%w: bearer tokens are not accepted", domain.ErrUnauthenticated)
+	}
+	opts := []jwt.ParserOption{jwt.WithValidMethods(a.methods), jwt.WithExpirationRequired()}
+	if a.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(a.issuer))
+	}
+	if a.audience != "" {
+		opts = append(opts, jwt.WithAudience(a.audience))
+	}
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(token, claims, a.key, opts...); err != nil {
+		return nil, fmt.Errorf("%w: %w", domain.ErrUnauthenticated, err)
+	}
+
+	subject, _ := claims.GetSubject()
+	if subject == "" {
+		return nil, fmt.Errorf("%w: token has no subject", domain.ErrUnauthenticated)
+	}
+	return &domain.Principal{Subject: subject, Roles: roles(claims[a.rolesClaim]), Method: MethodJWT}, nil
+}
+
+// key returns the key that verifies token, chosen by its algorithm and key ID.
+func (a *Authenticator) key(token *jwt.Token) (any, error) {
+	switch token.Method.Alg() {
+	case jwt.SigningMethodHS256.Alg():
+		return a.jwtSecret, nil
+	case jwt.SigningMethodRS256.Alg():
+		kid, _ := token.Header["kid"].(string)
+		if key, ok := a.jwks[kid]; ok {
+			return key, nil
+		}
+		if kid == "" && len(a.jwks) == 1 {
+			for _, key := range a.jwks {
+				return key, nil
+			}
+		}
+		return nil, fmt.Errorf("unknown key ID %q", kid)
+	default:
+		return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+}
+
+// roles reads a roles claim, which may be a list or a space-separated string.
+func roles(claim any) []string {
+	switch v := claim.(type) {
+	case string:
+		return strings.Fields(v)
+	case []any:
+		var roles []string
+		for _, role := range v {
+			if s, ok := role.(string); ok {
+				roles = append(roles, s)
+			}
+		}
+		return roles
+	default:
+		return nil
+	}
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,176 @@
+// Package certs serves TLS with certificate files that may change while the
+// service runs, as they do when a certificate manager rotates them.
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Client certificate policies of Config.ClientAuth.
+const (
+	// ClientAuthRequire rejects connections without a valid client certificate.
+	ClientAuthRequire = "require"
+	// ClientAuthOptional verifies client certificates if they are presented,
+	// so that callers may authenticate otherwise.
+	ClientAuthOptional = "optional"
+)
+
+// checkInterval is how often the files are checked for changes. Checks
+// happen during handshakes, so an idle server does not touch the files.
+const checkInterval = time.Second
+
+// Config names the files of a TLS server.
+type Config struct {
+	// CertFile and KeyFile hold the PEM-encoded certificate chain and key.
+	CertFile string
+	KeyFile  string
+	// ClientCAFile is a PEM bundle of the CAs that issue client
+	// certificates. Client certificates are not requested if it is empty.
+	ClientCAFile string
+	// ClientAuth is ClientAuthRequire or ClientAuthOptional; it defaults to
+	// ClientAuthRequire.
+	ClientAuth string
+}
+
+// Reloader loads the files of a Config and reloads them when they change.
+// If a reload fails, the previous certificate and CAs stay in use.
+type Reloader struct {
+	config Config
+	logger *slog.Logger
+	now    func() time.Time
+
+	mu       sync.Mutex
+	current  *tls.Config
+	versions []fileVersion
+	checked  time.Time
+}
+
+// fileVersion identifies the content of a file without reading it.
+type fileVersion struct {
+	modTime int64 // in nanoseconds since the epoch
+	size    int64
+}
+
+// NewReloader loads the files named by config.
+func NewReloader(config Config, logger *slog.Logger) (*Reloader, error) {
+	if config.CertFile == "" || config.KeyFile == "" {
+		return nil, errors.New("a certificate and a key file are required")
+	}
+	switch config.ClientAuth {
+	case "":
+		config.ClientAuth = ClientAuthRequire
+	case ClientAuthRequire, ClientAuthOptional:
+	default:
+		return nil, fmt.Errorf("unknown client certificate policy %q", config.ClientAuth)
+	}
+	r := &Reloader{config: config, logger: logger, now: time.Now}
+	versions, err := r.stat()
+	if err != nil {
+		return nil, err
+	}
+	current, err := r.load()
+	if err != nil {
+		return nil, err
+	}
+	r.current = current
+	r.versions = versions
+	r.checked = r.now()
+	return r, nil
+}
+
+// TLSConfig returns the configuration of a server that uses the current
+// files for every handshake.
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return r.configForClient(), nil
+		},
+	}
+}
+
+func (r *Reloader) configForClient() *tls.Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	if now.Sub(r.checked) < checkInterval {
+		return r.current
+	}
+	r.checked = now
+	versions, err := r.stat()
+	if err != nil {
+		r.logger.Error("failed to check TLS files, keeping the current ones", "error", err)
+		return r.current
+	}
+	if slices.Equal(versions, r.versions) {
+		return r.current
+	}
+	current, err := r.load()
+	if err != nil {
+		// Files are often replaced one after the other; the next check
+		// retries once they match.
+		r.logger.Error("failed to reload TLS files, keeping the current ones", "error", err)
+		return r.current
+	}
+	r.current = current
+	r.versions = versions
+	r.logger.Info("reloaded TLS files", "cert_file", r.config.CertFile)
+	return r.current
+}
+
+func (r *Reloader) files() []string {
+	files := []string{r.config.CertFile, r.config.KeyFile}
+	if r.config.ClientCAFile != "" {
+		files = append(files, r.config.ClientCAFile)
+	}
+	return files
+}
+
+func (r *Reloader) stat() ([]fileVersion, error) {
+	var versions []fileVersion
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, fileVersion{modTime: info.ModTime().UnixNano(), size: info.Size()})
+	}
+	return versions, nil
+}
+
+// load reads the files into the configuration of a single connection.
+func (r *Reloader) load() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(r.config.CertFile, r.config.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{"h2", "http/1.1"},
+	}
+	if r.config.ClientCAFile == "" {
+		return config, nil
+	}
+	pem, err := os.ReadFile(r.config.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA bundle: %w", err)
+	}
+	config.ClientCAs = x509.NewCertPool()
+	if !config.ClientCAs.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("%s: no certificates found", r.config.ClientCAFile)
+	}
+	config.ClientAuth = tls.RequireAndVerifyClientCert
+	if r.config.ClientAuth == ClientAuthOptional {
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return config, nil
+}
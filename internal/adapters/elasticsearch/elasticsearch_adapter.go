@@ -6,49 +6,133 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
-	"os"
+	"net/http"
 
 	"mangle-service/internal/core/domain"
 
 	"github.com/elastic/go-elasticsearch/v8"
 	"github.com/google/mangle/ast"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// defaultIndex is searched unless WithIndex says otherwise.
+const defaultIndex = "logs"
+
 // ElasticsearchAdapter implements the LogDataPort interface.
 type ElasticsearchAdapter struct {
-	client *elasticsearch.Client
+	client    *elasticsearch.Client
+	transport http.RoundTripper
+	index     string
+}
+
+// Option configures the adapter and its Elasticsearch client.
+type Option func(*ElasticsearchAdapter, *elasticsearch.Config)
+
+// WithAddresses sets the URLs of the cluster's nodes. Without it the client
+// connects to http://localhost:9200.
+func WithAddresses(addresses ...string) Option {
+	return func(_ *ElasticsearchAdapter, cfg *elasticsearch.Config) {
+		cfg.Addresses = addresses
+	}
+}
+
+// WithIndex sets the index, alias or index pattern that holds the logs.
+func WithIndex(index string) Option {
+	return func(a *ElasticsearchAdapter, _ *elasticsearch.Config) {
+		a.index = index
+	}
+}
+
+// WithBasicAuth authenticates to the cluster with a user name and password.
+func WithBasicAuth(username, password string) Option {
+	return func(_ *ElasticsearchAdapter, cfg *elasticsearch.Config) {
+		cfg.Username = username
+		cfg.Password = password
+	}
+}
+
+// WithAPIKey authenticates to the cluster with a base64-encoded API key.
+func WithAPIKey(apiKey string) Option {
+	return func(_ *ElasticsearchAdapter, cfg *elasticsearch.Config) {
+		cfg.APIKey = apiKey
+	}
+}
+
+// WithTracerProvider traces every Elasticsearch request, and each HTTP round
+// trip it takes, with spans from tp. The trace context is passed on to the
+// cluster in the traceparent header.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(_ *ElasticsearchAdapter, cfg *elasticsearch.Config) {
+		cfg.Instrumentation = elasticsearch.NewOpenTelemetryInstrumentation(tp, false)
+		cfg.Transport = otelhttp.NewTransport(http.DefaultTransport,
+			otelhttp.WithTracerProvider(tp),
+			otelhttp.WithPropagators(propagation.TraceContext{}))
+	}
 }
 
 // NewElasticsearchAdapter creates a new ElasticsearchAdapter.
-func NewElasticsearchAdapter() *ElasticsearchAdapter {
-	cfg := elasticsearch.Config{
-		Addresses: []string{
-			os.Getenv("ELASTICSEARCH_ADDRESS"),
-		},
+func NewElasticsearchAdapter(opts ...Option) *ElasticsearchAdapter {
+	adapter := &ElasticsearchAdapter{index: defaultIndex}
+	var cfg elasticsearch.Config
+	for _, opt := range opts {
+		opt(adapter, &cfg)
 	}
 	es, err := elasticsearch.NewClient(cfg)
 	if err != nil {
 		log.Fatalf("Error creating the Elasticsearch client: %s", err)
 	}
-	return &ElasticsearchAdapter{client: es}
+	adapter.client = es
+	adapter.transport = cfg.Transport
+	return adapter
+}
+
+// Close closes the idle connections to the cluster. Requests in progress
+// are not affected.
+func (a *ElasticsearchAdapter) Close() error {
+	transport := a.transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	(&http.Client{Transport: transport}).CloseIdleConnections()
+	return nil
 }
 
 // FetchLogs fetches logs from Elasticsearch and transforms them into Mangle facts.
-func (a *ElasticsearchAdapter) FetchLogs(queryCriteria map[string]string) ([]domain.Fact, error) {
+func (a *ElasticsearchAdapter) FetchLogs(ctx context.Context, queryCriteria map[string]string) ([]domain.Fact, error) {
 	var mustClauses []interface{}
+	timeRange := make(map[string]interface{})
 	for key, value := range queryCriteria {
-		mustClauses = append(mustClauses, map[string]interface{}{
-			"match": map[string]interface{}{
-				key: value,
-			},
-		})
+		switch key {
+		case domain.CriteriaFrom:
+			timeRange["gte"] = value
+		case domain.CriteriaTo:
+			timeRange["lt"] = value
+		default:
+			mustClauses = append(mustClauses, map[string]interface{}{
+				"match": map[string]interface{}{
+					key: value,
+				},
+			})
+		}
 	}
 
+	boolQuery := map[string]interface{}{
+		"must": mustClauses,
+	}
+	if len(timeRange) > 0 {
+		boolQuery["filter"] = []interface{}{
+			map[string]interface{}{
+				"range": map[string]interface{}{
+					"@timestamp": timeRange,
+				},
+			},
+		}
+	}
 	query := map[string]interface{}{
 		"query": map[string]interface{}{
-			"bool": map[string]interface{}{
-				"must": mustClauses,
-			},
+			"bool": boolQuery,
 		},
 	}
 
@@ -58,8 +142,8 @@ func (a *ElasticsearchAdapter) FetchLogs(queryCriteria map[string]string) ([]dom
 	}
 
 	res, err := a.client.Search(
-		a.client.Search.WithContext(context.Background()),
-		a.client.Search.WithIndex("logs"),
+		a.client.Search.WithContext(ctx),
+		a.client.Search.WithIndex(a.index),
 		a.client.Search.WithBody(&buf),
 		a.client.Search.WithTrackTotalHits(true),
 	)
@@ -143,3 +227,26 @@ func flattenSource(source map[string]interface{}, prefix string) map[string]stri
 	}
 	return flattened
 }
+
+// Predicates describes the facts returned by FetchLogs.
+func (a *ElasticsearchAdapter) Predicates() []domain.PredicateInfo {
+	return []domain.PredicateInfo{{
+		Name:        "log.field",
+		Arity:       3,
+		Args:        []string{"DocID", "Field", "Value"},
+		Description: "A field of a log document. Nested fields are joined with dots, e.g. \"http.status\".",
+	}}
+}
+
+// CheckHealth pings the Elasticsearch cluster.
+func (a *ElasticsearchAdapter) CheckHealth(ctx context.Context) error {
+	res, err := a.client.Ping(a.client.Ping.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("error pinging elasticsearch: %w", err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("elasticsearch error: %s", res.String())
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package file
+
+import (
+	"fmt"
+	"mangle-service/internal/core/domain"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// NewAccessPolicyLoader creates a new AccessPolicyLoader.
+func NewAccessPolicyLoader() *AccessPolicyLoader {
+	return &AccessPolicyLoader{}
+}
+
+// AccessPolicyLoader is a file-based loader for access policies.
+type AccessPolicyLoader struct{}
+
+// Load reads the YAML access policy at path. Every rule must name at least
+// one predicate or source and at least one role.
+func (l *AccessPolicyLoader) Load(path string) (*domain.AccessPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var policy domain.AccessPolicy
+	if err := yaml.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for i, rule := range policy.Rules {
+		if len(rule.Predicates) == 0 && len(rule.Sources) == 0 {
+			return nil, fmt.Errorf("%s: rule %d restricts no predicates or sources", path, i+1)
+		}
+		if len(rule.Roles) == 0 {
+			return nil, fmt.Errorf("%s: rule %d allows no roles", path, i+1)
+		}
+	}
+	return &policy, nil
+}
@@ -0,0 +1,96 @@
+package file
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mangle-service/internal/core/domain"
+	"mangle-service/internal/core/ports"
+	"os"
+	"slices"
+	"sync"
+)
+
+var _ ports.AuditSink = (*AuditLog)(nil)
+
+// AuditLog is an append-only audit sink that writes one JSON record per
+// line. Searches scan the whole file, which suits logs that are rotated or
+// shipped elsewhere before they grow large.
+type AuditLog struct {
+	path string
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewAuditLog opens the JSON-lines file at path for appending, creating it if needed.
+func NewAuditLog(path string) (*AuditLog, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return &AuditLog{path: path, file: f}, nil
+}
+
+// Write appends the record as a single line and syncs it to disk.
+func (l *AuditLog) Write(ctx context.Context, record domain.AuditRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("error encoding audit record: %w", err)
+	}
+	data = append(data, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.file.Write(data); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return l.file.Sync()
+}
+
+// Search scans the file for the records the filter selects. Lines that are
+// not valid records, such as one cut short by a crash, are skipped.
+func (l *AuditLog) Search(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditRecord, error) {
+	f, err := os.Open(l.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	var records []domain.AuditRecord
+	scanner := bufio.NewScanner(f)
+	// Queries may be long; allow lines of up to 16 MiB.
+	scanner.Buffer(make([]byte, 0, 64*1024), 16<<20)
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var record domain.AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		if filter.Matches(record) {
+			records = append(records, record)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	// Records are appended in the order their queries finished; sort them by
+	// when the queries started.
+	slices.SortStableFunc(records, func(a, b domain.AuditRecord) int {
+		return b.Time.Compare(a.Time)
+	})
+	if filter.Limit > 0 && len(records) > filter.Limit {
+		records = records[:filter.Limit]
+	}
+	return records, nil
+}
+
+// Close closes the file.
+func (l *AuditLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
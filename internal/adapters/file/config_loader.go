@@ -1,10 +1,11 @@
 package file
 
 import (
+	"fmt"
 	"mangle-service/internal/core/domain"
 	"os"
 
-	"gopkg.in/yaml.v2"
+	"gopkg.in/yaml.v3"
 )
 
 // NewConfigLoader creates a new ConfigLoader.
@@ -16,17 +17,66 @@ func NewConfigLoader() *ConfigLoader {
 type ConfigLoader struct{}
 
 // Load reads a YAML file from the given path and returns the RelationshipConfig.
+// The source line of every service and dependency is recorded for linting.
 func (l *ConfigLoader) Load(path string) (*domain.RelationshipConfig, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
 
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
 	var config domain.RelationshipConfig
-	err = yaml.Unmarshal(data, &config)
-	if err != nil {
-		return nil, err
+	if root.Kind == 0 {
+		return &config, nil
+	}
+	if err := root.Decode(&config); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
 	}
+	annotateLines(&root, &config)
 
 	return &config, nil
 }
+
+// annotateLines copies the source positions of the relationship entries from
+// the YAML node tree into config.
+func annotateLines(root *yaml.Node, config *domain.RelationshipConfig) {
+	doc := root
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		doc = doc.Content[0]
+	}
+	rels := mappingValue(doc, "relationships")
+	if rels == nil || rels.Kind != yaml.SequenceNode {
+		return
+	}
+	for i, item := range rels.Content {
+		if i >= len(config.Relationships) {
+			break
+		}
+		rel := &config.Relationships[i]
+		rel.Line = item.Line
+		if service := mappingValue(item, "service"); service != nil {
+			rel.Line = service.Line
+		}
+		if deps := mappingValue(item, "depends_on"); deps != nil && deps.Kind == yaml.SequenceNode {
+			rel.DependsOnLines = make([]int, len(deps.Content))
+			for j, dep := range deps.Content {
+				rel.DependsOnLines[j] = dep.Line
+			}
+		}
+	}
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package file
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mangle-service/internal/core/domain"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// RelationshipStore is a file-based implementation of the RelationshipStorePort.
+// The relationship graph is stored as a single JSON document that is replaced
+// atomically on every write.
+type RelationshipStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewRelationshipStore creates a new RelationshipStore backed by the file at path.
+func NewRelationshipStore(path string) *RelationshipStore {
+	return &RelationshipStore{path: path}
+}
+
+// Load reads the stored configuration. It returns nil if the file does not exist yet.
+func (s *RelationshipStore) Load() (*domain.RelationshipConfig, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.read()
+}
+
+// Save writes config if the stored version equals expectedVersion.
+func (s *RelationshipStore) Save(config domain.RelationshipConfig, expectedVersion int64) (*domain.RelationshipConfig, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	var currentVersion int64
+	if current != nil {
+		currentVersion = current.Version
+	}
+	if expectedVersion != domain.AnyVersion && expectedVersion != currentVersion {
+		return nil, fmt.Errorf("%w: expected version %d, stored version is %d", domain.ErrVersionConflict, expectedVersion, currentVersion)
+	}
+
+	config.Version = currentVersion + 1
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error encoding relationships: %w", err)
+	}
+	if err := writeFileAtomic(s.path, data); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func (s *RelationshipStore) read() (*domain.RelationshipConfig, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var config domain.RelationshipConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("error decoding relationship store %s: %w", s.path, err)
+	}
+	return &config, nil
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it into place.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,55 @@
+package file
+
+import (
+	"mangle-service/internal/core/domain"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ruleModuleExt is the file extension of Mangle rule modules.
+const ruleModuleExt = ".mg"
+
+// NewRuleModuleLoader creates a new RuleModuleLoader.
+func NewRuleModuleLoader() *RuleModuleLoader {
+	return &RuleModuleLoader{}
+}
+
+// RuleModuleLoader is a file-based loader for Mangle rule modules.
+type RuleModuleLoader struct{}
+
+// LoadModules reads the .mg file at path, or every .mg file directly inside
+// the directory at path, in lexical order. Each module is named after its file.
+func (l *RuleModuleLoader) LoadModules(path string) ([]domain.RuleModuleSource, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{path}
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		files = nil
+		for _, entry := range entries {
+			if !entry.IsDir() && filepath.Ext(entry.Name()) == ruleModuleExt {
+				files = append(files, filepath.Join(path, entry.Name()))
+			}
+		}
+	}
+
+	modules := make([]domain.RuleModuleSource, 0, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		modules = append(modules, domain.RuleModuleSource{
+			Name:   strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)),
+			Path:   file,
+			Source: string(data),
+		})
+	}
+	return modules, nil
+}
@@ -0,0 +1,192 @@
+// Package graphexport renders the relationship graph in formats understood by
+// common diagram tools: Graphviz DOT, Mermaid and GraphML.
+package graphexport
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mangle-service/internal/core/domain"
+	"strconv"
+	"strings"
+)
+
+// Supported formats.
+const (
+	FormatDOT     = "dot"
+	FormatMermaid = "mermaid"
+	FormatGraphML = "graphml"
+)
+
+// Formats lists the supported formats.
+var Formats = []string{FormatDOT, FormatMermaid, FormatGraphML}
+
+// ContentType returns the media type for the given format.
+func ContentType(format string) string {
+	switch format {
+	case FormatDOT:
+		return "text/vnd.graphviz; charset=utf-8"
+	case FormatGraphML:
+		return "application/graphml+xml; charset=utf-8"
+	default:
+		return "text/plain; charset=utf-8"
+	}
+}
+
+// Render writes graph to w in the given format.
+func Render(w io.Writer, graph *domain.Graph, format string) error {
+	switch format {
+	case FormatDOT:
+		return renderDOT(w, graph)
+	case FormatMermaid:
+		return renderMermaid(w, graph)
+	case FormatGraphML:
+		return renderGraphML(w, graph)
+	default:
+		return fmt.Errorf("unsupported graph format %q (supported: %s)", format, strings.Join(Formats, ", "))
+	}
+}
+
+func renderDOT(w io.Writer, graph *domain.Graph) error {
+	var b strings.Builder
+	b.WriteString("digraph relationships {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box, style=rounded];\n")
+	for _, n := range graph.Nodes {
+		if n.Highlighted {
+			fmt.Fprintf(&b, "  %s [style=\"rounded,filled\", fillcolor=\"#ffb74d\", color=\"#e65100\"];\n", dotQuote(n.ID))
+		} else {
+			fmt.Fprintf(&b, "  %s;\n", dotQuote(n.ID))
+		}
+	}
+	for _, e := range graph.Edges {
+		if e.Highlighted {
+			fmt.Fprintf(&b, "  %s -> %s [color=\"#e65100\", penwidth=2];\n", dotQuote(e.From), dotQuote(e.To))
+		} else {
+			fmt.Fprintf(&b, "  %s -> %s;\n", dotQuote(e.From), dotQuote(e.To))
+		}
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// renderMermaid uses generated node IDs, because service names may contain
+// characters that Mermaid does not allow in IDs, and shows the name as label.
+func renderMermaid(w io.Writer, graph *domain.Graph) error {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	ids := make(map[string]string, len(graph.Nodes))
+	var highlightedNodes []string
+	for i, n := range graph.Nodes {
+		id := "n" + strconv.Itoa(i)
+		ids[n.ID] = id
+		fmt.Fprintf(&b, "  %s[\"%s\"]\n", id, mermaidEscape(n.ID))
+		if n.Highlighted {
+			highlightedNodes = append(highlightedNodes, id)
+		}
+	}
+	var highlightedEdges []string
+	for i, e := range graph.Edges {
+		fmt.Fprintf(&b, "  %s --> %s\n", ids[e.From], ids[e.To])
+		if e.Highlighted {
+			highlightedEdges = append(highlightedEdges, strconv.Itoa(i))
+		}
+	}
+	if len(highlightedNodes) > 0 {
+		b.WriteString("  classDef highlight fill:#ffb74d,stroke:#e65100,stroke-width:2px\n")
+		fmt.Fprintf(&b, "  class %s highlight\n", strings.Join(highlightedNodes, ","))
+	}
+	if len(highlightedEdges) > 0 {
+		fmt.Fprintf(&b, "  linkStyle %s stroke:#e65100,stroke-width:3px\n", strings.Join(highlightedEdges, ","))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// dotQuote quotes s as a DOT ID. Unlike Go strings, DOT strings take any
+// UTF-8 text as is, so only quotes and backslashes are escaped.
+func dotQuote(s string) string {
+	return `"` + dotEscaper.Replace(s) + `"`
+}
+
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+func mermaidEscape(s string) string {
+	return strings.ReplaceAll(s, `"`, "#quot;")
+}
+
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   struct {
+		ID          string        `xml:"id,attr"`
+		EdgeDefault string        `xml:"edgedefault,attr"`
+		Nodes       []graphMLNode `xml:"node"`
+		Edges       []graphMLEdge `xml:"edge"`
+	} `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+	Default  string `xml:"default,omitempty"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	ID     string        `xml:"id,attr"`
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+func renderGraphML(w io.Writer, graph *domain.Graph) error {
+	doc := graphML{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "label", For: "node", AttrName: "label", AttrType: "string"},
+			{ID: "node_highlighted", For: "node", AttrName: "highlighted", AttrType: "boolean", Default: "false"},
+			{ID: "edge_highlighted", For: "edge", AttrName: "highlighted", AttrType: "boolean", Default: "false"},
+		},
+	}
+	doc.Graph.ID = "relationships"
+	doc.Graph.EdgeDefault = "directed"
+	for _, n := range graph.Nodes {
+		node := graphMLNode{ID: n.ID, Data: []graphMLData{{Key: "label", Value: n.ID}}}
+		if n.Highlighted {
+			node.Data = append(node.Data, graphMLData{Key: "node_highlighted", Value: "true"})
+		}
+		doc.Graph.Nodes = append(doc.Graph.Nodes, node)
+	}
+	for i, e := range graph.Edges {
+		edge := graphMLEdge{ID: "e" + strconv.Itoa(i), Source: e.From, Target: e.To}
+		if e.Highlighted {
+			edge.Data = append(edge.Data, graphMLData{Key: "edge_highlighted", Value: "true"})
+		}
+		doc.Graph.Edges = append(doc.Graph.Edges, edge)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,81 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mangle-service/internal/core/domain"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// errorDomain is the domain of the google.rpc.ErrorInfo attached to errors.
+const errorDomain = "mangle-service"
+
+// queryErrorCodes maps query error codes to gRPC status codes.
+var queryErrorCodes = map[domain.ErrorCode]codes.Code{
+	domain.CodeInvalidQuery:      codes.InvalidArgument,
+	domain.CodeParseError:        codes.InvalidArgument,
+	domain.CodeForbidden:         codes.PermissionDenied,
+	domain.CodeAnalysisError:     codes.InvalidArgument,
+	domain.CodeEvaluationError:   codes.InvalidArgument,
+	domain.CodeBudgetExceeded:    codes.ResourceExhausted,
+	domain.CodeRateLimited:       codes.ResourceExhausted,
+	domain.CodeSourceUnavailable: codes.Unavailable,
+	domain.CodeOverloaded:        codes.Unavailable,
+	domain.CodeTimeout:           codes.DeadlineExceeded,
+	domain.CodeCanceled:          codes.Canceled,
+	domain.CodeInternal:          codes.Internal,
+}
+
+// queryError returns the status for an error returned while running a query.
+// As over HTTP, details of server-side failures are logged but not sent to
+// clients.
+func (a *Adapter) queryError(ctx context.Context, err error) error {
+	var qerr *domain.QueryError
+	if !errors.As(err, &qerr) {
+		a.logger.ErrorContext(ctx, "error executing query", "error", err)
+		return errorStatus(codes.Internal, string(domain.CodeInternal), "internal server error", nil, 0)
+	}
+
+	code, ok := queryErrorCodes[qerr.Code]
+	if !ok {
+		code = codes.Internal
+	}
+	message := qerr.Error()
+	switch code {
+	case codes.Internal, codes.Unavailable, codes.DeadlineExceeded:
+		if qerr.Code != domain.CodeOverloaded {
+			a.logger.ErrorContext(ctx, "error executing query", "error", err, "code", qerr.Code)
+			message = qerr.Message
+		}
+	}
+	return errorStatus(code, string(qerr.Code), message, qerr.Details, qerr.RetryAfter)
+}
+
+// errorStatus builds a status carrying reason as a google.rpc.ErrorInfo and,
+// if retryAfter is set, a google.rpc.RetryInfo.
+func errorStatus(code codes.Code, reason, message string, details map[string]any, retryAfter time.Duration) error {
+	info := &errdetails.ErrorInfo{Reason: reason, Domain: errorDomain}
+	if len(details) > 0 {
+		info.Metadata = make(map[string]string, len(details))
+		for k, v := range details {
+			info.Metadata[k] = fmt.Sprint(v)
+		}
+	}
+	st := status.New(code, message)
+	withDetails, err := st.WithDetails(info)
+	if err != nil {
+		return st.Err()
+	}
+	if retryAfter > 0 {
+		if withRetry, err := withDetails.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)}); err == nil {
+			withDetails = withRetry
+		}
+	}
+	return withDetails.Err()
+}
@@ -0,0 +1,117 @@
+package grpc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"mangle-service/internal/core/domain"
+	"mangle-service/internal/core/ports"
+	"mangle-service/pkg/logger"
+	"net"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// Metadata keys, the gRPC counterparts of the HTTP headers.
+const (
+	requestIDKey     = "x-request-id"
+	apiKeyKey        = "x-api-key"
+	authorizationKey = "authorization"
+)
+
+// maxRequestIDLength bounds client-supplied request IDs, which end up in logs.
+const maxRequestIDLength = 128
+
+// publicServices are served without authentication so that load balancers
+// and orchestrators can probe the service and clients can discover the API.
+var publicServices = map[string]bool{
+	"grpc.health.v1.Health":                    true,
+	"grpc.reflection.v1.ServerReflection":      true,
+	"grpc.reflection.v1alpha.ServerReflection": true,
+}
+
+// WithAuthenticator requires every call, except health checks and
+// reflection, to carry an API key or bearer token accepted by authenticator.
+func WithAuthenticator(authenticator ports.Authenticator) Option {
+	return func(a *Adapter) {
+		a.authenticator = authenticator
+	}
+}
+
+func (a *Adapter) unaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := a.prepareContext(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a *Adapter) streamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.prepareContext(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+}
+
+// serverStream replaces the context of a stream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// prepareContext assigns the call a request ID, which is echoed in the
+// response headers, and makes it, the client address, the channel and the
+// authenticated caller available to the core through the context.
+func (a *Adapter) prepareContext(ctx context.Context, fullMethod string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	id := firstValue(md, requestIDKey)
+	if id == "" || len(id) > maxRequestIDLength {
+		id = newRequestID()
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDKey, id))
+	ctx = domain.ContextWithChannel(domain.ContextWithRequestID(ctx, id), domain.ChannelGRPC)
+	ctx = logger.ContextWithAttrs(ctx, slog.String("request_id", id))
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			ctx = domain.ContextWithClientAddress(ctx, host)
+		}
+	}
+
+	service, _, _ := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if a.authenticator == nil || publicServices[service] {
+		return ctx, nil
+	}
+	var token string
+	if scheme, credentials, ok := strings.Cut(firstValue(md, authorizationKey), " "); ok && strings.EqualFold(scheme, "Bearer") {
+		token = strings.TrimSpace(credentials)
+	}
+	principal, err := a.authenticator.Authenticate(firstValue(md, apiKeyKey), token)
+	if err != nil {
+		a.logger.InfoContext(ctx, "authentication failed", "error", err, "method", fullMethod)
+		return nil, errorStatus(codes.Unauthenticated, "unauthenticated", "missing or invalid credentials", nil, 0)
+	}
+	return domain.ContextWithPrincipal(ctx, principal), nil
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,209 @@
+// Package grpc serves the query service over gRPC, as described by
+// api/mangle/v1/mangle.proto. It is a driving adapter next to the HTTP one and
+// calls the same core services.
+package grpc
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	manglev1 "mangle-service/api/mangle/v1"
+	"mangle-service/internal/core/domain"
+	"mangle-service/internal/core/ports"
+	"mangle-service/pkg/logger"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+type Adapter struct {
+	manglev1.UnimplementedMangleServiceServer
+
+	service       ports.QueryService
+	relationships ports.RelationshipService
+	authenticator ports.Authenticator
+	logger        *slog.Logger
+	addr          string
+	server        *grpc.Server
+	health        *health.Server
+}
+
+// Option configures optional parts of the Adapter.
+type Option func(*Adapter)
+
+// WithRelationshipService enables ListRelationships.
+func WithRelationshipService(relationships ports.RelationshipService) Option {
+	return func(a *Adapter) {
+		a.relationships = relationships
+	}
+}
+
+func NewAdapter(service ports.QueryService, logger *slog.Logger, port string, opts ...Option) *Adapter {
+	adapter := &Adapter{
+		service: service,
+		logger:  logger,
+		addr:    ":" + port,
+		health:  health.NewServer(),
+	}
+	for _, opt := range opts {
+		opt(adapter)
+	}
+	adapter.server = grpc.NewServer(
+		grpc.ChainUnaryInterceptor(adapter.unaryInterceptor),
+		grpc.ChainStreamInterceptor(adapter.streamInterceptor),
+	)
+	manglev1.RegisterMangleServiceServer(adapter.server, adapter)
+	healthpb.RegisterHealthServer(adapter.server, adapter.health)
+	reflection.Register(adapter.server)
+	return adapter
+}
+
+// Start listens on the configured port and serves until Stop is called or
+// ctx is done, which stops the server without waiting for running calls.
+func (a *Adapter) Start(ctx context.Context) error {
+	lis, err := net.Listen("tcp", a.addr)
+	if err != nil {
+		return err
+	}
+	stop := context.AfterFunc(ctx, a.server.Stop)
+	defer stop()
+	return a.Serve(lis)
+}
+
+// Serve serves on lis until Stop is called.
+func (a *Adapter) Serve(lis net.Listener) error {
+	a.logger.Info("starting grpc server", "addr", lis.Addr().String())
+	return a.server.Serve(lis)
+}
+
+// SetNotServing reports the service as not serving over the health
+// protocol, while calls are still served.
+func (a *Adapter) SetNotServing() {
+	a.health.Shutdown()
+}
+
+// Stop reports the service as not serving and waits for running calls to
+// finish. Calls still running when ctx is done are cancelled.
+func (a *Adapter) Stop(ctx context.Context) error {
+	a.logger.Info("stopping grpc server")
+	a.health.Shutdown()
+	done := make(chan struct{})
+	go func() {
+		a.server.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		a.server.Stop()
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (a *Adapter) ExecuteQuery(ctx context.Context, req *manglev1.QueryRequest) (*manglev1.QueryResponse, error) {
+	query := toQueryRequest(req)
+	ctx = logger.ContextWithAttrs(ctx, slog.String("query_hash", query.Hash()))
+	result, err := a.service.ExecuteQuery(ctx, query)
+	if err != nil {
+		return nil, a.queryError(ctx, err)
+	}
+	resp := &manglev1.QueryResponse{
+		Columns: result.Columns,
+		Results: make([]*structpb.Struct, 0, len(result.Results)),
+		Count:   int64(result.Count),
+	}
+	for _, entry := range result.Results {
+		s, err := structpb.NewStruct(entry)
+		if err != nil {
+			return nil, a.queryError(ctx, err)
+		}
+		resp.Results = append(resp.Results, s)
+	}
+	return resp, nil
+}
+
+func (a *Adapter) StreamQuery(req *manglev1.QueryRequest, stream grpc.ServerStreamingServer[manglev1.StreamQueryResponse]) error {
+	query := toQueryRequest(req)
+	ctx := logger.ContextWithAttrs(stream.Context(), slog.String("query_hash", query.Hash()))
+	summary, err := a.service.StreamQuery(ctx, query, func(entry domain.LogEntry) error {
+		s, err := structpb.NewStruct(entry)
+		if err != nil {
+			return err
+		}
+		return stream.Send(&manglev1.StreamQueryResponse{Record: &manglev1.StreamQueryResponse_Result{Result: s}})
+	})
+	if err != nil {
+		return a.queryError(ctx, err)
+	}
+	return stream.Send(&manglev1.StreamQueryResponse{Record: &manglev1.StreamQueryResponse_Summary{Summary: &manglev1.QuerySummary{
+		Columns:    summary.Columns,
+		Count:      int64(summary.Count),
+		DurationMs: summary.DurationMS,
+	}}})
+}
+
+// ValidateQuery reports queries the caller can fix as invalid rather than
+// failing the call, which is kept for errors on the service's side.
+func (a *Adapter) ValidateQuery(ctx context.Context, req *manglev1.QueryRequest) (*manglev1.ValidateQueryResponse, error) {
+	validation, err := a.service.ValidateQuery(ctx, toQueryRequest(req))
+	var qerr *domain.QueryError
+	if errors.As(err, &qerr) && qerr.Code != domain.CodeInternal {
+		return &manglev1.ValidateQueryResponse{ErrorCode: string(qerr.Code), ErrorMessage: qerr.Error()}, nil
+	}
+	if err != nil {
+		return nil, a.queryError(ctx, err)
+	}
+	return &manglev1.ValidateQueryResponse{
+		Valid:   true,
+		Columns: validation.Columns,
+		Sources: validation.Sources,
+	}, nil
+}
+
+func (a *Adapter) ListRelationships(ctx context.Context, _ *manglev1.ListRelationshipsRequest) (*manglev1.ListRelationshipsResponse, error) {
+	if a.relationships == nil {
+		return nil, status.Error(codes.Unimplemented, "relationships are not available")
+	}
+	if err := a.service.AuthorizeRelationships(ctx); err != nil {
+		return nil, a.queryError(ctx, err)
+	}
+	config := a.relationships.GetConfig()
+	resp := &manglev1.ListRelationshipsResponse{
+		Relationships: make([]*manglev1.ServiceRelationship, 0, len(config.Relationships)),
+		Version:       config.Version,
+	}
+	for _, rel := range config.Relationships {
+		resp.Relationships = append(resp.Relationships, &manglev1.ServiceRelationship{
+			Service:     rel.Service,
+			DependsOn:   rel.DependsOn,
+			Criticality: rel.Criticality,
+		})
+	}
+	return resp, nil
+}
+
+func toQueryRequest(req *manglev1.QueryRequest) domain.QueryRequest {
+	query := domain.QueryRequest{
+		Query:   req.GetQuery(),
+		Sources: req.GetSources(),
+		Name:    req.GetName(),
+	}
+	if tr := req.GetTimeRange(); tr != nil {
+		query.TimeRange = &domain.TimeRange{}
+		if tr.GetFrom() != nil {
+			query.TimeRange.From = tr.GetFrom().AsTime()
+		}
+		if tr.GetTo() != nil {
+			query.TimeRange.To = tr.GetTo().AsTime()
+		}
+	}
+	return query
+}
@@ -0,0 +1,158 @@
+package http
+
+import (
+	"encoding/json"
+	"log/slog"
+	"mangle-service/internal/core/domain"
+	"mangle-service/pkg/logger"
+	"net/http"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// WithLogLevel enables the log level endpoints, which read and change level
+// at runtime. Like the other admin endpoints, they require an authenticator.
+func WithLogLevel(level *slog.LevelVar) Option {
+	return func(a *Adapter) {
+		a.logLevel = level
+	}
+}
+
+// WithAdminRoles lets callers with one of roles use the admin endpoints and
+// change the relationship graph. These endpoints are only served with an
+// authenticator; without one, nobody could be told apart from an admin.
+func WithAdminRoles(roles ...string) Option {
+	return func(a *Adapter) {
+		a.adminRoles = roles
+	}
+}
+
+// WithEffectiveConfig serves config at /admin/config, with the keys of its
+// YAML form. Pass a copy whose secrets are redacted.
+func WithEffectiveConfig(config any) Option {
+	return func(a *Adapter) {
+		a.effectiveConfig = config
+	}
+}
+
+// registerAdminRoutes registers the endpoints that show what the service
+// runs with. They require an authenticator and an admin role.
+func (a *Adapter) registerAdminRoutes() {
+	if a.authenticator == nil {
+		return
+	}
+	a.handle("GET /admin/program", a.requireAdmin(a.handleProgram))
+	a.handle("GET /admin/sources", a.requireAdmin(a.handleSources))
+	if a.relationships != nil {
+		a.handle("GET /admin/relationships", a.requireAdmin(a.handleRelationshipState))
+	}
+	if a.effectiveConfig != nil {
+		a.handle("GET /admin/config", a.requireAdmin(a.handleEffectiveConfig))
+	}
+	// Debug logs carry the query text, so changing the level is as
+	// sensitive as reading the queries.
+	if a.logLevel != nil {
+		a.handle("GET /admin/log-level", a.requireAdmin(a.handleGetLogLevel))
+		a.handle("PUT /admin/log-level", a.requireAdmin(a.handleSetLogLevel))
+	}
+}
+
+// requireAdmin refuses callers without an admin role.
+func (a *Adapter) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !domain.PrincipalFromContext(r.Context()).HasAnyRole(a.adminRoles) {
+			a.writeError(w, "requires one of the roles "+strings.Join(a.adminRoles, ", "), http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (a *Adapter) handleGetLogLevel(w http.ResponseWriter, r *http.Request) {
+	a.writeJSON(w, domain.LogLevel{Level: strings.ToLower(a.logLevel.Level().String())}, http.StatusOK)
+}
+
+// handleSetLogLevel changes the log level until the next restart.
+func (a *Adapter) handleSetLogLevel(w http.ResponseWriter, r *http.Request) {
+	var req domain.LogLevel
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.writeError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	level, err := logger.ParseLevel(req.Level)
+	if err != nil {
+		a.writeError(w, err.Error()+": must be debug, info, warn or error", http.StatusBadRequest)
+		return
+	}
+	previous := a.logLevel.Level()
+	a.logLevel.Set(level)
+	attrs := []any{"from", previous, "to", level}
+	if principal := domain.PrincipalFromContext(r.Context()); principal != nil {
+		attrs = append(attrs, "subject", principal.Subject)
+	}
+	a.logger.WarnContext(r.Context(), "changed log level", attrs...)
+	a.handleGetLogLevel(w, r)
+}
+
+func (a *Adapter) handleProgram(w http.ResponseWriter, r *http.Request) {
+	program, err := a.service.Program(r.Context())
+	if err != nil {
+		a.writeQueryError(w, r, err)
+		return
+	}
+	a.writeJSON(w, program, http.StatusOK)
+}
+
+// handleSources lists the configured log sources with the outcome of their
+// health checks.
+func (a *Adapter) handleSources(w http.ResponseWriter, r *http.Request) {
+	program, err := a.service.Program(r.Context())
+	if err != nil {
+		a.writeQueryError(w, r, err)
+		return
+	}
+	checks := make(map[string]domain.HealthCheck)
+	if a.health != nil {
+		for _, check := range a.health.Check(r.Context()).Checks {
+			checks[check.Name] = check
+		}
+	}
+	sources := make([]domain.SourceState, len(program.Sources))
+	for i, name := range program.Sources {
+		check := checks[domain.HealthCheckLogSource+":"+name]
+		sources[i] = domain.SourceState{
+			Name:    name,
+			Default: i == 0,
+			Status:  check.Status,
+			Message: check.Message,
+		}
+	}
+	a.writeJSON(w, sources, http.StatusOK)
+}
+
+func (a *Adapter) handleRelationshipState(w http.ResponseWriter, r *http.Request) {
+	status := a.relationships.Status()
+	a.writeJSON(w, domain.RelationshipState{
+		Config:         a.relationships.GetConfig(),
+		UpdatedAt:      status.UpdatedAt,
+		ReloadError:    status.ReloadError,
+		ReloadFailedAt: status.ReloadFailedAt,
+	}, http.StatusOK)
+}
+
+// handleEffectiveConfig converts the configuration to JSON through YAML, so
+// that keys and durations read as in the configuration file.
+func (a *Adapter) handleEffectiveConfig(w http.ResponseWriter, r *http.Request) {
+	data, err := yaml.Marshal(a.effectiveConfig)
+	var config map[string]any
+	if err == nil {
+		err = yaml.Unmarshal(data, &config)
+	}
+	if err != nil {
+		a.logger.ErrorContext(r.Context(), "failed to encode configuration", "error", err)
+		a.writeError(w, "failed to encode configuration", http.StatusInternalServerError)
+		return
+	}
+	a.writeJSON(w, config, http.StatusOK)
+}
@@ -0,0 +1,7 @@
+package http
+
+import "net/http"
+
+func (a *Adapter) handleAdmissionStats(w http.ResponseWriter, r *http.Request) {
+	a.writeJSON(w, a.admission.Stats(), http.StatusOK)
+}
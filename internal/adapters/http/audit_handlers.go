@@ -0,0 +1,73 @@
+package http
+
+import (
+	"errors"
+	"mangle-service/internal/core/domain"
+	"mangle-service/internal/core/ports"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// WithAuditService enables the audit log search endpoint. It is only served
+// with an authenticator, since records carry the text of every query.
+func WithAuditService(audit ports.AuditService) Option {
+	return func(a *Adapter) {
+		a.audit = audit
+	}
+}
+
+// handleSearchAudit serves GET /audit. Every query parameter narrows the
+// search; since and until are RFC 3339 timestamps.
+func (a *Adapter) handleSearchAudit(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	filter := domain.AuditFilter{
+		Subject:   params.Get("subject"),
+		Name:      params.Get("name"),
+		QueryHash: params.Get("query_hash"),
+		Source:    params.Get("source"),
+		Outcome:   domain.AuditOutcome(params.Get("outcome")),
+	}
+	switch filter.Outcome {
+	case "", domain.AuditSucceeded, domain.AuditFailed:
+	default:
+		a.writeError(w, "invalid outcome: must be succeeded or failed", http.StatusBadRequest)
+		return
+	}
+	for _, bound := range []struct {
+		name string
+		dst  *time.Time
+	}{{"since", &filter.Since}, {"until", &filter.Until}} {
+		value := params.Get(bound.name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			a.writeError(w, "invalid "+bound.name+": must be an RFC 3339 timestamp", http.StatusBadRequest)
+			return
+		}
+		*bound.dst = t
+	}
+	if limit := params.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			a.writeError(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = n
+	}
+
+	result, err := a.audit.Search(r.Context(), filter)
+	var qerr *domain.QueryError
+	if errors.As(err, &qerr) {
+		a.writeQueryError(w, r, err)
+		return
+	}
+	if err != nil {
+		a.logger.ErrorContext(r.Context(), "failed to search audit log", "error", err)
+		a.writeError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	a.writeJSON(w, result, http.StatusOK)
+}
@@ -0,0 +1,71 @@
+package http
+
+import (
+	"mangle-service/internal/core/domain"
+	"mangle-service/internal/core/ports"
+	"net/http"
+	"strings"
+)
+
+// apiKeyHeader carries API keys. Bearer tokens use the Authorization header.
+const apiKeyHeader = "X-API-Key"
+
+// publicPaths are served without authentication so that load balancers and
+// orchestrators can probe the service, monitoring can scrape it and clients
+// can discover the API.
+var publicPaths = map[string]bool{
+	"/healthz":      true,
+	"/readyz":       true,
+	"/metrics":      true,
+	"/openapi.yaml": true,
+	"/openapi.json": true,
+}
+
+// WithAuthenticator requires every request, except health checks, metrics,
+// the API document and the playground's static files, to carry an API key or
+// bearer token accepted by authenticator. Over TLS, requests without either
+// are identified by their client certificate if authenticator implements
+// ports.CertificateAuthenticator.
+func WithAuthenticator(authenticator ports.Authenticator) Option {
+	return func(a *Adapter) {
+		a.authenticator = authenticator
+	}
+}
+
+// withAuth authenticates the caller and makes the principal available to the
+// core through the context.
+func (a *Adapter) withAuth(next http.Handler) http.Handler {
+	if a.authenticator == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if publicPaths[r.URL.Path] || strings.HasPrefix(r.URL.Path, uiPath) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		var token string
+		if scheme, credentials, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") {
+			token = strings.TrimSpace(credentials)
+		}
+		principal, err := a.authenticate(r, token)
+		if err != nil {
+			a.logger.InfoContext(r.Context(), "authentication failed", "error", err, "path", r.URL.Path)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="mangle-service"`)
+			a.writeError(w, "missing or invalid credentials", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(domain.ContextWithPrincipal(r.Context(), principal)))
+	})
+}
+
+// authenticate prefers explicit credentials over the client certificate, so
+// that a client connecting with the certificate of its host may still act
+// for a user.
+func (a *Adapter) authenticate(r *http.Request, token string) (*domain.Principal, error) {
+	apiKey := r.Header.Get(apiKeyHeader)
+	certs, ok := a.authenticator.(ports.CertificateAuthenticator)
+	if apiKey != "" || token != "" || !ok || r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+		return a.authenticator.Authenticate(apiKey, token)
+	}
+	return certs.AuthenticateCertificate(r.TLS.VerifiedChains[0][0])
+}
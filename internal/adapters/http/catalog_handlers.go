@@ -0,0 +1,12 @@
+package http
+
+import "net/http"
+
+func (a *Adapter) handlePredicateCatalog(w http.ResponseWriter, r *http.Request) {
+	catalog, err := a.service.Catalog(r.Context())
+	if err != nil {
+		a.writeQueryError(w, r, err)
+		return
+	}
+	a.writeJSON(w, catalog, http.StatusOK)
+}
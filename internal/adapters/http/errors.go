@@ -0,0 +1,132 @@
+package http
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"mangle-service/internal/core/domain"
+	"mangle-service/pkg/logger"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// requestIDHeader carries the request ID. Clients may set it to correlate
+// their own logs; otherwise the adapter generates one.
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds client-supplied request IDs, which end up in logs.
+const maxRequestIDLength = 128
+
+// errorResponse is the body of every error response.
+type errorResponse struct {
+	Code      string         `json:"code"`
+	Message   string         `json:"message"`
+	Details   map[string]any `json:"details,omitempty"`
+	RequestID string         `json:"request_id,omitempty"`
+}
+
+// queryErrorStatus maps query error codes to HTTP status codes.
+var queryErrorStatus = map[domain.ErrorCode]int{
+	domain.CodeInvalidQuery:      http.StatusBadRequest,
+	domain.CodeParseError:        http.StatusBadRequest,
+	domain.CodeForbidden:         http.StatusForbidden,
+	domain.CodeAnalysisError:     http.StatusUnprocessableEntity,
+	domain.CodeEvaluationError:   http.StatusUnprocessableEntity,
+	domain.CodeBudgetExceeded:    http.StatusUnprocessableEntity,
+	domain.CodeRateLimited:       http.StatusTooManyRequests,
+	domain.CodeSourceUnavailable: http.StatusBadGateway,
+	domain.CodeOverloaded:        http.StatusServiceUnavailable,
+	domain.CodeTimeout:           http.StatusGatewayTimeout,
+	// The client has gone away; nginx's non-standard status makes this
+	// visible in access logs.
+	domain.CodeCanceled: 499,
+	domain.CodeInternal: http.StatusInternalServerError,
+}
+
+// withRequestID assigns every request an ID, echoes it in the response
+// headers and makes it, the client address and the channel available to the
+// core through the context. Every log line emitted with the context carries
+// the ID.
+func (a *Adapter) withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if id == "" || len(id) > maxRequestIDLength {
+			id = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, id)
+		ctx := domain.ContextWithChannel(domain.ContextWithRequestID(r.Context(), id), domain.ChannelHTTP)
+		ctx = logger.ContextWithAttrs(ctx, slog.String("request_id", id))
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			ctx = domain.ContextWithClientAddress(ctx, host)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// writeQueryError writes the response for an error returned while running a
+// query. Details of server-side failures are logged but not sent to clients.
+func (a *Adapter) writeQueryError(w http.ResponseWriter, r *http.Request, err error) {
+	var qerr *domain.QueryError
+	if !errors.As(err, &qerr) {
+		a.logger.ErrorContext(r.Context(), "error executing query", "error", err)
+		a.writeError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	status, ok := queryErrorStatus[qerr.Code]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	if qerr.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(qerr.RetryAfter.Seconds()))))
+	}
+	message := qerr.Error()
+	// Shedding load is expected under pressure and says nothing secret, so
+	// it is neither logged as an error nor hidden from the client.
+	if status >= http.StatusInternalServerError && qerr.Code != domain.CodeOverloaded {
+		a.logger.ErrorContext(r.Context(), "error executing query", "error", err, "code", qerr.Code)
+		message = qerr.Message
+	}
+	a.writeErrorResponse(w, errorResponse{Code: string(qerr.Code), Message: message, Details: qerr.Details}, status)
+}
+
+func (a *Adapter) writeErrorResponse(w http.ResponseWriter, resp errorResponse, status int) {
+	resp.RequestID = w.Header().Get(requestIDHeader)
+	a.writeJSON(w, resp, status)
+}
+
+// statusCode returns the error code used for errors that are not query errors.
+func statusCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "invalid_request"
+	case http.StatusUnauthorized:
+		return "unauthenticated"
+	case http.StatusForbidden:
+		return string(domain.CodeForbidden)
+	case http.StatusNotFound:
+		return "not_found"
+	case http.StatusMethodNotAllowed:
+		return "method_not_allowed"
+	case http.StatusPreconditionFailed:
+		return "version_conflict"
+	case http.StatusPreconditionRequired:
+		return "precondition_required"
+	case http.StatusUnprocessableEntity:
+		return "invalid_request"
+	default:
+		if status >= http.StatusInternalServerError {
+			return string(domain.CodeInternal)
+		}
+		return "error"
+	}
+}
@@ -0,0 +1,58 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"mangle-service/internal/adapters/graphexport"
+	"net/http"
+	"slices"
+)
+
+// graphRequest selects the output format and an optional query whose results
+// are highlighted. It can be sent as a JSON body or as URL parameters.
+type graphRequest struct {
+	Format string `json:"format"`
+	Query  string `json:"query"`
+}
+
+func (a *Adapter) handleGraph(w http.ResponseWriter, r *http.Request) {
+	req := graphRequest{
+		Format: r.URL.Query().Get("format"),
+		Query:  r.URL.Query().Get("query"),
+	}
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			a.writeError(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Format == "" {
+		req.Format = graphexport.FormatDOT
+	}
+	if req.Format != "json" && !slices.Contains(graphexport.Formats, req.Format) {
+		a.writeError(w, "unsupported format: "+req.Format, http.StatusBadRequest)
+		return
+	}
+
+	graph, err := a.graphs.GetGraph(r.Context(), req.Query)
+	if err != nil {
+		a.writeQueryError(w, r, err)
+		return
+	}
+	if req.Format == "json" {
+		a.writeJSON(w, graph, http.StatusOK)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := graphexport.Render(&buf, graph, req.Format); err != nil {
+		a.logger.ErrorContext(r.Context(), "error rendering relationship graph", "error", err)
+		a.writeError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", graphexport.ContentType(req.Format))
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		a.logger.ErrorContext(r.Context(), "failed to write graph response", "error", err)
+	}
+}
@@ -0,0 +1,49 @@
+package http
+
+import (
+	"mangle-service/internal/core/domain"
+	"mangle-service/internal/core/ports"
+	"net/http"
+)
+
+// WithHealthService enables the readiness and detailed health endpoints.
+func WithHealthService(health ports.HealthService) Option {
+	return func(a *Adapter) {
+		a.health = health
+	}
+}
+
+// handleReadiness answers 200 while the service can serve queries and 503
+// otherwise. The endpoint is public, so only the status of each check is
+// reported; see handleHealthDetails.
+func (a *Adapter) handleReadiness(w http.ResponseWriter, r *http.Request) {
+	report := a.health.Check(r.Context())
+	summary := &domain.HealthReport{
+		Status:    report.Status,
+		CheckedAt: report.CheckedAt,
+		Checks:    make([]domain.HealthCheck, len(report.Checks)),
+	}
+	for i, check := range report.Checks {
+		summary.Checks[i] = domain.HealthCheck{
+			Name:       check.Name,
+			Kind:       check.Kind,
+			Status:     check.Status,
+			DurationMS: check.DurationMS,
+		}
+	}
+	a.writeJSON(w, summary, readinessStatus(report))
+}
+
+// handleHealthDetails reports the result of every check, including why it
+// failed.
+func (a *Adapter) handleHealthDetails(w http.ResponseWriter, r *http.Request) {
+	report := a.health.Check(r.Context())
+	a.writeJSON(w, report, readinessStatus(report))
+}
+
+func readinessStatus(report *domain.HealthReport) int {
+	if report.Ready() {
+		return http.StatusOK
+	}
+	return http.StatusServiceUnavailable
+}
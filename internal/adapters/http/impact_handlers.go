@@ -0,0 +1,46 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"mangle-service/internal/core/domain"
+	"net/http"
+	"strconv"
+)
+
+// handleImpact serves both GET /impact/{service}?max_depth=N for quick lookups
+// and POST /impact with a full domain.ImpactRequest body.
+func (a *Adapter) handleImpact(w http.ResponseWriter, r *http.Request) {
+	var req domain.ImpactRequest
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			a.writeError(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+	} else {
+		req.Service = r.PathValue("service")
+		if maxDepth := r.URL.Query().Get("max_depth"); maxDepth != "" {
+			depth, err := strconv.Atoi(maxDepth)
+			if err != nil {
+				a.writeError(w, "invalid max_depth", http.StatusBadRequest)
+				return
+			}
+			req.MaxDepth = depth
+		}
+	}
+	if req.Service == "" {
+		a.writeError(w, "service is required", http.StatusBadRequest)
+		return
+	}
+
+	result, err := a.impact.AnalyzeImpact(r.Context(), req)
+	if errors.Is(err, domain.ErrServiceNotFound) {
+		a.writeError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		a.writeQueryError(w, r, err)
+		return
+	}
+	a.writeJSON(w, result, http.StatusOK)
+}
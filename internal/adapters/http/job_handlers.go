@@ -0,0 +1,56 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"mangle-service/internal/core/domain"
+	"mangle-service/internal/core/ports"
+	"net/http"
+)
+
+// WithJobService enables the asynchronous query job endpoints.
+func WithJobService(jobs ports.JobService) Option {
+	return func(a *Adapter) {
+		a.jobs = jobs
+	}
+}
+
+// handleSubmitJob queues a query and answers with the job, whose URL is in
+// the Location header.
+func (a *Adapter) handleSubmitJob(w http.ResponseWriter, r *http.Request) {
+	var req domain.QueryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.writeError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	job, err := a.jobs.Submit(r.Context(), req)
+	if err != nil {
+		a.writeQueryError(w, r, err)
+		return
+	}
+	w.Header().Set("Location", "/jobs/"+job.ID)
+	a.writeJSON(w, job, http.StatusAccepted)
+}
+
+func (a *Adapter) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	job, err := a.jobs.GetJob(r.Context(), r.PathValue("id"))
+	a.writeJob(w, job, err)
+}
+
+func (a *Adapter) handleCancelJob(w http.ResponseWriter, r *http.Request) {
+	job, err := a.jobs.CancelJob(r.Context(), r.PathValue("id"))
+	a.writeJob(w, job, err)
+}
+
+func (a *Adapter) writeJob(w http.ResponseWriter, job *domain.Job, err error) {
+	if errors.Is(err, domain.ErrJobNotFound) {
+		a.writeError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		a.logger.Error("failed to access job", "error", err)
+		a.writeError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	a.writeJSON(w, job, http.StatusOK)
+}
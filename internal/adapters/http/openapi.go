@@ -0,0 +1,45 @@
+package http
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// openAPISpec describes every endpoint of the adapter. Tests check that it
+// lists exactly the registered routes.
+//
+//go:embed openapi.yaml
+var openAPISpec []byte
+
+// openAPISpecJSON converts the document to JSON once, on first use.
+var openAPISpecJSON = sync.OnceValues(func() ([]byte, error) {
+	var spec map[string]any
+	if err := yaml.Unmarshal(openAPISpec, &spec); err != nil {
+		return nil, err
+	}
+	return json.Marshal(spec)
+})
+
+func (a *Adapter) handleOpenAPIYAML(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml")
+	if _, err := w.Write(openAPISpec); err != nil {
+		a.logger.Error("failed to write OpenAPI document", "error", err)
+	}
+}
+
+func (a *Adapter) handleOpenAPIJSON(w http.ResponseWriter, r *http.Request) {
+	spec, err := openAPISpecJSON()
+	if err != nil {
+		a.logger.Error("invalid OpenAPI document", "error", err)
+		a.writeError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(spec); err != nil {
+		a.logger.Error("failed to write OpenAPI document", "error", err)
+	}
+}
@@ -0,0 +1,170 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"mangle-service/internal/core/domain"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+func (a *Adapter) registerRelationshipRoutes() {
+	a.handle("GET /relationships", a.requireRelationships(a.handleListRelationships))
+	a.handle("GET /relationships/{service}", a.requireRelationships(a.handleGetService))
+	a.handle("GET /lint/relationships", a.requireRelationships(a.handleLintRelationships))
+	// Every query's depends_on facts come from the graph, so changing it
+	// requires an admin role, and thus an authenticator.
+	if a.authenticator == nil {
+		return
+	}
+	a.handle("PUT /relationships/{service}", a.requireAdmin(a.handlePutService))
+	a.handle("DELETE /relationships/{service}", a.requireAdmin(a.handleDeleteService))
+	a.handle("PUT /relationships/{service}/depends_on/{dependency}", a.requireAdmin(a.handleAddEdge))
+	a.handle("DELETE /relationships/{service}/depends_on/{dependency}", a.requireAdmin(a.handleRemoveEdge))
+}
+
+// requireRelationships refuses callers that the access policy denies the
+// relationship predicates, since the wrapped endpoints show the same edges.
+func (a *Adapter) requireRelationships(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := a.service.AuthorizeRelationships(r.Context()); err != nil {
+			a.writeQueryError(w, r, err)
+			return
+		}
+		next(w, r)
+	}
+}
+
+type lintResponse struct {
+	Version int64              `json:"version"`
+	Valid   bool               `json:"valid"`
+	Issues  []domain.LintIssue `json:"issues"`
+}
+
+type putServiceRequest struct {
+	DependsOn   []string `json:"depends_on"`
+	Criticality string   `json:"criticality"`
+}
+
+func (a *Adapter) handleListRelationships(w http.ResponseWriter, r *http.Request) {
+	config := a.relationships.GetConfig()
+	setETag(w, config.Version)
+	a.writeJSON(w, config, http.StatusOK)
+}
+
+func (a *Adapter) handleGetService(w http.ResponseWriter, r *http.Request) {
+	rel, version, err := a.relationships.GetService(r.PathValue("service"))
+	if err != nil {
+		a.writeRelationshipError(w, err)
+		return
+	}
+	setETag(w, version)
+	a.writeJSON(w, rel, http.StatusOK)
+}
+
+func (a *Adapter) handleLintRelationships(w http.ResponseWriter, r *http.Request) {
+	config := a.relationships.GetConfig()
+	issues := a.relationships.Lint()
+	if issues == nil {
+		issues = []domain.LintIssue{}
+	}
+	a.writeJSON(w, lintResponse{
+		Version: config.Version,
+		Valid:   !domain.HasLintErrors(issues),
+		Issues:  issues,
+	}, http.StatusOK)
+}
+
+func (a *Adapter) handlePutService(w http.ResponseWriter, r *http.Request) {
+	expectedVersion, ok := a.expectedVersion(w, r)
+	if !ok {
+		return
+	}
+	var req putServiceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.writeError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	rel := domain.ServiceRelationship{
+		Service:     r.PathValue("service"),
+		DependsOn:   req.DependsOn,
+		Criticality: req.Criticality,
+	}
+	version, err := a.relationships.PutService(rel, expectedVersion)
+	a.writeRelationshipChange(w, version, err)
+}
+
+func (a *Adapter) handleDeleteService(w http.ResponseWriter, r *http.Request) {
+	expectedVersion, ok := a.expectedVersion(w, r)
+	if !ok {
+		return
+	}
+	version, err := a.relationships.DeleteService(r.PathValue("service"), expectedVersion)
+	a.writeRelationshipChange(w, version, err)
+}
+
+func (a *Adapter) handleAddEdge(w http.ResponseWriter, r *http.Request) {
+	expectedVersion, ok := a.expectedVersion(w, r)
+	if !ok {
+		return
+	}
+	version, err := a.relationships.AddEdge(r.PathValue("service"), r.PathValue("dependency"), expectedVersion)
+	a.writeRelationshipChange(w, version, err)
+}
+
+func (a *Adapter) handleRemoveEdge(w http.ResponseWriter, r *http.Request) {
+	expectedVersion, ok := a.expectedVersion(w, r)
+	if !ok {
+		return
+	}
+	version, err := a.relationships.RemoveEdge(r.PathValue("service"), r.PathValue("dependency"), expectedVersion)
+	a.writeRelationshipChange(w, version, err)
+}
+
+// expectedVersion reads the If-Match header. Writes must name the version they
+// are based on, or "*" to overwrite unconditionally.
+func (a *Adapter) expectedVersion(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	ifMatch := strings.TrimSpace(r.Header.Get("If-Match"))
+	if ifMatch == "" {
+		a.writeError(w, "If-Match header is required", http.StatusPreconditionRequired)
+		return 0, false
+	}
+	if ifMatch == "*" {
+		return domain.AnyVersion, true
+	}
+	version, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(ifMatch, "W/"), `"`), 10, 64)
+	if err != nil {
+		a.writeError(w, "invalid If-Match header", http.StatusBadRequest)
+		return 0, false
+	}
+	return version, true
+}
+
+func (a *Adapter) writeRelationshipChange(w http.ResponseWriter, version int64, err error) {
+	if err != nil {
+		a.writeRelationshipError(w, err)
+		return
+	}
+	setETag(w, version)
+	a.writeJSON(w, map[string]int64{"version": version}, http.StatusOK)
+}
+
+func (a *Adapter) writeRelationshipError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, domain.ErrServiceNotFound), errors.Is(err, domain.ErrEdgeNotFound):
+		a.writeError(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, domain.ErrVersionConflict):
+		a.writeError(w, err.Error(), http.StatusPreconditionFailed)
+	case errors.Is(err, domain.ErrInvalidRelationship):
+		a.writeError(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		a.logger.Error("error updating relationships", "error", err)
+		a.writeError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+func setETag(w http.ResponseWriter, version int64) {
+	w.Header().Set("ETag", strconv.Quote(strconv.FormatInt(version, 10)))
+}
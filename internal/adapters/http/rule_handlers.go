@@ -0,0 +1,20 @@
+package http
+
+import "net/http"
+
+func (a *Adapter) handleListRuleModules(w http.ResponseWriter, r *http.Request) {
+	a.writeJSON(w, a.ruleModules.GetModules(), http.StatusOK)
+}
+
+// handleReloadRuleModules reloads the rule modules from disk. If a module is
+// invalid, the previous modules stay active and the error is reported.
+func (a *Adapter) handleReloadRuleModules(w http.ResponseWriter, r *http.Request) {
+	if err := a.ruleModules.Reload(); err != nil {
+		a.logger.WarnContext(r.Context(), "failed to reload rule modules", "error", err)
+		a.writeError(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	modules := a.ruleModules.GetModules()
+	a.logger.InfoContext(r.Context(), "reloaded rule modules", "modules", len(modules.Modules))
+	a.writeJSON(w, modules, http.StatusOK)
+}
@@ -2,50 +2,216 @@ package http
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"log/slog"
+	"mangle-service/internal/adapters/resultformat"
 	"mangle-service/internal/core/domain"
 	"mangle-service/internal/core/ports"
+	"mangle-service/pkg/logger"
+	"net"
 	"net/http"
 	"time"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
+// readHeaderTimeout bounds how long a client may take to send the headers
+// of a request.
+const readHeaderTimeout = 10 * time.Second
+
 type Adapter struct {
-	service ports.QueryService
-	logger  *slog.Logger
-	server  *http.Server
-	router  *http.ServeMux
+	service       ports.QueryService
+	relationships ports.RelationshipService
+	graphs        ports.GraphService
+	impact        ports.ImpactService
+	ruleModules   ports.RuleModuleService
+	authenticator ports.Authenticator
+	admission     ports.AdmissionService
+	jobs          ports.JobService
+	health        ports.HealthService
+	audit         ports.AuditService
+	metrics       http.Handler
+	logLevel      *slog.LevelVar
+	adminRoles    []string
+	// effectiveConfig is served at /admin/config if set.
+	effectiveConfig any
+	tracerProvider  trace.TracerProvider
+	tlsConfig       *tls.Config
+	logger          *slog.Logger
+	server          *http.Server
+	router          *http.ServeMux
+	routes          []string
+	handler         http.Handler
 }
 
-func NewAdapter(service ports.QueryService, logger *slog.Logger, port string) *Adapter {
+// Option configures optional parts of the Adapter.
+type Option func(*Adapter)
+
+// WithRelationshipService enables the relationship management endpoints.
+func WithRelationshipService(relationships ports.RelationshipService) Option {
+	return func(a *Adapter) {
+		a.relationships = relationships
+	}
+}
+
+// WithGraphService enables the relationship graph export endpoint.
+func WithGraphService(graphs ports.GraphService) Option {
+	return func(a *Adapter) {
+		a.graphs = graphs
+	}
+}
+
+// WithImpactService enables the impact analysis endpoints.
+func WithImpactService(impact ports.ImpactService) Option {
+	return func(a *Adapter) {
+		a.impact = impact
+	}
+}
+
+// WithRuleModuleService enables the rule module endpoints.
+func WithRuleModuleService(ruleModules ports.RuleModuleService) Option {
+	return func(a *Adapter) {
+		a.ruleModules = ruleModules
+	}
+}
+
+// WithAdmissionService enables the admission control statistics endpoint.
+func WithAdmissionService(admission ports.AdmissionService) Option {
+	return func(a *Adapter) {
+		a.admission = admission
+	}
+}
+
+// WithMetricsHandler serves metrics, such as those of the query pipeline, at /metrics.
+func WithMetricsHandler(metrics http.Handler) Option {
+	return func(a *Adapter) {
+		a.metrics = metrics
+	}
+}
+
+// WithTLSConfig serves HTTPS with config, which must provide the server
+// certificate, such as through GetConfigForClient.
+func WithTLSConfig(config *tls.Config) Option {
+	return func(a *Adapter) {
+		a.tlsConfig = config
+	}
+}
+
+func NewAdapter(service ports.QueryService, logger *slog.Logger, port string, opts ...Option) *Adapter {
 	mux := http.NewServeMux()
 	adapter := &Adapter{
 		service: service,
 		logger:  logger,
 		router:  mux,
-		server: &http.Server{
-			Addr:    ":" + port,
-			Handler: mux,
-		},
+	}
+	for _, opt := range opts {
+		opt(adapter)
 	}
 	adapter.registerRoutes()
+	adapter.handler = adapter.withTracing(adapter.withRequestID(adapter.withAuth(mux)))
+	adapter.server = &http.Server{
+		Addr:      ":" + port,
+		Handler:   adapter.handler,
+		TLSConfig: adapter.tlsConfig,
+		// Queries may run long, so only reading the headers is bounded; it
+		// keeps idle clients from holding connections open on shutdown.
+		ReadHeaderTimeout: readHeaderTimeout,
+		// Failed handshakes and other connection errors are logged here.
+		ErrorLog: slog.NewLogLogger(logger.Handler(), slog.LevelInfo),
+	}
 	return adapter
 }
 
 func (a *Adapter) registerRoutes() {
-	a.router.HandleFunc("/query", a.handleQuery)
-	a.router.HandleFunc("/healthz", a.handleHealthCheck)
+	a.handle("/query", a.handleQuery)
+	a.handle("/healthz", a.handleHealthCheck)
+	if a.metrics != nil {
+		a.handle("GET /metrics", a.metrics.ServeHTTP)
+	}
+	if a.health != nil {
+		a.handle("GET /readyz", a.handleReadiness)
+		a.handle("GET /health/details", a.handleHealthDetails)
+	}
+	a.handle("GET /openapi.yaml", a.handleOpenAPIYAML)
+	a.handle("GET /openapi.json", a.handleOpenAPIJSON)
+	a.handle("GET /predicates", a.handlePredicateCatalog)
+	a.handle("GET "+uiPath, uiHandler())
+	if a.relationships != nil {
+		a.registerRelationshipRoutes()
+	}
+	if a.graphs != nil {
+		a.handle("GET /graph", a.requireRelationships(a.handleGraph))
+		a.handle("POST /graph", a.requireRelationships(a.handleGraph))
+	}
+	if a.impact != nil {
+		a.handle("GET /impact/{service}", a.requireRelationships(a.handleImpact))
+		a.handle("POST /impact", a.requireRelationships(a.handleImpact))
+	}
+	if a.ruleModules != nil {
+		a.handle("GET /rules", a.handleListRuleModules)
+		// Reloading swaps the rules of every caller's queries.
+		if a.authenticator != nil {
+			a.handle("POST /rules/reload", a.requireAdmin(a.handleReloadRuleModules))
+		}
+	}
+	if a.admission != nil {
+		a.handle("GET /admission", a.handleAdmissionStats)
+	}
+	if a.jobs != nil {
+		a.handle("POST /jobs", a.handleSubmitJob)
+		a.handle("GET /jobs/{id}", a.handleGetJob)
+		a.handle("DELETE /jobs/{id}", a.handleCancelJob)
+	}
+	if a.audit != nil && a.authenticator != nil {
+		a.handle("GET /audit", a.handleSearchAudit)
+	}
+	a.registerAdminRoutes()
+}
+
+// handle registers a route and records its pattern for Routes.
+func (a *Adapter) handle(pattern string, handler http.HandlerFunc) {
+	a.router.HandleFunc(pattern, traceRoute(pattern, handler))
+	a.routes = append(a.routes, pattern)
+}
+
+// Routes lists the patterns of the registered routes, such as
+// "GET /relationships/{service}". Patterns without a method accept any
+// method and check it in the handler.
+func (a *Adapter) Routes() []string {
+	return append([]string(nil), a.routes...)
 }
 
 func (a *Adapter) GetRouter() http.Handler {
-	return a.router
+	return a.handler
 }
 
+// Start serves until Stop is called or ctx is done. Requests run with
+// contexts derived from ctx, so that cancelling it cancels their queries.
 func (a *Adapter) Start(ctx context.Context) error {
-	a.logger.Info("starting server", "addr", a.server.Addr)
-	return a.server.ListenAndServe()
+	lis, err := net.Listen("tcp", a.server.Addr)
+	if err != nil {
+		return err
+	}
+	a.server.BaseContext = func(net.Listener) context.Context { return ctx }
+	stop := context.AfterFunc(ctx, func() { a.server.Close() })
+	defer stop()
+	return a.Serve(lis)
+}
+
+// Serve serves on lis until Stop is called, over HTTPS if WithTLSConfig is
+// given.
+func (a *Adapter) Serve(lis net.Listener) error {
+	if a.tlsConfig != nil {
+		a.logger.Info("starting server", "addr", lis.Addr().String(), "tls", true)
+		return a.server.ServeTLS(lis, "", "")
+	}
+	a.logger.Info("starting server", "addr", lis.Addr().String())
+	return a.server.Serve(lis)
 }
 
+// Stop stops accepting connections and waits for the requests in progress
+// until ctx is done.
 func (a *Adapter) Stop(ctx context.Context) error {
 	a.logger.Info("stopping server")
 	return a.server.Shutdown(ctx)
@@ -53,7 +219,7 @@ func (a *Adapter) Stop(ctx context.Context) error {
 
 func (a *Adapter) handleHealthCheck(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "invalid method", http.StatusMethodNotAllowed)
+		a.writeError(w, "invalid method", http.StatusMethodNotAllowed)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
@@ -61,7 +227,7 @@ func (a *Adapter) handleHealthCheck(w http.ResponseWriter, r *http.Request) {
 
 func (a *Adapter) handleQuery(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "invalid method", http.StatusMethodNotAllowed)
+		a.writeError(w, "invalid method", http.StatusMethodNotAllowed)
 		return
 	}
 
@@ -71,16 +237,33 @@ func (a *Adapter) handleQuery(w http.ResponseWriter, r *http.Request) {
 		a.writeError(w, "invalid request body", http.StatusBadRequest)
 		return
 	}
+	r = r.WithContext(logger.ContextWithAttrs(r.Context(), slog.String("query_hash", req.Hash())))
+
+	mediaType, ok := queryMediaType(r)
+	if !ok {
+		a.writeError(w, "unsupported format: "+r.URL.Query().Get("format"), http.StatusBadRequest)
+		return
+	}
+	if mediaType == mediaTypeNDJSON || mediaType == mediaTypeSSE {
+		a.streamQuery(w, r, req, mediaType)
+		return
+	}
 
 	result, err := a.service.ExecuteQuery(r.Context(), req)
 	if err != nil {
-		a.logger.Error("error executing query", "error", err)
-		a.writeError(w, "internal server error", http.StatusInternalServerError)
+		a.writeQueryError(w, r, err)
 		return
 	}
 
-	a.writeJSON(w, result, http.StatusOK)
-	a.logger.Info("processed query", "duration", time.Since(start), "query", req.Query, "results", result.Count)
+	if format, ok := resultformat.FormatForMediaType(mediaType); ok {
+		w.Header().Set("Content-Type", resultformat.ContentType(format))
+		if err := resultformat.Render(w, result, format); err != nil {
+			a.logger.ErrorContext(r.Context(), "failed to write query response", "error", err)
+		}
+	} else {
+		a.writeJSON(w, result, http.StatusOK)
+	}
+	a.logger.InfoContext(r.Context(), "processed query", "duration", time.Since(start), "results", result.Count)
 }
 
 func (a *Adapter) writeJSON(w http.ResponseWriter, data interface{}, status int) {
@@ -91,6 +274,7 @@ func (a *Adapter) writeJSON(w http.ResponseWriter, data interface{}, status int)
 	}
 }
 
+// writeError writes an error response whose code is derived from the status.
 func (a *Adapter) writeError(w http.ResponseWriter, message string, status int) {
-	a.writeJSON(w, map[string]string{"error": message}, status)
+	a.writeErrorResponse(w, errorResponse{Code: statusCode(status), Message: message}, status)
 }
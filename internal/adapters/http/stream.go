@@ -0,0 +1,144 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"mangle-service/internal/adapters/resultformat"
+	"mangle-service/internal/core/domain"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Media types of the query endpoint.
+const (
+	mediaTypeJSON   = "application/json"
+	mediaTypeNDJSON = "application/x-ndjson"
+	mediaTypeSSE    = "text/event-stream"
+)
+
+// mediaTypeAliases maps alternative names to the media types above.
+var mediaTypeAliases = map[string]string{
+	"application/ndjson":    mediaTypeNDJSON,
+	"application/jsonlines": mediaTypeNDJSON,
+}
+
+// queryFormats maps values of the format query parameter to media types.
+var queryFormats = map[string]string{
+	"json":                    mediaTypeJSON,
+	"ndjson":                  mediaTypeNDJSON,
+	"sse":                     mediaTypeSSE,
+	resultformat.FormatCSV:    resultformat.MediaType(resultformat.FormatCSV),
+	resultformat.FormatTSV:    resultformat.MediaType(resultformat.FormatTSV),
+	resultformat.FormatTable:  resultformat.MediaType(resultformat.FormatTable),
+	resultformat.FormatMangle: resultformat.MediaType(resultformat.FormatMangle),
+}
+
+// queryMediaType picks the response media type of a query from the format
+// parameter or, without one, from the Accept header. It returns false for an
+// unknown format.
+func queryMediaType(r *http.Request) (string, bool) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		mediaType, ok := queryFormats[format]
+		return mediaType, ok
+	}
+	offers := []string{mediaTypeJSON, mediaTypeNDJSON, mediaTypeSSE}
+	for _, format := range resultformat.Formats {
+		offers = append(offers, resultformat.MediaType(format))
+	}
+	return negotiate(r.Header.Get("Accept"), offers...), true
+}
+
+// negotiate returns the first media type in the Accept header that is
+// offered, or the first offer if none is. Quality values other than q=0 are
+// not taken into account.
+func negotiate(accept string, offers ...string) string {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || params["q"] == "0" {
+			continue
+		}
+		if alias, ok := mediaTypeAliases[mediaType]; ok {
+			mediaType = alias
+		}
+		for _, offer := range offers {
+			if mediaType == offer {
+				return offer
+			}
+		}
+	}
+	return offers[0]
+}
+
+// resultStream writes query results as NDJSON records or Server-Sent Events
+// and flushes after each one. Every record is an object with a single key
+// naming its kind: result, summary or error. The same names are used as SSE
+// event types.
+type resultStream struct {
+	w         http.ResponseWriter
+	mediaType string
+	flusher   http.Flusher
+	started   bool
+}
+
+func newResultStream(w http.ResponseWriter, mediaType string) *resultStream {
+	flusher, _ := w.(http.Flusher)
+	return &resultStream{w: w, mediaType: mediaType, flusher: flusher}
+}
+
+func (s *resultStream) send(kind string, v any) error {
+	if !s.started {
+		s.started = true
+		s.w.Header().Set("Content-Type", s.mediaType)
+		s.w.Header().Set("Cache-Control", "no-cache")
+		s.w.WriteHeader(http.StatusOK)
+	}
+
+	var err error
+	if s.mediaType == mediaTypeSSE {
+		var data []byte
+		if data, err = json.Marshal(v); err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", kind, data)
+	} else {
+		err = json.NewEncoder(s.w).Encode(map[string]any{kind: v})
+	}
+	if err != nil {
+		return err
+	}
+	if s.flusher != nil {
+		s.flusher.Flush()
+	}
+	return nil
+}
+
+// streamQuery serves a query whose results are streamed. Errors that happen
+// before the first result get a regular error response; later ones are sent
+// as a final error record, since the status has already been written.
+func (a *Adapter) streamQuery(w http.ResponseWriter, r *http.Request, req domain.QueryRequest, mediaType string) {
+	start := time.Now()
+	stream := newResultStream(w, mediaType)
+	summary, err := a.service.StreamQuery(r.Context(), req, func(entry domain.LogEntry) error {
+		return stream.send("result", entry)
+	})
+	if err != nil {
+		if !stream.started {
+			a.writeQueryError(w, r, err)
+			return
+		}
+		if r.Context().Err() != nil {
+			a.logger.InfoContext(r.Context(), "client disconnected from query stream", "duration", time.Since(start))
+			return
+		}
+		a.logger.ErrorContext(r.Context(), "error streaming query results", "error", err)
+		_ = stream.send("error", errorResponse{Code: string(domain.CodeInternal), Message: "failed to stream results", RequestID: w.Header().Get(requestIDHeader)})
+		return
+	}
+	if err := stream.send("summary", summary); err != nil {
+		a.logger.WarnContext(r.Context(), "failed to write query summary", "error", err)
+		return
+	}
+	a.logger.InfoContext(r.Context(), "streamed query", "duration", time.Since(start), "results", summary.Count)
+}
@@ -0,0 +1,54 @@
+package http
+
+import (
+	"mangle-service/internal/core/domain"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// WithTracerProvider traces every request with a span from tp. Requests
+// carrying a W3C traceparent header continue the caller's trace.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(a *Adapter) {
+		a.tracerProvider = tp
+	}
+}
+
+// withTracing starts the span of every request. The span is named after the
+// method until the request is routed; see traceRoute.
+func (a *Adapter) withTracing(next http.Handler) http.Handler {
+	if a.tracerProvider == nil {
+		return next
+	}
+	return otelhttp.NewHandler(next, "http.request",
+		otelhttp.WithTracerProvider(a.tracerProvider),
+		otelhttp.WithPropagators(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})),
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return r.Method
+		}),
+	)
+}
+
+// traceRoute names the request's span after the route pattern, rather than
+// the path, so that requests for different jobs or services share a name.
+func traceRoute(pattern string, handler http.HandlerFunc) http.HandlerFunc {
+	_, route, ok := strings.Cut(pattern, " ")
+	if !ok {
+		route = pattern
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if span := trace.SpanFromContext(r.Context()); span.IsRecording() {
+			span.SetName(r.Method + " " + route)
+			span.SetAttributes(
+				attribute.String("http.route", route),
+				attribute.String("mangle.request_id", domain.RequestIDFromContext(r.Context())),
+			)
+		}
+		handler(w, r)
+	}
+}
@@ -0,0 +1,32 @@
+package http
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// uiPath is where the query playground is served. Its static files are
+// public; the API calls it makes carry the credentials entered in the page.
+const uiPath = "/ui/"
+
+//go:embed ui
+var uiFiles embed.FS
+
+// uiContentSecurityPolicy keeps the playground from loading anything that is
+// not served by the service itself.
+const uiContentSecurityPolicy = "default-src 'self'; img-src 'self' data:; frame-ancestors 'none'"
+
+// uiHandler serves the embedded query playground.
+func uiHandler() http.HandlerFunc {
+	files, err := fs.Sub(uiFiles, "ui")
+	if err != nil {
+		panic(err)
+	}
+	fileServer := http.StripPrefix(uiPath, http.FileServerFS(files))
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Security-Policy", uiContentSecurityPolicy)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		fileServer.ServeHTTP(w, r)
+	}
+}
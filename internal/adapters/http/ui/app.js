@@ -0,0 +1,535 @@
+// Mangle Playground: a small, dependency-free client for the mangle-service
+// HTTP API. It is served by the service itself, so API paths are relative to
+// the page.
+"use strict";
+
+const api = (path) => new URL("../" + path, window.location.href);
+
+const $ = (id) => document.getElementById(id);
+
+const storage = {
+  get: (key, fallback) => window.localStorage.getItem("mangle." + key) ?? fallback,
+  set: (key, value) => window.localStorage.setItem("mangle." + key, value),
+};
+
+const exampleQuery = `# Services that failed together with a service they call.
+failed(Service, Trace) :- logs(Trace, Service, 500, _).
+cascading_failure(Upstream, Downstream) :-
+  depends_on(Upstream, Downstream),
+  failed(Upstream, Trace),
+  failed(Downstream, Trace).
+cascading_failure(Upstream, Downstream).
+`;
+
+// ---------------------------------------------------------------------------
+// API calls
+
+class APIError extends Error {
+  constructor(status, body) {
+    super(body.message || `request failed with status ${status}`);
+    this.status = status;
+    this.code = body.code || "";
+    this.requestID = body.request_id || "";
+  }
+}
+
+async function request(path, options = {}) {
+  const headers = { Accept: "application/json", ...options.headers };
+  const key = $("api-key").value.trim();
+  if (key) {
+    headers["X-API-Key"] = key;
+  }
+  if (options.body !== undefined) {
+    headers["Content-Type"] = "application/json";
+  }
+  const resp = await fetch(api(path), {
+    method: options.method || "GET",
+    headers,
+    body: options.body === undefined ? undefined : JSON.stringify(options.body),
+  });
+  let body = {};
+  try {
+    body = await resp.json();
+  } catch {
+    // Not every error response is JSON, e.g. those of proxies.
+  }
+  if (!resp.ok) {
+    throw new APIError(resp.status, body);
+  }
+  return body;
+}
+
+// ---------------------------------------------------------------------------
+// Editor with syntax highlighting
+//
+// The textarea is transparent and sits on top of a <pre> holding the same
+// text, split into coloured tokens.
+
+const tokenPattern = new RegExp(
+  [
+    /(#[^\n]*)/, // comment
+    /("(?:[^"\\\n]|\\.)*"?|'(?:[^'\\\n]|\\.)*'?)/, // string
+    /(\/[A-Za-z_]\w*(?:[.\/][A-Za-z_]\w*)*)/, // name constant
+    /(-?\b\d+(?:\.\d+)?\b)/, // number
+    /(:-|⟸|\|>|!=|<=|>=|[<>=!])/, // operator
+    /\b(Decl|Package|Use|bound|descr|do|let)\b/, // keyword
+    /\b(fn:[\w:]+)/, // function
+    /\b([A-Z_]\w*)/, // variable
+    /\b([a-z][\w.:]*)(?=\s*\()/, // predicate
+  ]
+    .map((re) => re.source)
+    .join("|"),
+  "g",
+);
+const tokenClasses = ["comment", "string", "name", "number", "op", "keyword", "keyword", "var", "pred"];
+
+function escapeHTML(text) {
+  return text.replace(/[&<>"]/g, (c) => ({ "&": "&amp;", "<": "&lt;", ">": "&gt;", '"': "&quot;" })[c]);
+}
+
+function highlight(text) {
+  let html = "";
+  let last = 0;
+  for (const match of text.matchAll(tokenPattern)) {
+    const group = match.slice(1).findIndex((g) => g !== undefined);
+    html += escapeHTML(text.slice(last, match.index));
+    html += `<span class="tok-${tokenClasses[group]}">${escapeHTML(match[0])}</span>`;
+    last = match.index + match[0].length;
+  }
+  // A trailing newline needs content after it to take up a line in <pre>.
+  return html + escapeHTML(text.slice(last)) + "\n";
+}
+
+function initEditor() {
+  const textarea = $("query");
+  const pre = $("highlight");
+  const render = () => {
+    pre.innerHTML = highlight(textarea.value);
+    pre.scrollTop = textarea.scrollTop;
+    pre.scrollLeft = textarea.scrollLeft;
+  };
+  textarea.value = storage.get("query", exampleQuery);
+  textarea.addEventListener("input", () => {
+    storage.set("query", textarea.value);
+    render();
+  });
+  textarea.addEventListener("scroll", () => {
+    pre.scrollTop = textarea.scrollTop;
+    pre.scrollLeft = textarea.scrollLeft;
+  });
+  textarea.addEventListener("keydown", (event) => {
+    if (event.key === "Enter" && (event.ctrlKey || event.metaKey)) {
+      event.preventDefault();
+      runQuery();
+    } else if (event.key === "Tab" && !event.shiftKey) {
+      event.preventDefault();
+      insertAtCursor("  ");
+    }
+  });
+  render();
+}
+
+function insertAtCursor(text) {
+  const textarea = $("query");
+  textarea.focus();
+  textarea.setRangeText(text, textarea.selectionStart, textarea.selectionEnd, "end");
+  textarea.dispatchEvent(new Event("input"));
+}
+
+// ---------------------------------------------------------------------------
+// Predicate catalog
+
+const originTitles = {
+  logs: "Log sources",
+  relationships: "Relationships",
+  module: "Rule modules",
+};
+
+let catalog = { predicates: [], sources: [] };
+
+async function loadCatalog() {
+  try {
+    catalog = await request("predicates");
+  } catch (err) {
+    $("catalog-list").innerHTML = `<p class="muted">${escapeHTML(catalogError(err))}</p>`;
+    return;
+  }
+  renderCatalog();
+  renderSources();
+}
+
+function catalogError(err) {
+  if (err.status === 401) {
+    return "Enter an API key to see the predicates you may use.";
+  }
+  return "Could not load predicates: " + err.message;
+}
+
+function renderCatalog() {
+  const filter = $("catalog-filter").value.trim().toLowerCase();
+  const list = $("catalog-list");
+  list.replaceChildren();
+  for (const origin of Object.keys(originTitles)) {
+    const predicates = catalog.predicates.filter(
+      (p) => p.origin === origin && (!filter || p.name.toLowerCase().includes(filter)),
+    );
+    if (predicates.length === 0) {
+      continue;
+    }
+    const heading = document.createElement("h3");
+    heading.textContent = originTitles[origin];
+    list.append(heading);
+    for (const predicate of predicates) {
+      list.append(predicateButton(predicate));
+    }
+  }
+  if (list.childElementCount === 0) {
+    list.innerHTML = `<p class="muted">No predicates match.</p>`;
+  }
+}
+
+function predicateArgs(predicate) {
+  if (predicate.args && predicate.args.length === predicate.arity) {
+    return predicate.args;
+  }
+  return Array.from({ length: predicate.arity }, (_, i) => "X" + (i + 1));
+}
+
+function predicateButton(predicate) {
+  const button = document.createElement("button");
+  button.type = "button";
+  button.className = "predicate";
+  const signature = document.createElement("code");
+  signature.textContent = `${predicate.name}(${predicateArgs(predicate).join(", ")})`;
+  button.append(signature);
+  const details = [predicate.description, predicate.source && `from ${predicate.source}`].filter(Boolean);
+  if (details.length > 0) {
+    const small = document.createElement("small");
+    small.textContent = details.join(" · ");
+    button.append(small);
+  }
+  button.title = `${predicate.name}/${predicate.arity}`;
+  button.addEventListener("click", () => insertAtCursor(signature.textContent));
+  return button;
+}
+
+function renderSources() {
+  const list = $("source-list");
+  list.replaceChildren();
+  const selected = new Set(JSON.parse(storage.get("sources", "[]")));
+  for (const source of catalog.sources) {
+    const label = document.createElement("label");
+    const checkbox = document.createElement("input");
+    checkbox.type = "checkbox";
+    checkbox.value = source;
+    checkbox.checked = selected.size === 0 || selected.has(source);
+    checkbox.addEventListener("change", () => storage.set("sources", JSON.stringify(selectedSources())));
+    label.append(checkbox, " " + source);
+    list.append(label);
+  }
+  // A single source needs no choice.
+  $("sources").hidden = catalog.sources.length < 2;
+}
+
+function selectedSources() {
+  return Array.from($("source-list").querySelectorAll("input:checked"), (input) => input.value);
+}
+
+// ---------------------------------------------------------------------------
+// Time range
+
+const rangeDurations = { "15m": 15 * 60e3, "1h": 3600e3, "6h": 6 * 3600e3, "24h": 24 * 3600e3, "7d": 7 * 24 * 3600e3 };
+
+function initTimeRange() {
+  const select = $("range");
+  select.value = storage.get("range", "");
+  const update = () => {
+    $("custom-range").hidden = select.value !== "custom";
+    storage.set("range", select.value);
+  };
+  select.addEventListener("change", update);
+  update();
+}
+
+// timeRange returns the time_range of the request, or undefined for all time.
+function timeRange() {
+  const value = $("range").value;
+  if (value === "") {
+    return undefined;
+  }
+  if (value !== "custom") {
+    const now = Date.now();
+    return { from: new Date(now - rangeDurations[value]).toISOString(), to: new Date(now).toISOString() };
+  }
+  const range = {};
+  // datetime-local values are in the browser's time zone.
+  if ($("range-from").value) {
+    range.from = new Date($("range-from").value).toISOString();
+  }
+  if ($("range-to").value) {
+    range.to = new Date($("range-to").value).toISOString();
+  }
+  return range;
+}
+
+// ---------------------------------------------------------------------------
+// Running queries
+
+let lastResult = null;
+let graphStale = true;
+
+async function runQuery() {
+  const query = $("query").value;
+  if (!query.trim()) {
+    return;
+  }
+  const body = { query, time_range: timeRange() };
+  if (!$("sources").hidden) {
+    body.sources = selectedSources();
+  }
+  const button = $("run");
+  button.disabled = true;
+  setStatus("Running…");
+  try {
+    lastResult = await request("query", { method: "POST", body });
+    setStatus(`${lastResult.count} result${lastResult.count === 1 ? "" : "s"}`);
+    renderTable(lastResult);
+    graphStale = true;
+    if (activeTab() === "graph") {
+      await renderGraph();
+    }
+  } catch (err) {
+    setError(err);
+  } finally {
+    button.disabled = false;
+  }
+}
+
+function setStatus(text) {
+  const status = $("status");
+  status.className = "";
+  status.textContent = text;
+}
+
+function setError(err) {
+  const status = $("status");
+  status.className = "error";
+  let text = err.code ? `${err.code}: ${err.message}` : err.message;
+  if (err.requestID) {
+    text += `\n(request ${err.requestID})`;
+  }
+  status.textContent = text;
+}
+
+let sortState = { column: -1, descending: false };
+
+function renderTable(result) {
+  const table = $("results");
+  table.replaceChildren();
+  if (!result || result.columns.length === 0) {
+    return;
+  }
+  const rows = result.results.map((entry) => result.columns.map((column) => String(entry[column] ?? "")));
+  if (sortState.column >= 0 && sortState.column < result.columns.length) {
+    const i = sortState.column;
+    const compare = new Intl.Collator(undefined, { numeric: true }).compare;
+    rows.sort((a, b) => (sortState.descending ? -1 : 1) * compare(a[i], b[i]));
+  }
+
+  const head = table.createTHead().insertRow();
+  result.columns.forEach((column, i) => {
+    const th = document.createElement("th");
+    th.textContent = column;
+    if (i === sortState.column) {
+      th.setAttribute("aria-sort", sortState.descending ? "descending" : "ascending");
+    }
+    th.addEventListener("click", () => {
+      sortState = { column: i, descending: sortState.column === i && !sortState.descending };
+      renderTable(lastResult);
+    });
+    head.append(th);
+  });
+  const tbody = table.createTBody();
+  for (const row of rows) {
+    const tr = tbody.insertRow();
+    for (const value of row) {
+      tr.insertCell().textContent = value;
+    }
+  }
+}
+
+// ---------------------------------------------------------------------------
+// Graph view
+
+const svgNS = "http://www.w3.org/2000/svg";
+
+function svg(name, attributes = {}) {
+  const element = document.createElementNS(svgNS, name);
+  for (const [key, value] of Object.entries(attributes)) {
+    element.setAttribute(key, value);
+  }
+  return element;
+}
+
+async function renderGraph() {
+  const container = $("graph");
+  if (!graphStale) {
+    return;
+  }
+  const query = lastResult ? $("query").value : "";
+  let graph;
+  try {
+    graph = await request("graph", { method: "POST", body: { format: "json", query } });
+  } catch (err) {
+    container.textContent = err.status === 404 ? "The graph is not available on this service." : "Could not load the graph: " + err.message;
+    return;
+  }
+  graphStale = false;
+  container.replaceChildren(drawGraph(graph));
+}
+
+// layers assigns every node the length of the longest path reaching it, so
+// that edges point from left to right. Edges closing a cycle are ignored.
+function layers(graph) {
+  const outgoing = new Map(graph.nodes.map((node) => [node.id, []]));
+  for (const edge of graph.edges) {
+    outgoing.get(edge.from)?.push(edge.to);
+  }
+  const layer = new Map(graph.nodes.map((node) => [node.id, 0]));
+  const visiting = new Set();
+  const visit = (id, depth) => {
+    if (visiting.has(id) || depth < layer.get(id)) {
+      return;
+    }
+    layer.set(id, depth);
+    visiting.add(id);
+    for (const next of outgoing.get(id) || []) {
+      visit(next, depth + 1);
+    }
+    visiting.delete(id);
+  };
+  const targets = new Set(graph.edges.map((edge) => edge.to));
+  const roots = graph.nodes.filter((node) => !targets.has(node.id));
+  for (const node of roots.length > 0 ? roots : graph.nodes) {
+    visit(node.id, 0);
+  }
+  return layer;
+}
+
+function drawGraph(graph) {
+  const nodeWidth = 150;
+  const nodeHeight = 30;
+  const gapX = 80;
+  const gapY = 24;
+  const margin = 20;
+
+  const layer = layers(graph);
+  const columns = [];
+  for (const node of graph.nodes) {
+    const i = layer.get(node.id);
+    (columns[i] ||= []).push(node);
+  }
+  const position = new Map();
+  columns.forEach((nodes, i) => {
+    nodes.forEach((node, j) => {
+      position.set(node.id, { x: margin + i * (nodeWidth + gapX), y: margin + j * (nodeHeight + gapY) });
+    });
+  });
+  const width = margin * 2 + columns.length * (nodeWidth + gapX) - gapX;
+  const height = margin * 2 + Math.max(1, ...columns.map((c) => (c ? c.length : 0))) * (nodeHeight + gapY) - gapY;
+
+  const root = svg("svg", { width: Math.max(width, 200), height: Math.max(height, 80), role: "img", "aria-label": "Relationship graph" });
+  const defs = svg("defs");
+  for (const [id, colour] of [["arrow", "#9aa5b1"], ["arrow-highlighted", "#e8590c"]]) {
+    const marker = svg("marker", { id, viewBox: "0 0 10 10", refX: 10, refY: 5, markerWidth: 7, markerHeight: 7, orient: "auto-start-reverse" });
+    marker.append(svg("path", { d: "M 0 0 L 10 5 L 0 10 z", fill: colour }));
+    defs.append(marker);
+  }
+  root.append(defs);
+
+  for (const edge of graph.edges) {
+    const from = position.get(edge.from);
+    const to = position.get(edge.to);
+    if (!from || !to) {
+      continue;
+    }
+    const x1 = from.x + nodeWidth;
+    const y1 = from.y + nodeHeight / 2;
+    let x2 = to.x;
+    const y2 = to.y + nodeHeight / 2;
+    let path;
+    if (x2 > x1) {
+      const mid = (x1 + x2) / 2;
+      path = `M ${x1} ${y1} C ${mid} ${y1}, ${mid} ${y2}, ${x2} ${y2}`;
+    } else {
+      // A backward edge of a cycle: loop around above the nodes.
+      x2 = to.x + nodeWidth / 2;
+      const top = Math.min(from.y, to.y) - gapY;
+      path = `M ${from.x + nodeWidth / 2} ${from.y} C ${from.x + nodeWidth / 2} ${top}, ${x2} ${top}, ${x2} ${to.y}`;
+    }
+    const line = svg("path", {
+      d: path,
+      class: edge.highlighted ? "edge highlighted" : "edge",
+      "marker-end": edge.highlighted ? "url(#arrow-highlighted)" : "url(#arrow)",
+    });
+    const title = svg("title");
+    title.textContent = `${edge.from} → ${edge.to}`;
+    line.append(title);
+    root.append(line);
+  }
+
+  for (const node of graph.nodes) {
+    const { x, y } = position.get(node.id);
+    const group = svg("g", { class: node.highlighted ? "node highlighted" : "node" });
+    group.append(svg("rect", { x, y, width: nodeWidth, height: nodeHeight, rx: 5 }));
+    const label = svg("text", { x: x + nodeWidth / 2, y: y + nodeHeight / 2, "text-anchor": "middle", "dominant-baseline": "central" });
+    label.textContent = node.id.length > 20 ? node.id.slice(0, 19) + "…" : node.id;
+    const title = svg("title");
+    title.textContent = node.id;
+    group.append(label, title);
+    root.append(group);
+  }
+  return root;
+}
+
+// ---------------------------------------------------------------------------
+// Tabs
+
+function activeTab() {
+  return document.querySelector('.tabs [aria-selected="true"]').dataset.tab;
+}
+
+function initTabs() {
+  for (const button of document.querySelectorAll(".tabs button")) {
+    button.addEventListener("click", () => {
+      for (const other of document.querySelectorAll(".tabs button")) {
+        other.setAttribute("aria-selected", String(other === button));
+        $("tab-" + other.dataset.tab).hidden = other !== button;
+      }
+      if (button.dataset.tab === "graph") {
+        renderGraph();
+      }
+    });
+  }
+}
+
+// ---------------------------------------------------------------------------
+
+function init() {
+  const key = $("api-key");
+  // Keys are kept for the browser session only.
+  key.value = window.sessionStorage.getItem("mangle.apiKey") || "";
+  key.addEventListener("change", () => {
+    window.sessionStorage.setItem("mangle.apiKey", key.value.trim());
+    graphStale = true;
+    loadCatalog();
+  });
+  $("catalog-filter").addEventListener("input", renderCatalog);
+  $("run").addEventListener("click", runQuery);
+  initEditor();
+  initTimeRange();
+  initTabs();
+  loadCatalog();
+}
+
+init();
@@ -0,0 +1,69 @@
+package importer
+
+import (
+	"fmt"
+	"mangle-service/internal/core/domain"
+	"strings"
+)
+
+// BackstageLoader reads service relationships from Backstage catalog
+// descriptors (catalog-info.yaml). Every Component, Resource and API entity
+// becomes a service, and its spec.dependsOn entity references become
+// dependencies.
+type BackstageLoader struct{}
+
+// NewBackstageLoader creates a new BackstageLoader.
+func NewBackstageLoader() *BackstageLoader {
+	return &BackstageLoader{}
+}
+
+type backstageEntity struct {
+	Kind     string `yaml:"kind"`
+	Metadata struct {
+		Name string `yaml:"name"`
+	} `yaml:"metadata"`
+	Spec struct {
+		DependsOn []string `yaml:"dependsOn"`
+	} `yaml:"spec"`
+}
+
+// Load reads every entity at path, which may be a single catalog file or a
+// directory of them.
+func (l *BackstageLoader) Load(path string) (*domain.RelationshipConfig, error) {
+	docs, err := readYAMLDocuments(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var configs []*domain.RelationshipConfig
+	for _, doc := range docs {
+		var entity backstageEntity
+		if err := doc.node.Decode(&entity); err != nil {
+			return nil, fmt.Errorf("%s: %w", doc.path, err)
+		}
+		switch strings.ToLower(entity.Kind) {
+		case "component", "resource", "api":
+		default:
+			continue
+		}
+
+		rel := domain.ServiceRelationship{Service: entity.Metadata.Name}
+		for _, ref := range entity.Spec.DependsOn {
+			rel.DependsOn = append(rel.DependsOn, entityName(ref))
+		}
+		configs = append(configs, &domain.RelationshipConfig{Relationships: []domain.ServiceRelationship{rel}})
+	}
+	return domain.MergeRelationshipConfigs(configs...), nil
+}
+
+// entityName extracts the name from a Backstage entity reference of the form
+// [kind:][namespace/]name.
+func entityName(ref string) string {
+	if i := strings.Index(ref, ":"); i >= 0 {
+		ref = ref[i+1:]
+	}
+	if i := strings.LastIndex(ref, "/"); i >= 0 {
+		ref = ref[i+1:]
+	}
+	return ref
+}
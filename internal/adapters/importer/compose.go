@@ -0,0 +1,85 @@
+package importer
+
+import (
+	"fmt"
+	"mangle-service/internal/core/domain"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ComposeLoader reads service relationships from the depends_on entries of a
+// docker-compose file.
+type ComposeLoader struct{}
+
+// NewComposeLoader creates a new ComposeLoader.
+func NewComposeLoader() *ComposeLoader {
+	return &ComposeLoader{}
+}
+
+type composeFile struct {
+	Services map[string]struct {
+		// DependsOn is either a list of service names or, in the long
+		// syntax, a map from service name to its start condition.
+		DependsOn yaml.Node `yaml:"depends_on"`
+	} `yaml:"services"`
+}
+
+// Load reads the compose file at path. Every compose service becomes a
+// service, and every depends_on entry becomes a dependency.
+func (l *ComposeLoader) Load(path string) (*domain.RelationshipConfig, error) {
+	docs, err := readYAMLDocuments(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var configs []*domain.RelationshipConfig
+	for _, doc := range docs {
+		var file composeFile
+		if err := doc.node.Decode(&file); err != nil {
+			return nil, fmt.Errorf("%s: %w", doc.path, err)
+		}
+
+		names := make([]string, 0, len(file.Services))
+		for name := range file.Services {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		config := &domain.RelationshipConfig{}
+		for _, name := range names {
+			service := file.Services[name]
+			deps, err := composeDependsOn(&service.DependsOn)
+			if err != nil {
+				return nil, fmt.Errorf("%s: service %q: %w", doc.path, name, err)
+			}
+			config.Relationships = append(config.Relationships, domain.ServiceRelationship{
+				Service:   name,
+				DependsOn: deps,
+			})
+		}
+		configs = append(configs, config)
+	}
+	return domain.MergeRelationshipConfigs(configs...), nil
+}
+
+func composeDependsOn(node *yaml.Node) ([]string, error) {
+	switch node.Kind {
+	case 0:
+		return nil, nil
+	case yaml.SequenceNode:
+		var deps []string
+		if err := node.Decode(&deps); err != nil {
+			return nil, err
+		}
+		return deps, nil
+	case yaml.MappingNode:
+		var deps []string
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			deps = append(deps, node.Content[i].Value)
+		}
+		return deps, nil
+	default:
+		return nil, fmt.Errorf("line %d: depends_on must be a list or a map", node.Line)
+	}
+}
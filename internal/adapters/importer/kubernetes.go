@@ -0,0 +1,169 @@
+package importer
+
+import (
+	"fmt"
+	"mangle-service/internal/core/domain"
+	"strings"
+)
+
+// KubernetesLoader derives service relationships from Kubernetes manifests.
+// A workload depends on a Service if one of its container environment
+// variables refers to the Service's DNS name, for example
+// ORDERS_URL=http://order-service:8080 or order-service.shop.svc.cluster.local.
+type KubernetesLoader struct{}
+
+// NewKubernetesLoader creates a new KubernetesLoader.
+func NewKubernetesLoader() *KubernetesLoader {
+	return &KubernetesLoader{}
+}
+
+type k8sObject struct {
+	Kind     string `yaml:"kind"`
+	Metadata struct {
+		Name string `yaml:"name"`
+	} `yaml:"metadata"`
+	Spec struct {
+		// Selector is a label map for Services and a LabelSelector for workloads.
+		Selector map[string]interface{} `yaml:"selector"`
+		Template k8sPodTemplate         `yaml:"template"`
+	} `yaml:"spec"`
+}
+
+type k8sPodTemplate struct {
+	Metadata struct {
+		Labels map[string]string `yaml:"labels"`
+	} `yaml:"metadata"`
+	Spec struct {
+		Containers     []k8sContainer `yaml:"containers"`
+		InitContainers []k8sContainer `yaml:"initContainers"`
+	} `yaml:"spec"`
+}
+
+type k8sContainer struct {
+	Env []struct {
+		Name  string `yaml:"name"`
+		Value string `yaml:"value"`
+	} `yaml:"env"`
+}
+
+type k8sWorkload struct {
+	name     string
+	template k8sPodTemplate
+}
+
+// Load reads every manifest at path, which may be a file or a directory.
+// Namespaces are not taken into account; Service names are assumed to be unique.
+func (l *KubernetesLoader) Load(path string) (*domain.RelationshipConfig, error) {
+	docs, err := readYAMLDocuments(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var serviceNames []string
+	selectors := make(map[string]map[string]string)
+	var workloads []k8sWorkload
+	for _, doc := range docs {
+		var obj k8sObject
+		if err := doc.node.Decode(&obj); err != nil {
+			return nil, fmt.Errorf("%s: %w", doc.path, err)
+		}
+		switch obj.Kind {
+		case "Service":
+			serviceNames = append(serviceNames, obj.Metadata.Name)
+			selectors[obj.Metadata.Name] = stringMap(obj.Spec.Selector)
+		case "Deployment", "StatefulSet", "DaemonSet":
+			workloads = append(workloads, k8sWorkload{name: obj.Metadata.Name, template: obj.Spec.Template})
+		}
+	}
+
+	config := &domain.RelationshipConfig{}
+	for _, name := range serviceNames {
+		config.Relationships = append(config.Relationships, domain.ServiceRelationship{Service: name})
+	}
+	known := make(map[string]bool)
+	for _, name := range serviceNames {
+		known[name] = true
+	}
+
+	var configs []*domain.RelationshipConfig
+	configs = append(configs, config)
+	for _, w := range workloads {
+		var deps []string
+		for _, c := range append(w.template.Spec.Containers, w.template.Spec.InitContainers...) {
+			for _, env := range c.Env {
+				deps = append(deps, referencedServices(env.Value, known)...)
+			}
+		}
+		for _, owner := range workloadServices(w, serviceNames, selectors) {
+			var ownDeps []string
+			for _, dep := range deps {
+				if dep != owner {
+					ownDeps = append(ownDeps, dep)
+				}
+			}
+			configs = append(configs, &domain.RelationshipConfig{
+				Relationships: []domain.ServiceRelationship{{Service: owner, DependsOn: ownDeps}},
+			})
+		}
+	}
+	return domain.MergeRelationshipConfigs(configs...), nil
+}
+
+// workloadServices returns the Services whose selector matches the workload's
+// pod labels, or the workload name if no Service selects it.
+func workloadServices(w k8sWorkload, serviceNames []string, selectors map[string]map[string]string) []string {
+	var owners []string
+	for _, name := range serviceNames {
+		selector := selectors[name]
+		if len(selector) > 0 && labelsMatch(selector, w.template.Metadata.Labels) {
+			owners = append(owners, name)
+		}
+	}
+	if len(owners) == 0 {
+		owners = []string{w.name}
+	}
+	return owners
+}
+
+// referencedServices returns the known Service names that appear as host
+// names in value.
+func referencedServices(value string, known map[string]bool) []string {
+	var refs []string
+	fields := strings.FieldsFunc(value, func(r rune) bool {
+		return !(r == '-' || r == '.' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	})
+	for _, host := range fields {
+		labels := strings.Split(host, ".")
+		if !known[labels[0]] {
+			continue
+		}
+		// Accept "svc", "svc.namespace", "svc.namespace.svc" and
+		// "svc.namespace.svc.cluster.local"; anything else is most likely an
+		// unrelated host that happens to share the first label.
+		if len(labels) <= 2 || labels[2] == "svc" {
+			refs = append(refs, labels[0])
+		}
+	}
+	return refs
+}
+
+func labelsMatch(selector, labels map[string]string) bool {
+	for k, v := range selector {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// stringMap converts a Service selector into a label map. Selectors of other
+// shapes, such as LabelSelectors with matchLabels, are ignored.
+func stringMap(m map[string]interface{}) map[string]string {
+	out := make(map[string]string)
+	for k, v := range m {
+		if s, ok := v.(string); ok {
+			out[k] = s
+		}
+	}
+	return out
+}
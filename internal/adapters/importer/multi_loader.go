@@ -0,0 +1,76 @@
+package importer
+
+import (
+	"fmt"
+	"mangle-service/internal/core/domain"
+	"mangle-service/internal/core/ports"
+	"strings"
+)
+
+var _ ports.ConfigLoaderPort = (*MultiLoader)(nil)
+
+// MultiLoader loads relationships from several sources and merges them.
+// The path passed to Load is a comma-separated list of sources, each
+// optionally prefixed with the kind of loader to use:
+//
+//	relationships.yml,compose:docker-compose.yml,kubernetes:deploy/,backstage:catalog-info.yaml
+//
+// Sources without the prefix of a known kind are read by the default loader.
+type MultiLoader struct {
+	defaultLoader ports.ConfigLoaderPort
+	loaders       map[string]ports.ConfigLoaderPort
+}
+
+// NewMultiLoader creates a MultiLoader. The default loader reads sources
+// without a kind prefix; loaders maps kind prefixes to their loaders.
+func NewMultiLoader(defaultLoader ports.ConfigLoaderPort, loaders map[string]ports.ConfigLoaderPort) *MultiLoader {
+	return &MultiLoader{defaultLoader: defaultLoader, loaders: loaders}
+}
+
+// NewDefaultMultiLoader creates a MultiLoader that reads relationship files by
+// default and knows the compose, kubernetes (or k8s) and backstage kinds.
+func NewDefaultMultiLoader(defaultLoader ports.ConfigLoaderPort) *MultiLoader {
+	k8s := NewKubernetesLoader()
+	return NewMultiLoader(defaultLoader, map[string]ports.ConfigLoaderPort{
+		"compose":    NewComposeLoader(),
+		"kubernetes": k8s,
+		"k8s":        k8s,
+		"backstage":  NewBackstageLoader(),
+	})
+}
+
+// Load reads every source listed in spec and merges the results. A single
+// source is returned unchanged, keeping its source line information.
+func (l *MultiLoader) Load(spec string) (*domain.RelationshipConfig, error) {
+	var configs []*domain.RelationshipConfig
+	for _, source := range strings.Split(spec, ",") {
+		source = strings.TrimSpace(source)
+		if source == "" {
+			continue
+		}
+		loader, path := l.loaderFor(source)
+		config, err := loader.Load(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to import %s: %w", source, err)
+		}
+		configs = append(configs, config)
+	}
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("no relationship sources configured")
+	}
+	if len(configs) == 1 {
+		return configs[0], nil
+	}
+	return domain.MergeRelationshipConfigs(configs...), nil
+}
+
+// loaderFor picks the loader of source. Only the known kinds are taken as a
+// prefix, so that paths containing a colon are read by the default loader.
+func (l *MultiLoader) loaderFor(source string) (ports.ConfigLoaderPort, string) {
+	if kind, path, ok := strings.Cut(source, ":"); ok {
+		if loader, found := l.loaders[kind]; found {
+			return loader, path
+		}
+	}
+	return l.defaultLoader, source
+}
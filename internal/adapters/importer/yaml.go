@@ -0,0 +1,83 @@
+// Package importer contains ConfigLoaderPort implementations that derive
+// service relationships from descriptors that teams already maintain, such as
+// docker-compose files, Kubernetes manifests and Backstage catalogs.
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlDocument is a single YAML document together with the file it came from.
+type yamlDocument struct {
+	path string
+	node *yaml.Node
+}
+
+// readYAMLDocuments reads every YAML document from path. If path is a
+// directory, all .yaml and .yml files below it are read in lexical order.
+func readYAMLDocuments(path string) ([]yamlDocument, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return readYAMLFile(path)
+	}
+
+	var files []string
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		ext := strings.ToLower(filepath.Ext(p))
+		if !d.IsDir() && (ext == ".yaml" || ext == ".yml") {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	var docs []yamlDocument
+	for _, f := range files {
+		fileDocs, err := readYAMLFile(f)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, fileDocs...)
+	}
+	return docs, nil
+}
+
+func readYAMLFile(path string) ([]yamlDocument, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var docs []yamlDocument
+	dec := yaml.NewDecoder(f)
+	for {
+		var node yaml.Node
+		err := dec.Decode(&node)
+		if errors.Is(err, io.EOF) {
+			return docs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		docs = append(docs, yamlDocument{path: path, node: &node})
+	}
+}
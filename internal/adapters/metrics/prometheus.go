@@ -0,0 +1,150 @@
+// Package metrics exposes measurements of the query pipeline to Prometheus.
+package metrics
+
+import (
+	"mangle-service/internal/core/domain"
+	"mangle-service/internal/core/ports"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	_ ports.QueryMetrics        = (*PrometheusMetrics)(nil)
+	_ ports.RelationshipMetrics = (*PrometheusMetrics)(nil)
+)
+
+// Label values for queries without a name and for names beyond maxQueryNames.
+const (
+	adhocQuery = "adhoc"
+	otherQuery = "other"
+)
+
+// maxQueryNames bounds the number of distinct query names used as label
+// values. Names are chosen by clients, and every name adds a time series to
+// each query metric.
+const maxQueryNames = 100
+
+// durationBuckets range from a millisecond to about four minutes.
+var durationBuckets = prometheus.ExponentialBuckets(0.001, 4, 10)
+
+// PrometheusMetrics records query and relationship metrics in its own
+// registry, together with the Go runtime and process metrics.
+type PrometheusMetrics struct {
+	registry      *prometheus.Registry
+	phaseDuration *prometheus.HistogramVec
+	queryDuration *prometheus.HistogramVec
+	inFlight      *prometheus.GaugeVec
+	errors        *prometheus.CounterVec
+	factsFetched  *prometheus.CounterVec
+	factsDerived  *prometheus.CounterVec
+	reloads       *prometheus.CounterVec
+
+	mu    sync.Mutex
+	names map[string]bool
+}
+
+// NewPrometheusMetrics creates and registers the metrics.
+func NewPrometheusMetrics() *PrometheusMetrics {
+	m := &PrometheusMetrics{
+		registry: prometheus.NewRegistry(),
+		phaseDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "mangle_query_phase_duration_seconds",
+			Help:    "Time spent in each phase of a query: parse, fetch, analysis, evaluation and extraction.",
+			Buckets: durationBuckets,
+		}, []string{"query", "phase"}),
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "mangle_query_duration_seconds",
+			Help:    "Time from admission to the end of a query, whether it succeeded or not.",
+			Buckets: durationBuckets,
+		}, []string{"query"}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "mangle_queries_in_flight",
+			Help: "Queries being evaluated.",
+		}, []string{"query"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "mangle_query_errors_total",
+			Help: "Failed queries by error code, including those turned away by admission control.",
+		}, []string{"query", "code"}),
+		factsFetched: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "mangle_log_facts_fetched_total",
+			Help: "Log facts read from each source.",
+		}, []string{"source"}),
+		factsDerived: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "mangle_facts_derived_total",
+			Help: "Facts derived by query evaluation.",
+		}, []string{"query"}),
+		reloads: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "mangle_relationship_reloads_total",
+			Help: "Reloads of the relationship configuration by result, success or failure.",
+		}, []string{"result"}),
+		names: make(map[string]bool),
+	}
+	m.registry.MustRegister(
+		m.phaseDuration, m.queryDuration, m.inFlight, m.errors, m.factsFetched, m.factsDerived, m.reloads,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (m *PrometheusMetrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+func (m *PrometheusMetrics) QueryStarted(name string) {
+	m.inFlight.WithLabelValues(m.queryLabel(name)).Inc()
+}
+
+func (m *PrometheusMetrics) QueryDone(name string, duration time.Duration) {
+	label := m.queryLabel(name)
+	m.inFlight.WithLabelValues(label).Dec()
+	m.queryDuration.WithLabelValues(label).Observe(duration.Seconds())
+}
+
+func (m *PrometheusMetrics) QueryFailed(name string, code domain.ErrorCode) {
+	m.errors.WithLabelValues(m.queryLabel(name), string(code)).Inc()
+}
+
+func (m *PrometheusMetrics) ObservePhase(name string, phase domain.QueryPhase, duration time.Duration) {
+	m.phaseDuration.WithLabelValues(m.queryLabel(name), string(phase)).Observe(duration.Seconds())
+}
+
+func (m *PrometheusMetrics) FactsFetched(source string, count int) {
+	m.factsFetched.WithLabelValues(source).Add(float64(count))
+}
+
+func (m *PrometheusMetrics) FactsDerived(name string, count int) {
+	m.factsDerived.WithLabelValues(m.queryLabel(name)).Add(float64(count))
+}
+
+func (m *PrometheusMetrics) RelationshipsReloaded(err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	m.reloads.WithLabelValues(result).Inc()
+}
+
+// queryLabel returns the label value for a query name. The first
+// maxQueryNames names are kept; later ones are recorded as otherQuery.
+func (m *PrometheusMetrics) queryLabel(name string) string {
+	if name == "" {
+		return adhocQuery
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.names[name] {
+		return name
+	}
+	if len(m.names) >= maxQueryNames {
+		return otherQuery
+	}
+	m.names[name] = true
+	return name
+}
@@ -1,6 +1,7 @@
 package mock
 
 import (
+	"context"
 	"mangle-service/internal/core/domain"
 
 	"github.com/google/mangle/ast"
@@ -15,7 +16,7 @@ func NewMockLogAdapter() *MockLogAdapter {
 }
 
 // FetchLogs returns a hardcoded list of log facts for testing purposes.
-func (a *MockLogAdapter) FetchLogs(queryCriteria map[string]string) ([]domain.Fact, error) {
+func (a *MockLogAdapter) FetchLogs(ctx context.Context, queryCriteria map[string]string) ([]domain.Fact, error) {
 	facts := []domain.Fact{
 		// logs('A', 200, 'call to B')
 		ast.NewAtom(
@@ -34,3 +35,18 @@ func (a *MockLogAdapter) FetchLogs(queryCriteria map[string]string) ([]domain.Fa
 	}
 	return facts, nil
 }
+
+// Predicates describes the facts returned by FetchLogs.
+func (a *MockLogAdapter) Predicates() []domain.PredicateInfo {
+	return []domain.PredicateInfo{{
+		Name:        "logs",
+		Arity:       3,
+		Args:        []string{"Service", "Status", "Message"},
+		Description: "A log line of Service with its HTTP status and message.",
+	}}
+}
+
+// CheckHealth always succeeds, as the logs are built in.
+func (a *MockLogAdapter) CheckHealth(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,135 @@
+// Package resultformat renders query results in formats other than JSON:
+// CSV and TSV for spreadsheets, an aligned text table for terminals and
+// Mangle fact syntax for feeding results back into Mangle.
+package resultformat
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"mangle-service/internal/core/domain"
+	"strings"
+	"text/tabwriter"
+)
+
+// Supported formats.
+const (
+	FormatCSV    = "csv"
+	FormatTSV    = "tsv"
+	FormatTable  = "table"
+	FormatMangle = "mangle"
+)
+
+// Formats lists the supported formats.
+var Formats = []string{FormatCSV, FormatTSV, FormatTable, FormatMangle}
+
+// mediaTypes maps formats to the media types they are served as.
+var mediaTypes = map[string]string{
+	FormatCSV:    "text/csv",
+	FormatTSV:    "text/tab-separated-values",
+	FormatTable:  "text/plain",
+	FormatMangle: "text/x-mangle",
+}
+
+// MediaType returns the media type of the given format, without parameters.
+func MediaType(format string) string {
+	return mediaTypes[format]
+}
+
+// ContentType returns the Content-Type header value for the given format.
+func ContentType(format string) string {
+	if mediaType, ok := mediaTypes[format]; ok {
+		return mediaType + "; charset=utf-8"
+	}
+	return "text/plain; charset=utf-8"
+}
+
+// FormatForMediaType returns the format served as mediaType, if any.
+func FormatForMediaType(mediaType string) (string, bool) {
+	for format, mt := range mediaTypes {
+		if mt == mediaType {
+			return format, true
+		}
+	}
+	return "", false
+}
+
+// Render writes result to w in the given format. Tabular formats have one
+// column per query variable, in query order.
+func Render(w io.Writer, result *domain.QueryResult, format string) error {
+	switch format {
+	case FormatCSV:
+		return renderDelimited(w, result, ',')
+	case FormatTSV:
+		return renderDelimited(w, result, '\t')
+	case FormatTable:
+		return renderTable(w, result)
+	case FormatMangle:
+		return renderMangle(w, result)
+	default:
+		return fmt.Errorf("unsupported result format %q (supported: %s)", format, strings.Join(Formats, ", "))
+	}
+}
+
+func renderDelimited(w io.Writer, result *domain.QueryResult, comma rune) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+	if err := cw.Write(result.Columns); err != nil {
+		return err
+	}
+	for _, entry := range result.Results {
+		if err := cw.Write(row(result.Columns, entry)); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// tableCellReplacer removes characters that would break the table alignment.
+var tableCellReplacer = strings.NewReplacer("\t", " ", "\n", " ", "\r", " ")
+
+func renderTable(w io.Writer, result *domain.QueryResult) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	rules := make([]string, len(result.Columns))
+	for i, column := range result.Columns {
+		rules[i] = strings.Repeat("-", len(column))
+	}
+	lines := [][]string{append([]string(nil), result.Columns...), rules}
+	for _, entry := range result.Results {
+		lines = append(lines, row(result.Columns, entry))
+	}
+	for _, line := range lines {
+		for i, cell := range line {
+			line[i] = tableCellReplacer.Replace(cell)
+		}
+		if _, err := fmt.Fprintln(tw, strings.Join(line, "\t")); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(tw, "(%d rows)\n", result.Count); err != nil {
+		return err
+	}
+	return tw.Flush()
+}
+
+// renderMangle writes every fact that matched the query, so that results can
+// be loaded into another Mangle program as they are.
+func renderMangle(w io.Writer, result *domain.QueryResult) error {
+	for _, fact := range result.Facts {
+		if _, err := fmt.Fprintf(w, "%s.\n", fact.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func row(columns []string, entry domain.LogEntry) []string {
+	cells := make([]string, len(columns))
+	for i, column := range columns {
+		if value, ok := entry[column]; ok {
+			cells[i] = fmt.Sprint(value)
+		}
+	}
+	return cells
+}
@@ -0,0 +1,187 @@
+// Package sqlite stores the audit log in a SQLite database.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"mangle-service/internal/core/domain"
+	"mangle-service/internal/core/ports"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+var _ ports.AuditSink = (*AuditStore)(nil)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS audit_records (
+	id             TEXT PRIMARY KEY,
+	time           INTEGER NOT NULL,
+	duration_ms    INTEGER NOT NULL,
+	request_id     TEXT NOT NULL,
+	channel        TEXT NOT NULL,
+	subject        TEXT NOT NULL,
+	auth_method    TEXT NOT NULL,
+	client_address TEXT NOT NULL,
+	name           TEXT NOT NULL,
+	query          TEXT NOT NULL,
+	query_hash     TEXT NOT NULL,
+	time_from      INTEGER,
+	time_to        INTEGER,
+	sources        TEXT NOT NULL,
+	facts_read     INTEGER NOT NULL,
+	results        INTEGER NOT NULL,
+	outcome        TEXT NOT NULL,
+	error_code     TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS audit_records_time ON audit_records (time);
+CREATE INDEX IF NOT EXISTS audit_records_subject ON audit_records (subject, time);
+CREATE INDEX IF NOT EXISTS audit_records_query_hash ON audit_records (query_hash, time);
+`
+
+// AuditStore is an audit sink backed by a SQLite database, which keeps
+// searches fast as the log grows. Times are stored as Unix nanoseconds.
+type AuditStore struct {
+	db *sql.DB
+}
+
+// NewAuditStore opens the database at path, creating it and its table if needed.
+func NewAuditStore(path string) (*AuditStore, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit database: %w", err)
+	}
+	// SQLite allows a single writer; serializing connections avoids
+	// "database is locked" errors under concurrent queries.
+	db.SetMaxOpenConns(1)
+	for _, pragma := range []string{"PRAGMA journal_mode = WAL", "PRAGMA busy_timeout = 5000"} {
+		if _, err := db.Exec(pragma); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to configure audit database: %w", err)
+		}
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create audit table: %w", err)
+	}
+	return &AuditStore{db: db}, nil
+}
+
+// Write inserts the record.
+func (s *AuditStore) Write(ctx context.Context, r domain.AuditRecord) error {
+	sources, err := json.Marshal(r.Sources)
+	if err != nil {
+		return fmt.Errorf("error encoding audit sources: %w", err)
+	}
+	var from, to sql.NullInt64
+	if r.TimeRange != nil {
+		from = nullTime(r.TimeRange.From)
+		to = nullTime(r.TimeRange.To)
+	}
+	_, err = s.db.ExecContext(ctx, `INSERT INTO audit_records (
+		id, time, duration_ms, request_id, channel, subject, auth_method, client_address,
+		name, query, query_hash, time_from, time_to, sources, facts_read, results, outcome, error_code
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		r.ID, r.Time.UnixNano(), r.DurationMS, r.RequestID, r.Channel, r.Subject, r.AuthMethod, r.ClientAddress,
+		r.Name, r.Query, r.QueryHash, from, to, string(sources), r.FactsRead, r.Results, string(r.Outcome), string(r.ErrorCode))
+	if err != nil {
+		return fmt.Errorf("failed to insert audit record: %w", err)
+	}
+	return nil
+}
+
+// Search selects the records matching the filter, newest first.
+func (s *AuditStore) Search(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditRecord, error) {
+	var where []string
+	var args []any
+	add := func(cond string, arg any) {
+		where = append(where, cond)
+		args = append(args, arg)
+	}
+	if filter.Subject != "" {
+		add("subject = ?", filter.Subject)
+	}
+	if filter.Name != "" {
+		add("name = ?", filter.Name)
+	}
+	if filter.QueryHash != "" {
+		add("query_hash = ?", filter.QueryHash)
+	}
+	if filter.Source != "" {
+		add("EXISTS (SELECT 1 FROM json_each(audit_records.sources) WHERE value = ?)", filter.Source)
+	}
+	if filter.Outcome != "" {
+		add("outcome = ?", string(filter.Outcome))
+	}
+	if !filter.Since.IsZero() {
+		add("time >= ?", filter.Since.UnixNano())
+	}
+	if !filter.Until.IsZero() {
+		add("time < ?", filter.Until.UnixNano())
+	}
+
+	query := `SELECT id, time, duration_ms, request_id, channel, subject, auth_method, client_address,
+		name, query, query_hash, time_from, time_to, sources, facts_read, results, outcome, error_code
+		FROM audit_records`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY time DESC, rowid DESC"
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit records: %w", err)
+	}
+	defer rows.Close()
+	var records []domain.AuditRecord
+	for rows.Next() {
+		var r domain.AuditRecord
+		var t int64
+		var from, to sql.NullInt64
+		var sources, outcome, code string
+		if err := rows.Scan(&r.ID, &t, &r.DurationMS, &r.RequestID, &r.Channel, &r.Subject, &r.AuthMethod, &r.ClientAddress,
+			&r.Name, &r.Query, &r.QueryHash, &from, &to, &sources, &r.FactsRead, &r.Results, &outcome, &code); err != nil {
+			return nil, fmt.Errorf("failed to read audit record: %w", err)
+		}
+		r.Time = time.Unix(0, t).UTC()
+		if from.Valid || to.Valid {
+			r.TimeRange = &domain.TimeRange{From: fromNullTime(from), To: fromNullTime(to)}
+		}
+		if err := json.Unmarshal([]byte(sources), &r.Sources); err != nil {
+			return nil, fmt.Errorf("error decoding sources of audit record %s: %w", r.ID, err)
+		}
+		r.Outcome = domain.AuditOutcome(outcome)
+		r.ErrorCode = domain.ErrorCode(code)
+		records = append(records, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit records: %w", err)
+	}
+	return records, nil
+}
+
+// Close closes the database.
+func (s *AuditStore) Close() error {
+	return s.db.Close()
+}
+
+func nullTime(t time.Time) sql.NullInt64 {
+	if t.IsZero() {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: t.UnixNano(), Valid: true}
+}
+
+func fromNullTime(n sql.NullInt64) time.Time {
+	if !n.Valid {
+		return time.Time{}
+	}
+	return time.Unix(0, n.Int64).UTC()
+}
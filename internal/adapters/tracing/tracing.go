@@ -0,0 +1,80 @@
+// Package tracing sets up the export of OpenTelemetry spans.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Exporters that Config.Exporter accepts.
+const (
+	ExporterNone = "none"
+	ExporterOTLP = "otlp"
+)
+
+// OTLP protocols that Config.Protocol accepts.
+const (
+	ProtocolGRPC = "grpc"
+	ProtocolHTTP = "http/protobuf"
+)
+
+// defaultServiceName is reported unless OTEL_SERVICE_NAME says otherwise.
+const defaultServiceName = "mangle-service"
+
+// Config selects where spans are exported. The names follow the
+// OpenTelemetry environment variables OTEL_TRACES_EXPORTER and
+// OTEL_EXPORTER_OTLP_PROTOCOL. The exporter reads its endpoint, headers and
+// TLS settings from the other OTEL_EXPORTER_OTLP_* variables, and the
+// provider its sampler from OTEL_TRACES_SAMPLER.
+type Config struct {
+	// Exporter is ExporterOTLP or ExporterNone. Empty means ExporterNone.
+	Exporter string
+	// Protocol is ProtocolGRPC or ProtocolHTTP. Empty means ProtocolHTTP.
+	Protocol string
+}
+
+// Enabled reports whether spans are exported.
+func (c Config) Enabled() bool {
+	return c.Exporter != "" && c.Exporter != ExporterNone
+}
+
+// NewTracerProvider creates a provider that exports spans in batches as
+// configured. The caller must shut it down to flush the last spans.
+func NewTracerProvider(ctx context.Context, config Config) (*sdktrace.TracerProvider, error) {
+	if config.Exporter != ExporterOTLP {
+		return nil, fmt.Errorf("unsupported trace exporter %q", config.Exporter)
+	}
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch config.Protocol {
+	case "", ProtocolHTTP:
+		exporter, err = otlptracehttp.New(ctx)
+	case ProtocolGRPC:
+		exporter, err = otlptracegrpc.New(ctx)
+	default:
+		return nil, fmt.Errorf("unsupported OTLP protocol %q", config.Protocol)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", defaultServiceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe the service for tracing: %w", err)
+	}
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	), nil
+}
@@ -0,0 +1,334 @@
+// Package config defines the service configuration and loads it from a YAML
+// file, environment variables and command-line flags.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"mangle-service/internal/adapters/certs"
+	"mangle-service/internal/adapters/tracing"
+	"mangle-service/pkg/logger"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Environments that Config.Env accepts.
+const (
+	EnvDev  = "dev"
+	EnvProd = "prod"
+	EnvTest = "test"
+)
+
+// Log source types that SourcesConfig.Type accepts.
+const (
+	SourceElasticsearch = "elasticsearch"
+	SourceMock          = "mock"
+)
+
+// Audit log formats that AuditConfig.Format accepts.
+const (
+	AuditFormatJSONL  = "jsonl"
+	AuditFormatSQLite = "sqlite"
+)
+
+// Log outputs that LoggingConfig.Output accepts besides file paths.
+const (
+	LogOutputStdout = "stdout"
+	LogOutputStderr = "stderr"
+)
+
+// redacted replaces secrets in Config.Redacted.
+const redacted = "REDACTED"
+
+// Config is the configuration of the service. Every setting can be given in
+// the YAML file, in the environment variables listed in its env tag (the
+// first is canonical; the others are accepted for compatibility) and as a
+// flag named after its YAML path, such as -server.port.
+type Config struct {
+	// Env selects defaults for an environment. In the test environment the
+	// log source is the built-in mock unless sources.type says otherwise.
+	Env           string              `yaml:"env" env:"MANGLE_ENV" usage:"environment (dev, prod, test)"`
+	Server        ServerConfig        `yaml:"server"`
+	Logging       LoggingConfig       `yaml:"logging"`
+	Sources       SourcesConfig       `yaml:"sources"`
+	Relationships RelationshipsConfig `yaml:"relationships"`
+	Rules         RulesConfig         `yaml:"rules"`
+	Limits        LimitsConfig        `yaml:"limits"`
+	Jobs          JobsConfig          `yaml:"jobs"`
+	Health        HealthConfig        `yaml:"health"`
+	Auth          AuthConfig          `yaml:"auth"`
+	Audit         AuditConfig         `yaml:"audit"`
+	Tracing       TracingConfig       `yaml:"tracing"`
+}
+
+type ServerConfig struct {
+	Port string `yaml:"port" env:"PORT,MANGLE_SERVICE_PORT" usage:"HTTP port"`
+	// GRPCPort enables the gRPC API if set.
+	GRPCPort string `yaml:"grpc_port" env:"GRPC_PORT" usage:"gRPC port; the gRPC API is off if empty"`
+	// ShutdownDelay gives load balancers time to notice that the service is
+	// unready before it refuses queries.
+	ShutdownDelay time.Duration `yaml:"shutdown_delay" env:"SHUTDOWN_DELAY" usage:"how long the service reports itself unready before refusing queries on shutdown"`
+	// DrainTimeout bounds the wait for running queries on shutdown; those
+	// still running are then cancelled.
+	DrainTimeout time.Duration `yaml:"drain_timeout" env:"SHUTDOWN_DRAIN_TIMEOUT" usage:"how long running queries may finish on shutdown before they are cancelled"`
+	// TLSCertFile and TLSKeyFile make the HTTP server serve HTTPS. Both are
+	// reloaded when they change.
+	TLSCertFile string `yaml:"tls_cert_file" env:"TLS_CERT_FILE" usage:"PEM certificate chain of the HTTPS server"`
+	TLSKeyFile  string `yaml:"tls_key_file" env:"TLS_KEY_FILE" usage:"PEM private key of the HTTPS server"`
+	// TLSClientCAFile enables client certificate verification.
+	TLSClientCAFile string `yaml:"tls_client_ca_file" env:"TLS_CLIENT_CA_FILE" usage:"PEM bundle of the CAs whose client certificates are accepted"`
+	// TLSClientAuth is certs.ClientAuthRequire or certs.ClientAuthOptional.
+	TLSClientAuth string `yaml:"tls_client_auth" env:"TLS_CLIENT_AUTH" usage:"whether client certificates are required or optional (require, optional)"`
+}
+
+// TLSEnabled reports whether the HTTP server serves HTTPS.
+func (c ServerConfig) TLSEnabled() bool {
+	return c.TLSCertFile != ""
+}
+
+type LoggingConfig struct {
+	// Level is debug, info, warn or error. It defaults to info in the prod
+	// environment and to debug otherwise.
+	Level  string `yaml:"level" env:"LOG_LEVEL" usage:"minimum log level (debug, info, warn, error)"`
+	Format string `yaml:"format" env:"LOG_FORMAT" usage:"log line format (json, text)"`
+	// Output is stdout, stderr or the path of a file to append to.
+	Output string `yaml:"output" env:"LOG_OUTPUT" usage:"log destination (stdout, stderr or a file path)"`
+	// SlowQuery is how long a query may run before its profile is logged.
+	SlowQuery time.Duration `yaml:"slow_query" env:"SLOW_QUERY_THRESHOLD" usage:"log the profile of queries running this long; 0 disables the log"`
+}
+
+// SourcesConfig selects the default log source.
+type SourcesConfig struct {
+	// Type is SourceElasticsearch or SourceMock.
+	Type          string              `yaml:"type" env:"LOG_SOURCE" usage:"log source (elasticsearch, mock)"`
+	Elasticsearch ElasticsearchConfig `yaml:"elasticsearch"`
+}
+
+type ElasticsearchConfig struct {
+	Addresses []string `yaml:"addresses" env:"ELASTICSEARCH_ADDRESS,ELASTICSEARCH_URL" usage:"comma-separated Elasticsearch URLs"`
+	Index     string   `yaml:"index" env:"ELASTICSEARCH_INDEX" usage:"index or index pattern holding the logs"`
+	Username  string   `yaml:"username" env:"ELASTICSEARCH_USERNAME" usage:"user for basic authentication"`
+	Password  string   `yaml:"password" env:"ELASTICSEARCH_PASSWORD" secret:"true" usage:"password for basic authentication"`
+	APIKey    string   `yaml:"api_key" env:"ELASTICSEARCH_API_KEY" secret:"true" usage:"base64-encoded API key"`
+}
+
+type RelationshipsConfig struct {
+	// ConfigPath names the relationship sources, such as a YAML file or
+	// "compose:docker-compose.yml".
+	ConfigPath string `yaml:"config_path" env:"RELATIONSHIP_CONFIG_PATH,RELATIONSHIPS_CONFIG_PATH" usage:"relationship sources"`
+	// StorePath persists changes made over the API; they are kept in memory if empty.
+	StorePath string `yaml:"store_path" env:"RELATIONSHIP_STORE_PATH" usage:"file persisting relationship changes made over the API"`
+}
+
+type RulesConfig struct {
+	ModulesPath string `yaml:"modules_path" env:"RULE_MODULES_PATH" usage:"directory or file of shared Mangle rule modules"`
+}
+
+// LimitsConfig bounds single queries and the query load. Zero disables a limit.
+type LimitsConfig struct {
+	QueryTimeout  time.Duration `yaml:"query_timeout" env:"QUERY_TIMEOUT" usage:"how long a query may run"`
+	FactLimit     int           `yaml:"fact_limit" env:"QUERY_FACT_LIMIT" usage:"how many facts a query may derive"`
+	MaxConcurrent int           `yaml:"max_concurrent" env:"QUERY_MAX_CONCURRENT" usage:"how many queries are evaluated at once"`
+	MaxQueue      int           `yaml:"max_queue" env:"QUERY_MAX_QUEUE" usage:"how many queries may wait for a free slot"`
+	QueueTimeout  time.Duration `yaml:"queue_timeout" env:"QUERY_QUEUE_TIMEOUT" usage:"how long a query waits for a free slot"`
+	RateLimit     float64       `yaml:"rate_limit" env:"QUERY_RATE_LIMIT" usage:"queries per second per client"`
+	RateBurst     int           `yaml:"rate_burst" env:"QUERY_RATE_BURST" usage:"queries a client may send at once"`
+}
+
+type JobsConfig struct {
+	Workers   int           `yaml:"workers" env:"JOB_WORKERS" usage:"how many jobs run at once"`
+	MaxQueued int           `yaml:"max_queued" env:"JOB_MAX_QUEUED" usage:"how many jobs may wait for a worker"`
+	Timeout   time.Duration `yaml:"timeout" env:"JOB_TIMEOUT" usage:"how long the query of a job may run; 0 disables the limit"`
+	ResultTTL time.Duration `yaml:"result_ttl" env:"JOB_RESULT_TTL" usage:"how long a finished job is kept"`
+}
+
+type HealthConfig struct {
+	CheckTimeout time.Duration `yaml:"check_timeout" env:"HEALTH_CHECK_TIMEOUT" usage:"how long each readiness check may take"`
+}
+
+// AuthConfig enables authentication if any of APIKeysPath, JWTSecret and
+// JWKSPath is set.
+type AuthConfig struct {
+	APIKeysPath      string `yaml:"api_keys_path" env:"API_KEYS_PATH" usage:"YAML file of accepted API keys"`
+	JWTSecret        string `yaml:"jwt_hs256_secret" env:"JWT_HS256_SECRET" secret:"true" usage:"secret of HS256 bearer tokens"`
+	JWKSPath         string `yaml:"jwt_jwks_path" env:"JWT_JWKS_PATH" usage:"JWKS file of RS256 bearer token keys"`
+	Issuer           string `yaml:"jwt_issuer" env:"JWT_ISSUER" usage:"required iss claim of bearer tokens"`
+	Audience         string `yaml:"jwt_audience" env:"JWT_AUDIENCE" usage:"required aud claim of bearer tokens"`
+	RolesClaim       string `yaml:"jwt_roles_claim" env:"JWT_ROLES_CLAIM" usage:"bearer token claim holding the roles"`
+	AccessPolicyPath string `yaml:"access_policy_path" env:"ACCESS_POLICY_PATH" usage:"YAML file restricting predicates and sources to roles"`
+	// ClientCertsPath maps client certificate identities to subjects and
+	// roles; it requires server.tls_client_ca_file.
+	ClientCertsPath string `yaml:"client_certs_path" env:"CLIENT_CERTS_PATH" usage:"YAML file mapping client certificate identities to roles"`
+	// AdminRoles may use the admin endpoints, such as changing the log level.
+	AdminRoles []string `yaml:"admin_roles" env:"ADMIN_ROLES" usage:"comma-separated roles that may use the admin endpoints"`
+}
+
+// Enabled reports whether callers must authenticate.
+func (c AuthConfig) Enabled() bool {
+	return c.APIKeysPath != "" || c.JWTSecret != "" || c.JWKSPath != "" || c.ClientCertsPath != ""
+}
+
+type AuditConfig struct {
+	// Path enables the audit log if set.
+	Path        string   `yaml:"path" env:"AUDIT_LOG_PATH" usage:"file recording every query execution"`
+	Format      string   `yaml:"format" env:"AUDIT_LOG_FORMAT" usage:"audit log format (jsonl, sqlite)"`
+	ReaderRoles []string `yaml:"reader_roles" env:"AUDIT_READER_ROLES" usage:"comma-separated roles that may search every caller's audit records"`
+}
+
+// TracingConfig uses the OpenTelemetry variable names. The exporter reads its
+// endpoint and headers from the other OTEL_EXPORTER_OTLP_* variables.
+type TracingConfig struct {
+	Exporter string `yaml:"exporter" env:"OTEL_TRACES_EXPORTER" usage:"trace exporter (none, otlp)"`
+	Protocol string `yaml:"protocol" env:"OTEL_EXPORTER_OTLP_PROTOCOL" usage:"OTLP protocol (http/protobuf, grpc)"`
+}
+
+// Default returns the configuration used where nothing else is set.
+func Default() *Config {
+	return &Config{
+		Env: EnvProd,
+		Server: ServerConfig{
+			Port:          "8080",
+			ShutdownDelay: 5 * time.Second,
+			DrainTimeout:  25 * time.Second,
+			TLSClientAuth: certs.ClientAuthRequire,
+		},
+		Logging: LoggingConfig{Format: logger.FormatJSON, Output: LogOutputStdout, SlowQuery: 10 * time.Second},
+		Sources: SourcesConfig{
+			Elasticsearch: ElasticsearchConfig{
+				Addresses: []string{"http://localhost:9200"},
+				Index:     "logs",
+			},
+		},
+		Relationships: RelationshipsConfig{ConfigPath: "relationships.json"},
+		Limits: LimitsConfig{
+			QueryTimeout:  30 * time.Second,
+			FactLimit:     1_000_000,
+			MaxConcurrent: 8,
+			MaxQueue:      32,
+			QueueTimeout:  10 * time.Second,
+			RateLimit:     10,
+			RateBurst:     20,
+		},
+		Jobs: JobsConfig{
+			Workers:   2,
+			MaxQueued: 100,
+			Timeout:   10 * time.Minute,
+			ResultTTL: time.Hour,
+		},
+		Health:  HealthConfig{CheckTimeout: 2 * time.Second},
+		Auth:    AuthConfig{AdminRoles: []string{"admin"}},
+		Audit:   AuditConfig{Format: AuditFormatJSONL, ReaderRoles: []string{"auditor"}},
+		Tracing: TracingConfig{Exporter: tracing.ExporterNone},
+	}
+}
+
+// applyEnvDefaults fills the settings whose default depends on the environment.
+func (c *Config) applyEnvDefaults() {
+	if c.Logging.Level == "" {
+		c.Logging.Level = "debug"
+		if c.Env == EnvProd {
+			c.Logging.Level = "info"
+		}
+	}
+	if c.Sources.Type == "" {
+		c.Sources.Type = SourceElasticsearch
+		if c.Env == EnvTest {
+			c.Sources.Type = SourceMock
+		}
+	}
+}
+
+// LogLevel returns the parsed Logging.Level. It is only valid after Validate.
+func (c *Config) LogLevel() slog.Level {
+	var level slog.Level
+	_ = level.UnmarshalText([]byte(c.Logging.Level))
+	return level
+}
+
+// Validate reports every invalid setting.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, path, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: "+format, append([]any{path}, args...)...))
+		}
+	}
+	oneOf := func(path, value string, allowed ...string) {
+		for _, a := range allowed {
+			if value == a {
+				return
+			}
+		}
+		check(false, path, "%q is not one of %q", value, allowed)
+	}
+
+	oneOf("env", c.Env, EnvDev, EnvProd, EnvTest)
+	check(validPort(c.Server.Port), "server.port", "%q is not a port number", c.Server.Port)
+	check(c.Server.GRPCPort == "" || validPort(c.Server.GRPCPort), "server.grpc_port", "%q is not a port number", c.Server.GRPCPort)
+	check(c.Server.GRPCPort == "" || c.Server.GRPCPort != c.Server.Port, "server.grpc_port", "must differ from server.port")
+	check(c.Server.ShutdownDelay >= 0, "server.shutdown_delay", "must not be negative")
+	check(c.Server.DrainTimeout >= 0, "server.drain_timeout", "must not be negative")
+	check(c.Server.TLSCertFile == "" || c.Server.TLSKeyFile != "", "server.tls_cert_file", "requires server.tls_key_file")
+	check(c.Server.TLSKeyFile == "" || c.Server.TLSCertFile != "", "server.tls_key_file", "requires server.tls_cert_file")
+	check(c.Server.TLSClientCAFile == "" || c.Server.TLSEnabled(), "server.tls_client_ca_file", "requires server.tls_cert_file")
+	oneOf("server.tls_client_auth", c.Server.TLSClientAuth, certs.ClientAuthRequire, certs.ClientAuthOptional)
+	var level slog.Level
+	check(level.UnmarshalText([]byte(c.Logging.Level)) == nil, "logging.level", "%q is not a log level", c.Logging.Level)
+	oneOf("logging.format", c.Logging.Format, logger.FormatJSON, logger.FormatText)
+	check(c.Logging.Output != "", "logging.output", "must not be empty")
+	check(c.Logging.SlowQuery >= 0, "logging.slow_query", "must not be negative")
+
+	oneOf("sources.type", c.Sources.Type, SourceElasticsearch, SourceMock)
+	if c.Sources.Type == SourceElasticsearch {
+		es := c.Sources.Elasticsearch
+		check(len(es.Addresses) > 0, "sources.elasticsearch.addresses", "at least one address is required")
+		for _, addr := range es.Addresses {
+			u, err := url.Parse(addr)
+			check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", "sources.elasticsearch.addresses", "%q is not an http or https URL", addr)
+		}
+		check(es.Index != "", "sources.elasticsearch.index", "must not be empty")
+		check(es.Password == "" || es.Username != "", "sources.elasticsearch.password", "requires a username")
+		check(es.APIKey == "" || es.Username == "", "sources.elasticsearch.api_key", "cannot be combined with a username")
+	}
+	check(c.Relationships.ConfigPath != "", "relationships.config_path", "must not be empty")
+
+	check(c.Limits.QueryTimeout >= 0, "limits.query_timeout", "must not be negative")
+	check(c.Limits.FactLimit >= 0, "limits.fact_limit", "must not be negative")
+	check(c.Limits.MaxConcurrent >= 0, "limits.max_concurrent", "must not be negative")
+	check(c.Limits.MaxQueue >= 0, "limits.max_queue", "must not be negative")
+	check(c.Limits.QueueTimeout >= 0, "limits.queue_timeout", "must not be negative")
+	check(c.Limits.RateLimit >= 0, "limits.rate_limit", "must not be negative")
+	check(c.Limits.RateBurst >= 0, "limits.rate_burst", "must not be negative")
+	check(c.Jobs.Workers > 0, "jobs.workers", "must be at least 1")
+	check(c.Jobs.MaxQueued >= 0, "jobs.max_queued", "must not be negative")
+	check(c.Jobs.Timeout >= 0, "jobs.timeout", "must not be negative")
+	check(c.Jobs.ResultTTL > 0, "jobs.result_ttl", "must be positive")
+	check(c.Health.CheckTimeout > 0, "health.check_timeout", "must be positive")
+
+	check(c.Auth.Issuer == "" && c.Auth.Audience == "" || c.Auth.JWTSecret != "" || c.Auth.JWKSPath != "",
+		"auth", "jwt_issuer and jwt_audience require jwt_hs256_secret or jwt_jwks_path")
+	check(c.Auth.ClientCertsPath == "" || c.Server.TLSClientCAFile != "", "auth.client_certs_path", "requires server.tls_client_ca_file")
+	oneOf("audit.format", c.Audit.Format, AuditFormatJSONL, AuditFormatSQLite)
+	oneOf("tracing.exporter", c.Tracing.Exporter, "", tracing.ExporterNone, tracing.ExporterOTLP)
+	oneOf("tracing.protocol", c.Tracing.Protocol, "", tracing.ProtocolHTTP, tracing.ProtocolGRPC)
+	return errors.Join(errs...)
+}
+
+func validPort(port string) bool {
+	n, err := strconv.Atoi(port)
+	return err == nil && n > 0 && n <= 65535
+}
+
+// Redacted returns a copy of the configuration whose secrets are replaced,
+// for printing and logging.
+func (c *Config) Redacted() *Config {
+	out := *c
+	for _, f := range settings(&out) {
+		if f.secret && f.value.String() != "" {
+			f.value.SetString(redacted)
+		}
+	}
+	return &out
+}
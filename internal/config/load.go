@@ -0,0 +1,190 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fileEnv names the configuration file if the -config-file flag is not given.
+const fileEnv = "MANGLE_CONFIG_FILE"
+
+// setting is a single configuration value, a leaf of Config.
+type setting struct {
+	// path is the YAML path, such as "server.port", which is also the flag name.
+	path   string
+	env    []string
+	usage  string
+	secret bool
+	value  reflect.Value
+}
+
+// settings lists the settings of c in declaration order; their values can
+// be set through the returned reflect.Values.
+func settings(c *Config) []setting {
+	var out []setting
+	var walk func(v reflect.Value, prefix string)
+	walk = func(v reflect.Value, prefix string) {
+		t := v.Type()
+		for i := range t.NumField() {
+			field := t.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+			path := prefix + name
+			if field.Type.Kind() == reflect.Struct {
+				walk(v.Field(i), path+".")
+				continue
+			}
+			s := setting{
+				path:   path,
+				usage:  field.Tag.Get("usage"),
+				secret: field.Tag.Get("secret") == "true",
+				value:  v.Field(i),
+			}
+			if env := field.Tag.Get("env"); env != "" {
+				s.env = strings.Split(env, ",")
+			}
+			out = append(out, s)
+		}
+	}
+	walk(reflect.ValueOf(c).Elem(), "")
+	return out
+}
+
+// Load builds the configuration from, in increasing order of precedence,
+// the defaults, the YAML file named by the -config-file flag or the
+// MANGLE_CONFIG_FILE variable, environment variables and flags, and
+// validates it. Every setting is registered as a flag on flags, which may
+// carry flags of its own; Load parses args with it.
+func Load(flags *flag.FlagSet, args []string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	c := Default()
+	all := settings(c)
+
+	// Flags are parsed first to find the file, but applied last.
+	configFile := flags.String("config-file", "", "YAML configuration file (default $"+fileEnv+")")
+	flagValues := make(map[string]string)
+	for _, s := range all {
+		record := func(value string) error {
+			flagValues[s.path] = value
+			return nil
+		}
+		usage := s.usage
+		if len(s.env) > 0 {
+			usage += " ($" + s.env[0] + ")"
+		}
+		if s.value.Kind() == reflect.Bool {
+			flags.BoolFunc(s.path, usage, record)
+		} else {
+			flags.Func(s.path, usage, record)
+		}
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	path := *configFile
+	if path == "" {
+		path, _ = lookupEnv(fileEnv)
+	}
+	if path != "" {
+		if err := c.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	var errs []error
+	for _, s := range all {
+		for _, name := range s.env {
+			if value, ok := lookupEnv(name); ok && value != "" {
+				if err := set(s.value, value); err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", name, err))
+				}
+				break
+			}
+		}
+	}
+	for _, s := range all {
+		if value, ok := flagValues[s.path]; ok {
+			if err := set(s.value, value); err != nil {
+				errs = append(errs, fmt.Errorf("-%s: %w", s.path, err))
+			}
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	c.applyEnvDefaults()
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return c, nil
+}
+
+// loadFile reads the YAML file at path over c. Unknown keys are rejected so
+// that misspelt settings do not go unnoticed.
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read configuration file: %w", err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// set parses value into v according to its type. Lists are comma-separated.
+func set(v reflect.Value, value string) error {
+	if v.Type() == reflect.TypeFor[time.Duration]() {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported setting type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package domain
+
+import "time"
+
+// LogLevel is the minimum level of the service's log lines: debug, info,
+// warn or error.
+type LogLevel struct {
+	Level string `json:"level"`
+}
+
+// RelationshipState is the active relationship configuration and how it
+// came to be active.
+type RelationshipState struct {
+	Config RelationshipConfig `json:"config"`
+	// UpdatedAt is when the configuration was loaded or last changed.
+	UpdatedAt time.Time `json:"updated_at,omitzero"`
+	// ReloadError is the error of the last reload if it failed, in which
+	// case Config is the previous configuration.
+	ReloadError    string    `json:"reload_error,omitempty"`
+	ReloadFailedAt time.Time `json:"reload_failed_at,omitzero"`
+}
+
+// Predicate kinds in a program.
+const (
+	// PredicateExtensional marks predicates whose facts are given, such as
+	// those read from the logs.
+	PredicateExtensional = "extensional"
+	// PredicateIntensional marks predicates derived by rules.
+	PredicateIntensional = "intensional"
+)
+
+// ProgramInfo is the program every query is evaluated against before the
+// query adds its own rules and the log facts.
+type ProgramInfo struct {
+	// Rules are the clauses given to analysis, in the order they are given:
+	// those of the relationship graph, then those of the rule modules.
+	Rules []string `json:"rules"`
+	// Predicates are the predicates of the rules, with the signatures
+	// declared by the rule modules or inferred by analysis.
+	Predicates []PredicateSignature `json:"predicates"`
+	// Sources are the configured log sources; the first is the default.
+	Sources []string `json:"sources"`
+}
+
+// PredicateSignature is the signature of a predicate as analysis sees it.
+type PredicateSignature struct {
+	Name  string   `json:"name"`
+	Arity int      `json:"arity"`
+	Args  []string `json:"args"`
+	// Kind is PredicateExtensional or PredicateIntensional.
+	Kind string `json:"kind"`
+	// Declared is true if a rule module declares the predicate. The
+	// signatures of other predicates are inferred from their use.
+	Declared bool `json:"declared"`
+	// Bounds lists the declared type bounds, one per argument, for each
+	// alternative.
+	Bounds [][]string `json:"bounds,omitempty"`
+	Doc    string     `json:"doc,omitempty"`
+}
+
+// SourceState is a configured log source and the outcome of its health
+// check.
+type SourceState struct {
+	Name    string `json:"name"`
+	Default bool   `json:"default"`
+	// Status is empty if the service does not check its sources.
+	Status  HealthStatus `json:"status,omitempty"`
+	Message string       `json:"message,omitempty"`
+}
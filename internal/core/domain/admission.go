@@ -0,0 +1,38 @@
+package domain
+
+import "time"
+
+// AdmissionConfig limits how many queries run at once and how often a single
+// client may query. Zero values disable the respective limit.
+type AdmissionConfig struct {
+	// MaxConcurrent is the number of queries evaluated at the same time.
+	MaxConcurrent int
+	// MaxQueue is the number of queries that may wait for a free slot.
+	MaxQueue int
+	// QueueTimeout is how long a query waits for a slot before it is rejected.
+	QueueTimeout time.Duration
+	// ClientRate is the sustained number of queries per second per client.
+	ClientRate float64
+	// ClientBurst is the number of queries a client may send at once.
+	ClientBurst int
+}
+
+// AdmissionStats describes the current load and how many queries were turned away.
+type AdmissionStats struct {
+	MaxConcurrent int `json:"max_concurrent"`
+	MaxQueue      int `json:"max_queue"`
+	// Running is the number of queries being evaluated.
+	Running int `json:"running"`
+	// Queued is the number of queries waiting for a slot.
+	Queued int `json:"queued"`
+	// Clients is the number of clients with rate limit state.
+	Clients int `json:"clients"`
+
+	Admitted uint64 `json:"admitted"`
+	// RateLimited counts queries rejected by a client's rate limit.
+	RateLimited uint64 `json:"rate_limited"`
+	// QueueFull counts queries rejected because the wait queue was full.
+	QueueFull uint64 `json:"queue_full"`
+	// QueueTimeouts counts queries that waited longer than the queue timeout.
+	QueueTimeouts uint64 `json:"queue_timeouts"`
+}
@@ -0,0 +1,101 @@
+package domain
+
+import (
+	"slices"
+	"time"
+)
+
+// Audit search limits.
+const (
+	// DefaultAuditLimit is the number of records returned when a search sets no limit.
+	DefaultAuditLimit = 100
+	// MaxAuditLimit is the largest number of records a single search returns.
+	MaxAuditLimit = 1000
+)
+
+// Channels through which queries reach the query service.
+const (
+	ChannelHTTP = "http"
+	ChannelGRPC = "grpc"
+	ChannelJob  = "job"
+)
+
+// AuditOutcome says whether an audited query execution succeeded.
+type AuditOutcome string
+
+const (
+	AuditSucceeded AuditOutcome = "succeeded"
+	AuditFailed    AuditOutcome = "failed"
+)
+
+// AuditRecord describes a single query execution: who ran which query over
+// which time window and sources, what it read and what it returned.
+type AuditRecord struct {
+	ID string `json:"id"`
+	// Time is when the query was received, before it waited for admission.
+	Time       time.Time `json:"time"`
+	DurationMS int64     `json:"duration_ms"`
+	RequestID  string    `json:"request_id,omitempty"`
+	// Channel is how the query arrived: ChannelHTTP, ChannelGRPC or ChannelJob.
+	Channel string `json:"channel,omitempty"`
+	// Subject and AuthMethod identify the authenticated caller. They are
+	// empty if authentication is disabled.
+	Subject       string     `json:"subject,omitempty"`
+	AuthMethod    string     `json:"auth_method,omitempty"`
+	ClientAddress string     `json:"client_address,omitempty"`
+	Name          string     `json:"name,omitempty"`
+	Query         string     `json:"query"`
+	QueryHash     string     `json:"query_hash"`
+	TimeRange     *TimeRange `json:"time_range,omitempty"`
+	// Sources are the log sources the query read, or the ones it asked for
+	// if it failed before reading any.
+	Sources []string `json:"sources"`
+	// FactsRead is the number of log facts read from the sources.
+	FactsRead int          `json:"facts_read"`
+	Results   int          `json:"results"`
+	Outcome   AuditOutcome `json:"outcome"`
+	ErrorCode ErrorCode    `json:"error_code,omitempty"`
+}
+
+// AuditFilter selects audit records. Zero fields match every record.
+type AuditFilter struct {
+	Subject   string
+	Name      string
+	QueryHash string
+	// Source matches records that read the source.
+	Source  string
+	Outcome AuditOutcome
+	// Since and Until bound the record time to [Since, Until).
+	Since time.Time
+	Until time.Time
+	// Limit is the largest number of records returned, newest first.
+	Limit int
+}
+
+// Matches reports whether the filter selects the record.
+func (f AuditFilter) Matches(r AuditRecord) bool {
+	switch {
+	case f.Subject != "" && r.Subject != f.Subject,
+		f.Name != "" && r.Name != f.Name,
+		f.QueryHash != "" && r.QueryHash != f.QueryHash,
+		f.Source != "" && !slices.Contains(r.Sources, f.Source),
+		f.Outcome != "" && r.Outcome != f.Outcome,
+		!f.Since.IsZero() && r.Time.Before(f.Since),
+		!f.Until.IsZero() && !r.Time.Before(f.Until):
+		return false
+	}
+	return true
+}
+
+// AuditSearchResult is a page of audit records, newest first.
+type AuditSearchResult struct {
+	Records []AuditRecord `json:"records"`
+	Count   int           `json:"count"`
+}
+
+// AuditConfig controls who may read the audit log.
+type AuditConfig struct {
+	// ReaderRoles may search every caller's records. Other callers only see
+	// their own.
+	ReaderRoles []string
+}
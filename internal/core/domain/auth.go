@@ -0,0 +1,39 @@
+package domain
+
+import "slices"
+
+// Principal is an authenticated caller.
+type Principal struct {
+	Subject string   `json:"subject"`
+	Roles   []string `json:"roles"`
+	// Method is how the caller authenticated, such as "api_key" or "jwt".
+	Method string `json:"method"`
+}
+
+// HasAnyRole reports whether the principal has at least one of roles.
+func (p *Principal) HasAnyRole(roles []string) bool {
+	if p == nil {
+		return false
+	}
+	for _, role := range roles {
+		if slices.Contains(p.Roles, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// AccessPolicy restricts predicates and log sources to callers with certain
+// roles. Predicates and sources that no rule matches are open to every caller.
+type AccessPolicy struct {
+	Rules []AccessRule `yaml:"rules" json:"rules"`
+}
+
+// AccessRule restricts the matching predicates and sources to callers that
+// have one of Roles. Predicate patterns use path.Match syntax, so "audit.*"
+// matches every predicate of the audit rule module.
+type AccessRule struct {
+	Predicates []string `yaml:"predicates" json:"predicates,omitempty"`
+	Sources    []string `yaml:"sources" json:"sources,omitempty"`
+	Roles      []string `yaml:"roles" json:"roles"`
+}
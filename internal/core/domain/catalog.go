@@ -0,0 +1,30 @@
+package domain
+
+// Origins of predicates in the catalog.
+const (
+	// OriginLogs marks predicates whose facts are read from a log source.
+	OriginLogs = "logs"
+	// OriginRelationships marks predicates derived from the relationship graph.
+	OriginRelationships = "relationships"
+	// OriginModule marks predicates defined by a shared rule module.
+	OriginModule = "module"
+)
+
+// PredicateInfo describes a predicate that queries can use.
+type PredicateInfo struct {
+	Name  string `json:"name"`
+	Arity int    `json:"arity"`
+	// Args names the arguments, if they are known.
+	Args []string `json:"args,omitempty"`
+	// Origin is one of OriginLogs, OriginRelationships and OriginModule.
+	Origin string `json:"origin"`
+	// Source is the log source or rule module that provides the predicate.
+	Source      string `json:"source,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// PredicateCatalog lists the predicates and log sources available to a caller.
+type PredicateCatalog struct {
+	Predicates []PredicateInfo `json:"predicates"`
+	Sources    []string        `json:"sources"`
+}
@@ -0,0 +1,72 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrServiceNotFound is returned when a service is not part of the relationship graph.
+	ErrServiceNotFound = errors.New("service not found")
+	// ErrEdgeNotFound is returned when a dependency edge is not part of the relationship graph.
+	ErrEdgeNotFound = errors.New("dependency not found")
+	// ErrVersionConflict is returned when a write was based on an outdated version.
+	ErrVersionConflict = errors.New("version conflict")
+	// ErrInvalidRelationship is returned when a relationship change is malformed.
+	ErrInvalidRelationship = errors.New("invalid relationship")
+	// ErrUnauthenticated is returned when a caller presents no or invalid credentials.
+	ErrUnauthenticated = errors.New("unauthenticated")
+)
+
+// ErrorCode is a machine-readable classification of a failed query.
+type ErrorCode string
+
+const (
+	// CodeInvalidQuery means the query is well-formed Mangle but not a valid request, e.g. empty.
+	CodeInvalidQuery ErrorCode = "invalid_query"
+	// CodeParseError means the query is not valid Mangle syntax.
+	CodeParseError ErrorCode = "parse_error"
+	// CodeAnalysisError means the query failed static analysis, e.g. it uses an unknown predicate.
+	CodeAnalysisError ErrorCode = "analysis_error"
+	// CodeEvaluationError means the query failed while it was evaluated.
+	CodeEvaluationError ErrorCode = "evaluation_error"
+	// CodeForbidden means the caller may not use a predicate or source the query needs.
+	CodeForbidden ErrorCode = "forbidden"
+	// CodeBudgetExceeded means evaluating the query derived more facts than allowed.
+	CodeBudgetExceeded ErrorCode = "budget_exceeded"
+	// CodeSourceUnavailable means a data source, such as the log store, could not be reached.
+	CodeSourceUnavailable ErrorCode = "source_unavailable"
+	// CodeRateLimited means the caller sent more queries than its rate limit allows.
+	CodeRateLimited ErrorCode = "rate_limited"
+	// CodeOverloaded means the service is running as many queries as it can
+	// and the query could not wait for a slot.
+	CodeOverloaded ErrorCode = "overloaded"
+	// CodeTimeout means the query did not finish within its deadline.
+	CodeTimeout ErrorCode = "timeout"
+	// CodeCanceled means the caller gave up on the query.
+	CodeCanceled ErrorCode = "canceled"
+	// CodeInternal means the query failed for reasons the caller cannot fix.
+	CodeInternal ErrorCode = "internal_error"
+)
+
+// QueryError is returned when a query cannot be answered. Code tells callers
+// what went wrong; Details carries code-specific context such as a limit.
+type QueryError struct {
+	Code    ErrorCode
+	Message string
+	Details map[string]any
+	Err     error
+	// RetryAfter, if set, tells callers when trying again may succeed.
+	RetryAfter time.Duration
+}
+
+func (e *QueryError) Error() string {
+	if e.Err == nil {
+		return e.Message
+	}
+	return e.Message + ": " + e.Err.Error()
+}
+
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,21 @@
+package domain
+
+// GraphNode is a service in the relationship graph.
+type GraphNode struct {
+	ID          string `json:"id"`
+	Highlighted bool   `json:"highlighted,omitempty"`
+}
+
+// GraphEdge is a calls relationship from one service to another.
+type GraphEdge struct {
+	From        string `json:"from"`
+	To          string `json:"to"`
+	Highlighted bool   `json:"highlighted,omitempty"`
+}
+
+// Graph is the service relationship graph, optionally with the nodes and
+// edges that appear in a query result highlighted.
+type Graph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
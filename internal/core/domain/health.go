@@ -0,0 +1,66 @@
+package domain
+
+import "time"
+
+// HealthStatus is the outcome of a health check.
+type HealthStatus string
+
+// Health check outcomes, from best to worst. A warning does not make the
+// service unready; a failure does.
+const (
+	HealthPass HealthStatus = "pass"
+	HealthWarn HealthStatus = "warn"
+	HealthFail HealthStatus = "fail"
+)
+
+// Kinds of health checks.
+const (
+	HealthCheckLogSource     = "log_source"
+	HealthCheckRelationships = "relationships"
+	HealthCheckQueryQueue    = "query_queue"
+	HealthCheckShutdown      = "shutdown"
+)
+
+// HealthCheck is the result of checking one dependency of the service.
+type HealthCheck struct {
+	// Name identifies the check, e.g. "log_source:logs".
+	Name string `json:"name"`
+	// Kind is one of the HealthCheck kinds.
+	Kind       string         `json:"kind"`
+	Status     HealthStatus   `json:"status"`
+	Message    string         `json:"message,omitempty"`
+	DurationMS int64          `json:"duration_ms"`
+	Details    map[string]any `json:"details,omitempty"`
+}
+
+// HealthReport combines the results of every health check. Its status is
+// that of the worst check.
+type HealthReport struct {
+	Status    HealthStatus  `json:"status"`
+	CheckedAt time.Time     `json:"checked_at"`
+	Checks    []HealthCheck `json:"checks"`
+}
+
+// Ready reports whether the service should receive traffic.
+func (r *HealthReport) Ready() bool {
+	return r.Status != HealthFail
+}
+
+// HealthConfig configures the readiness checks.
+type HealthConfig struct {
+	// Timeout bounds each check; checks that take longer fail.
+	Timeout time.Duration
+}
+
+// RelationshipStatus describes when the relationship configuration was last
+// loaded and whether reloading it failed.
+type RelationshipStatus struct {
+	Loaded  bool
+	Version int64
+	// UpdatedAt is when the active configuration was loaded or last changed.
+	UpdatedAt time.Time
+	// ReloadError is the error of the last reload if it failed, in which case
+	// the previous configuration is still active.
+	ReloadError    string
+	ReloadFailedAt time.Time
+}
@@ -0,0 +1,32 @@
+package domain
+
+// ImpactRequest asks which services are affected when a service fails.
+type ImpactRequest struct {
+	// Service is the failing service.
+	Service string `json:"service"`
+	// MaxDepth limits how many hops upstream to look; 0 means unlimited.
+	MaxDepth int `json:"max_depth,omitempty"`
+	// ErrorsQuery is an optional Mangle query returning the services that
+	// logged errors. If set, only impacted services that appear in its results
+	// are returned. The query's Service variable is used if it has one,
+	// otherwise every bound value counts.
+	ErrorsQuery string `json:"errors_query,omitempty"`
+	// TimeRange restricts the logs seen by ErrorsQuery.
+	TimeRange *TimeRange `json:"time_range,omitempty"`
+}
+
+// ImpactedService is an upstream service that transitively depends on the failing service.
+type ImpactedService struct {
+	Service     string `json:"service"`
+	Distance    int    `json:"distance"`
+	Criticality string `json:"criticality,omitempty"`
+	// Paths are the shortest dependency paths from this service to the failing service.
+	Paths [][]string `json:"paths"`
+}
+
+// ImpactResult lists the impacted services, ordered by distance and then by criticality.
+type ImpactResult struct {
+	Service  string            `json:"service"`
+	Impacted []ImpactedService `json:"impacted"`
+	Count    int               `json:"count"`
+}
@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrJobNotFound is returned when a job does not exist, has expired or belongs to another caller.
+var ErrJobNotFound = errors.New("job not found")
+
+// JobStatus is the state of an asynchronous query job.
+type JobStatus string
+
+const (
+	// JobQueued means the job waits for a free worker.
+	JobQueued JobStatus = "queued"
+	// JobRunning means the job's query is being evaluated.
+	JobRunning JobStatus = "running"
+	// JobSucceeded means the job's result is available.
+	JobSucceeded JobStatus = "succeeded"
+	// JobFailed means the job's query failed; the job's error says why.
+	JobFailed JobStatus = "failed"
+	// JobCanceled means the job was cancelled before it finished.
+	JobCanceled JobStatus = "canceled"
+)
+
+// Done reports whether a job in this state has finished.
+func (s JobStatus) Done() bool {
+	return s == JobSucceeded || s == JobFailed || s == JobCanceled
+}
+
+// JobConfig limits the asynchronous query jobs.
+type JobConfig struct {
+	// Workers is the number of jobs run at the same time.
+	Workers int
+	// MaxQueued is the number of jobs that may wait for a free worker.
+	MaxQueued int
+	// ResultTTL is how long a finished job and its result are kept.
+	ResultTTL time.Duration
+}
+
+// Job is a query run in the background. Result is set once the job has
+// succeeded and Error once it has failed.
+type Job struct {
+	ID         string       `json:"id"`
+	Status     JobStatus    `json:"status"`
+	Request    QueryRequest `json:"request"`
+	CreatedAt  time.Time    `json:"created_at"`
+	StartedAt  time.Time    `json:"started_at,omitzero"`
+	FinishedAt time.Time    `json:"finished_at,omitzero"`
+	// ExpiresAt is when a finished job and its result are discarded.
+	ExpiresAt time.Time    `json:"expires_at,omitzero"`
+	Progress  JobProgress  `json:"progress"`
+	Result    *QueryResult `json:"result,omitempty"`
+	Error     *JobError    `json:"error,omitempty"`
+}
+
+// JobProgress describes how far a job has got.
+type JobProgress struct {
+	// Results is the number of results found so far.
+	Results int `json:"results"`
+	// ElapsedMS is how long the job has been running, in milliseconds.
+	ElapsedMS int64 `json:"elapsed_ms"`
+}
+
+// JobError describes why a job failed, with the codes of failed queries.
+type JobError struct {
+	Code    ErrorCode      `json:"code"`
+	Message string         `json:"message"`
+	Details map[string]any `json:"details,omitempty"`
+}
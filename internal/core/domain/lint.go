@@ -0,0 +1,63 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LintSeverity describes how serious a lint issue is.
+type LintSeverity string
+
+const (
+	// LintError marks issues that make the configuration unusable.
+	LintError LintSeverity = "error"
+	// LintWarning marks issues that are most likely mistakes.
+	LintWarning LintSeverity = "warning"
+	// LintInfo marks issues that are worth knowing about but are often intended.
+	LintInfo LintSeverity = "info"
+)
+
+// LintIssue is a single finding of the relationship linter.
+type LintIssue struct {
+	Severity LintSeverity `json:"severity"`
+	Code     string       `json:"code"`
+	Message  string       `json:"message"`
+	Service  string       `json:"service,omitempty"`
+	// Line is the line in the source document, or 0 if unknown.
+	Line int `json:"line,omitempty"`
+}
+
+// String formats the issue like a compiler message, "N: severity: message
+// [code]", so that it can follow the name of the file; the line is left out
+// if unknown.
+func (i LintIssue) String() string {
+	if i.Line > 0 {
+		return fmt.Sprintf("%d: %s: %s [%s]", i.Line, i.Severity, i.Message, i.Code)
+	}
+	return fmt.Sprintf("%s: %s [%s]", i.Severity, i.Message, i.Code)
+}
+
+// LintFailedError is returned when a configuration has lint issues of error severity.
+type LintFailedError struct {
+	Issues []LintIssue
+}
+
+func (e *LintFailedError) Error() string {
+	var lines []string
+	for _, issue := range e.Issues {
+		if issue.Severity == LintError {
+			lines = append(lines, issue.String())
+		}
+	}
+	return fmt.Sprintf("relationship config has %d error(s): %s", len(lines), strings.Join(lines, "; "))
+}
+
+// HasLintErrors reports whether any of the issues has error severity.
+func HasLintErrors(issues []LintIssue) bool {
+	for _, issue := range issues {
+		if issue.Severity == LintError {
+			return true
+		}
+	}
+	return false
+}
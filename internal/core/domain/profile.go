@@ -0,0 +1,35 @@
+package domain
+
+// QueryProfile breaks down where a query spent its time and how much it
+// read and derived. Durations are in fractional milliseconds.
+type QueryProfile struct {
+	DurationMS float64 `json:"duration_ms"`
+	// Phases lists the phases that ran, in order. A failed query ends with
+	// the phase that failed.
+	Phases []PhaseProfile `json:"phases"`
+	// Facts counts the input facts of each predicate, from the logs and the
+	// relationship graph.
+	Facts map[string]int `json:"facts"`
+	// Derived counts the facts derived for each predicate defined by rules.
+	Derived map[string]int `json:"derived"`
+	// Strata lists the strata of the program in evaluation order.
+	Strata []StratumProfile `json:"strata"`
+	// Iterations is the number of fixpoint iterations that derived new
+	// facts, over all strata.
+	Iterations int `json:"iterations"`
+}
+
+// PhaseProfile is the time spent in a single query phase.
+type PhaseProfile struct {
+	Phase      QueryPhase `json:"phase"`
+	DurationMS float64    `json:"duration_ms"`
+}
+
+// StratumProfile describes the evaluation of a single stratum: a group of
+// predicates whose rules are evaluated to a fixpoint together.
+type StratumProfile struct {
+	Predicates []string `json:"predicates"`
+	Derived    int      `json:"derived"`
+	Iterations int      `json:"iterations"`
+	DurationMS float64  `json:"duration_ms"`
+}
@@ -1,6 +1,78 @@
 package domain
 
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// Query criteria keys with a special meaning for LogDataPort implementations.
+// Their values are RFC 3339 timestamps.
+const (
+	// CriteriaFrom is the inclusive start of the time window.
+	CriteriaFrom = "@timestamp.from"
+	// CriteriaTo is the exclusive end of the time window.
+	CriteriaTo = "@timestamp.to"
+)
+
+// DefaultLogSource is the name of the log source passed to the query service constructor.
+const DefaultLogSource = "logs"
+
 // QueryRequest represents the incoming request for a Mangle query.
 type QueryRequest struct {
 	Query string `json:"query"`
+	// TimeRange restricts the log facts to a time window, if set.
+	TimeRange *TimeRange `json:"time_range,omitempty"`
+	// Sources names the log sources to read facts from. If empty, every
+	// source the caller may use is read.
+	Sources []string `json:"sources,omitempty"`
+	// Name identifies a saved or recurring query, such as a dashboard
+	// panel, in metrics. It does not change what the query does.
+	Name string `json:"name,omitempty"`
+	// Debug returns the query's profile with its result.
+	Debug bool `json:"debug,omitempty"`
+}
+
+// Hash identifies the query text without revealing it, so that executions
+// of the same query can be correlated in traces and logs.
+func (r QueryRequest) Hash() string {
+	sum := sha256.Sum256([]byte(r.Query))
+	return hex.EncodeToString(sum[:8])
+}
+
+// QueryPhase is a stage of query execution.
+type QueryPhase string
+
+// Query phases, in the order they run.
+const (
+	// PhaseParse parses the query and checks the caller's access.
+	PhaseParse QueryPhase = "parse"
+	// PhaseFetch reads the log facts and the relationship graph.
+	PhaseFetch QueryPhase = "fetch"
+	// PhaseAnalysis analyzes and stratifies the combined program.
+	PhaseAnalysis QueryPhase = "analysis"
+	// PhaseEvaluation derives facts until a fixpoint is reached.
+	PhaseEvaluation QueryPhase = "evaluation"
+	// PhaseExtraction reads the results from the fact store.
+	PhaseExtraction QueryPhase = "extraction"
+)
+
+// TimeRange is the half-open time window [From, To). A zero bound is open.
+type TimeRange struct {
+	From time.Time `json:"from,omitzero"`
+	To   time.Time `json:"to,omitzero"`
+}
+
+// Criteria returns the log fetching criteria for the request.
+func (r QueryRequest) Criteria() map[string]string {
+	criteria := make(map[string]string)
+	if r.TimeRange != nil {
+		if !r.TimeRange.From.IsZero() {
+			criteria[CriteriaFrom] = r.TimeRange.From.Format(time.RFC3339Nano)
+		}
+		if !r.TimeRange.To.IsZero() {
+			criteria[CriteriaTo] = r.TimeRange.To.Format(time.RFC3339Nano)
+		}
+	}
+	return criteria
 }
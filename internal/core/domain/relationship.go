@@ -1,12 +1,114 @@
 package domain
 
+import "slices"
+
+// AnyVersion can be passed as an expected version to skip the optimistic concurrency check.
+const AnyVersion int64 = -1
+
+// Criticality levels of a service, from most to least critical.
+const (
+	CriticalityCritical = "critical"
+	CriticalityHigh     = "high"
+	CriticalityMedium   = "medium"
+	CriticalityLow      = "low"
+)
+
+// CriticalityRank orders criticality levels; lower ranks are more critical.
+// Unknown or unset levels rank last.
+func CriticalityRank(criticality string) int {
+	switch criticality {
+	case CriticalityCritical:
+		return 0
+	case CriticalityHigh:
+		return 1
+	case CriticalityMedium:
+		return 2
+	case CriticalityLow:
+		return 3
+	default:
+		return 4
+	}
+}
+
 // ServiceRelationship defines a single service and its dependencies.
 type ServiceRelationship struct {
-	Service   string   `yaml:"service"`
-	DependsOn []string `yaml:"depends_on"`
+	Service   string   `yaml:"service" json:"service"`
+	DependsOn []string `yaml:"depends_on" json:"depends_on"`
+	// Criticality is one of the Criticality levels, or empty if unknown.
+	Criticality string `yaml:"criticality,omitempty" json:"criticality,omitempty"`
+
+	// Line is the line in the source document that declares the service, or 0 if unknown.
+	Line int `yaml:"-" json:"-"`
+	// DependsOnLines holds the source line of each entry in DependsOn, if known.
+	DependsOnLines []int `yaml:"-" json:"-"`
+}
+
+// DependencyLine returns the source line of the i-th dependency, falling back
+// to the line of the service itself.
+func (r ServiceRelationship) DependencyLine(i int) int {
+	if i < len(r.DependsOnLines) && r.DependsOnLines[i] > 0 {
+		return r.DependsOnLines[i]
+	}
+	return r.Line
 }
 
 // RelationshipConfig represents the entire service relationship configuration.
 type RelationshipConfig struct {
-	Relationships []ServiceRelationship `yaml:"relationships"`
+	Relationships []ServiceRelationship `yaml:"relationships" json:"relationships"`
+	// Version is incremented on every change and is used for optimistic concurrency.
+	Version int64 `yaml:"-" json:"version"`
+}
+
+// Clone returns a deep copy of the configuration.
+func (c RelationshipConfig) Clone() RelationshipConfig {
+	clone := RelationshipConfig{
+		Relationships: make([]ServiceRelationship, len(c.Relationships)),
+		Version:       c.Version,
+	}
+	for i, rel := range c.Relationships {
+		clone.Relationships[i] = ServiceRelationship{
+			Service:        rel.Service,
+			DependsOn:      append([]string(nil), rel.DependsOn...),
+			Criticality:    rel.Criticality,
+			Line:           rel.Line,
+			DependsOnLines: append([]int(nil), rel.DependsOnLines...),
+		}
+	}
+	return clone
+}
+
+// MergeRelationshipConfigs combines several configurations into one. Services
+// that appear in more than one configuration get the union of their
+// dependencies, in order of first appearance. Source lines are only kept when
+// a single configuration is given, since they would be ambiguous otherwise.
+func MergeRelationshipConfigs(configs ...*RelationshipConfig) *RelationshipConfig {
+	if len(configs) == 1 && configs[0] != nil {
+		merged := configs[0].Clone()
+		return &merged
+	}
+
+	merged := &RelationshipConfig{}
+	index := make(map[string]int)
+	for _, config := range configs {
+		if config == nil {
+			continue
+		}
+		for _, rel := range config.Relationships {
+			i, ok := index[rel.Service]
+			if !ok {
+				i = len(merged.Relationships)
+				index[rel.Service] = i
+				merged.Relationships = append(merged.Relationships, ServiceRelationship{Service: rel.Service})
+			}
+			if merged.Relationships[i].Criticality == "" {
+				merged.Relationships[i].Criticality = rel.Criticality
+			}
+			for _, dep := range rel.DependsOn {
+				if !slices.Contains(merged.Relationships[i].DependsOn, dep) {
+					merged.Relationships[i].DependsOn = append(merged.Relationships[i].DependsOn, dep)
+				}
+			}
+		}
+	}
+	return merged
 }
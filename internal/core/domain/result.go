@@ -6,6 +6,30 @@ type LogEntry map[string]interface{}
 
 // QueryResult represents the result of a Mangle query.
 type QueryResult struct {
+	// Columns lists the query's variables in the order they appear in the query atom.
+	Columns []string   `json:"columns"`
 	Results []LogEntry `json:"results"`
 	Count   int        `json:"count"`
+	// Facts holds the fact behind each result, for output formats that need
+	// the original values rather than their string form.
+	Facts []Fact `json:"-"`
+	// Profile is set if the request asked for it with Debug.
+	Profile *QueryProfile `json:"profile,omitempty"`
+}
+
+// QuerySummary describes a completed query whose results were streamed.
+type QuerySummary struct {
+	Columns    []string `json:"columns"`
+	Count      int      `json:"count"`
+	DurationMS int64    `json:"duration_ms"`
+	// Profile is set if the request asked for it with Debug.
+	Profile *QueryProfile `json:"profile,omitempty"`
+}
+
+// QueryValidation describes a query that passed validation.
+type QueryValidation struct {
+	// Columns are the columns the query would return.
+	Columns []string `json:"columns"`
+	// Sources are the log sources the query would read.
+	Sources []string `json:"sources"`
 }
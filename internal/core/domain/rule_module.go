@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/mangle/parse"
+)
+
+// RuleModuleSource is the raw text of a Mangle rule module.
+type RuleModuleSource struct {
+	// Name is the default module name, usually the file name without extension.
+	// A Package declaration in the source takes precedence.
+	Name   string
+	Path   string
+	Source string
+}
+
+// RuleModule describes a loaded rule module.
+type RuleModule struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	// Predicates lists the namespaced predicates the module defines, as name/arity.
+	Predicates []string `json:"predicates"`
+}
+
+// RuleModuleSet is the result of loading every rule module.
+type RuleModuleSet struct {
+	Modules  []RuleModule `json:"modules"`
+	LoadedAt time.Time    `json:"loaded_at,omitzero"`
+}
+
+// RuleSet holds namespaced Mangle clauses and declarations that are added to
+// every query program.
+type RuleSet = parse.SourceUnit
@@ -0,0 +1,17 @@
+package ports
+
+import (
+	"context"
+	"mangle-service/internal/core/domain"
+)
+
+// AuditSink persists a record of every query execution and searches them.
+// Implementations must be safe for concurrent use.
+type AuditSink interface {
+	// Write appends a record.
+	Write(ctx context.Context, record domain.AuditRecord) error
+	// Search returns the records the filter selects, newest first, up to its limit.
+	Search(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditRecord, error)
+	// Close releases the sink's file or database.
+	Close() error
+}
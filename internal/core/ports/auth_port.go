@@ -0,0 +1,19 @@
+package ports
+
+import (
+	"crypto/x509"
+	"mangle-service/internal/core/domain"
+)
+
+// Authenticator verifies the credentials presented by a caller of an inbound
+// adapter. Exactly one of apiKey and bearerToken is expected to be set.
+type Authenticator interface {
+	Authenticate(apiKey, bearerToken string) (*domain.Principal, error)
+}
+
+// CertificateAuthenticator is implemented by Authenticators that identify
+// callers by their TLS client certificate. The certificate has already been
+// verified against the trusted CAs; only its identity is checked.
+type CertificateAuthenticator interface {
+	AuthenticateCertificate(cert *x509.Certificate) (*domain.Principal, error)
+}
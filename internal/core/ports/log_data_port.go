@@ -1,8 +1,25 @@
 package ports
 
-import "mangle-service/internal/core/domain"
+import (
+	"context"
+	"mangle-service/internal/core/domain"
+)
 
 // LogDataPort is an interface for fetching log data from a data source.
 type LogDataPort interface {
-	FetchLogs(queryCriteria map[string]string) ([]domain.Fact, error)
+	FetchLogs(ctx context.Context, queryCriteria map[string]string) ([]domain.Fact, error)
+}
+
+// PredicateDescriber is implemented by log data adapters that know the
+// predicates of the facts they return, so that clients can discover them.
+type PredicateDescriber interface {
+	Predicates() []domain.PredicateInfo
+}
+
+// HealthChecker is implemented by log data adapters that can tell whether
+// their data source is reachable, so that the service is only reported ready
+// when it can answer queries.
+type HealthChecker interface {
+	// CheckHealth returns an error if the data source cannot be queried.
+	CheckHealth(ctx context.Context) error
 }
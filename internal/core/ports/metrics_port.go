@@ -0,0 +1,30 @@
+package ports
+
+import (
+	"mangle-service/internal/core/domain"
+	"time"
+)
+
+// QueryMetrics records measurements of query executions. Queries are
+// identified by their request's name, which is empty for ad-hoc queries.
+// Implementations must be safe for concurrent use.
+type QueryMetrics interface {
+	// QueryStarted is called once a query has been admitted.
+	QueryStarted(name string)
+	// QueryDone is called when an admitted query ends, however it ends.
+	QueryDone(name string, duration time.Duration)
+	// QueryFailed counts a failed query, including those turned away before they started.
+	QueryFailed(name string, code domain.ErrorCode)
+	// ObservePhase records how long a phase of a query took.
+	ObservePhase(name string, phase domain.QueryPhase, duration time.Duration)
+	// FactsFetched counts the log facts read from a source.
+	FactsFetched(source string, count int)
+	// FactsDerived counts the facts a query derived.
+	FactsDerived(name string, count int)
+}
+
+// RelationshipMetrics records reloads of the relationship configuration.
+type RelationshipMetrics interface {
+	// RelationshipsReloaded counts a reload; err is nil if it succeeded.
+	RelationshipsReloaded(err error)
+}
@@ -0,0 +1,12 @@
+package ports
+
+import "mangle-service/internal/core/domain"
+
+// RelationshipStorePort is an interface for persisting the service relationship graph.
+type RelationshipStorePort interface {
+	// Load returns the stored configuration, or nil if nothing has been stored yet.
+	Load() (*domain.RelationshipConfig, error)
+	// Save stores config if the stored version still equals expectedVersion and
+	// returns the stored configuration with its new version.
+	Save(config domain.RelationshipConfig, expectedVersion int64) (*domain.RelationshipConfig, error)
+}
@@ -0,0 +1,8 @@
+package ports
+
+import "mangle-service/internal/core/domain"
+
+// RuleModuleLoaderPort is an interface for reading Mangle rule modules.
+type RuleModuleLoaderPort interface {
+	LoadModules(path string) ([]domain.RuleModuleSource, error)
+}
@@ -8,12 +8,96 @@ import (
 // QueryService defines the port for the core application service.
 type QueryService interface {
 	ExecuteQuery(ctx context.Context, req domain.QueryRequest) (*domain.QueryResult, error)
+	// StreamQuery passes each result to yield as soon as it is available.
+	StreamQuery(ctx context.Context, req domain.QueryRequest, yield func(domain.LogEntry) error) (*domain.QuerySummary, error)
+	// ValidateQuery checks that a query parses, is permitted and passes analysis, without running it.
+	ValidateQuery(ctx context.Context, req domain.QueryRequest) (*domain.QueryValidation, error)
+	// Catalog lists the predicates and log sources the caller may use.
+	Catalog(ctx context.Context) (*domain.PredicateCatalog, error)
+	// Program returns the rules and predicate signatures every query starts from.
+	Program(ctx context.Context) (*domain.ProgramInfo, error)
+	// AuthorizeRelationships checks that the caller may read the predicates derived
+	// from the relationship graph, which the relationship views disclose too.
+	AuthorizeRelationships(ctx context.Context) error
 }
 
 // RelationshipService defines the port for the relationship service.
 type RelationshipService interface {
 	LoadRelationships(path string) error
+	// Reload loads the relationships from the last path again. On failure the previous configuration stays active.
+	Reload() error
+	// Status describes when the configuration was last loaded and whether reloading it failed.
+	Status() domain.RelationshipStatus
 	GetRelationships() []domain.ServiceRelationship
 	GetMangleRulesAsString() (string, error)
 	GetMangleFacts() ([]domain.Fact, error)
+
+	// GetConfig returns a snapshot of the relationship configuration, including its version.
+	GetConfig() domain.RelationshipConfig
+	// Lint checks the active configuration for mistakes such as cycles and unknown services.
+	Lint() []domain.LintIssue
+	// LoadIssues returns the lint issues found when the configuration was last loaded,
+	// with the lines of its source file.
+	LoadIssues() []domain.LintIssue
+	// GetService returns the relationship entry for a single service and the
+	// version of the configuration it was read from.
+	GetService(name string) (domain.ServiceRelationship, int64, error)
+	// PutService creates or replaces a service and its dependencies and returns the new version.
+	PutService(rel domain.ServiceRelationship, expectedVersion int64) (int64, error)
+	// DeleteService removes a service and every edge pointing to it and returns the new version.
+	DeleteService(name string, expectedVersion int64) (int64, error)
+	// AddEdge adds a dependency from one service to another and returns the new version.
+	AddEdge(from, to string, expectedVersion int64) (int64, error)
+	// RemoveEdge removes a dependency from one service to another and returns the new version.
+	RemoveEdge(from, to string, expectedVersion int64) (int64, error)
+}
+
+// GraphService defines the port for building the relationship graph.
+type GraphService interface {
+	GetGraph(ctx context.Context, highlightQuery string) (*domain.Graph, error)
+}
+
+// ImpactService defines the port for blast-radius analysis.
+type ImpactService interface {
+	AnalyzeImpact(ctx context.Context, req domain.ImpactRequest) (*domain.ImpactResult, error)
+}
+
+// RuleModuleService defines the port for shared Mangle rule modules.
+type RuleModuleService interface {
+	// LoadModules loads the modules at path and remembers path for Reload.
+	LoadModules(path string) error
+	// Reload loads the modules again. On failure the previous modules stay active.
+	Reload() error
+	// GetModules describes the active modules.
+	GetModules() domain.RuleModuleSet
+	// GetMangleRules returns the namespaced clauses and declarations of every module.
+	GetMangleRules() domain.RuleSet
+}
+
+// AdmissionService defines the port for inspecting query admission control.
+type AdmissionService interface {
+	// Stats describes the current load and how many queries were rejected.
+	Stats() domain.AdmissionStats
+}
+
+// JobService runs queries in the background for clients that cannot wait for the result.
+type JobService interface {
+	// Submit checks a query and queues it as a job of the caller.
+	Submit(ctx context.Context, req domain.QueryRequest) (*domain.Job, error)
+	// GetJob returns a job of the caller, with its result once it has succeeded.
+	GetJob(ctx context.Context, id string) (*domain.Job, error)
+	// CancelJob cancels an unfinished job of the caller, or discards a finished one.
+	CancelJob(ctx context.Context, id string) (*domain.Job, error)
+}
+
+// HealthService checks whether the service and its dependencies can serve queries.
+type HealthService interface {
+	// Check runs every health check and combines their results.
+	Check(ctx context.Context) *domain.HealthReport
+}
+
+// AuditService searches the record of past query executions.
+type AuditService interface {
+	// Search returns the audit records the filter selects that the caller may see, newest first.
+	Search(ctx context.Context, filter domain.AuditFilter) (*domain.AuditSearchResult, error)
 }
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"mangle-service/internal/core/domain"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/google/mangle/ast"
+)
+
+// restrictedRoles returns the roles of the first rule whose patterns match
+// name, and false if no rule restricts it.
+func restrictedRoles(policy *domain.AccessPolicy, name string, patterns func(domain.AccessRule) []string) ([]string, bool) {
+	if policy == nil {
+		return nil, false
+	}
+	for _, rule := range policy.Rules {
+		for _, pattern := range patterns(rule) {
+			if ok, _ := path.Match(pattern, name); ok {
+				return rule.Roles, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func rulePredicates(rule domain.AccessRule) []string { return rule.Predicates }
+
+func ruleSources(rule domain.AccessRule) []string { return rule.Sources }
+
+// checkPredicates rejects the query if the caller lacks the roles for one of
+// the predicates its clauses define or use. The check runs on the parsed
+// query, before any data is fetched.
+func (s *queryService) checkPredicates(principal *domain.Principal, clauses []ast.Clause) *domain.QueryError {
+	if s.policy == nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	check := func(sym ast.PredicateSym) *domain.QueryError {
+		if seen[sym.Symbol] {
+			return nil
+		}
+		seen[sym.Symbol] = true
+		roles, restricted := restrictedRoles(s.policy, sym.Symbol, rulePredicates)
+		if !restricted || principal.HasAnyRole(roles) {
+			return nil
+		}
+		return forbidden("predicate", sym.Symbol, roles)
+	}
+	for _, clause := range clauses {
+		if qerr := check(clause.Head.Predicate); qerr != nil {
+			return qerr
+		}
+	}
+	for _, sym := range premisePredicates(clauses) {
+		if qerr := check(sym); qerr != nil {
+			return qerr
+		}
+	}
+	return nil
+}
+
+// AuthorizeRelationships refuses callers that the access policy denies one of
+// the relationship predicates. The graph, impact analysis and relationship
+// endpoints show the same edges as these predicates, so they check it first.
+func (s *queryService) AuthorizeRelationships(ctx context.Context) error {
+	principal := domain.PrincipalFromContext(ctx)
+	for _, name := range slices.Sorted(maps.Keys(relationshipPredicates)) {
+		if roles, restricted := restrictedRoles(s.policy, name, rulePredicates); restricted && !principal.HasAnyRole(roles) {
+			return forbidden("predicate", name, roles)
+		}
+	}
+	return nil
+}
+
+// selectSources returns the log sources to read. Without requested sources,
+// these are all sources the caller may use; requested sources must exist and
+// be permitted.
+func (s *queryService) selectSources(principal *domain.Principal, requested []string) ([]string, *domain.QueryError) {
+	allowed := func(source string) ([]string, bool) {
+		roles, restricted := restrictedRoles(s.policy, source, ruleSources)
+		return roles, !restricted || principal.HasAnyRole(roles)
+	}
+	if len(requested) == 0 {
+		var sources []string
+		for _, source := range s.sourceNames {
+			if _, ok := allowed(source); ok {
+				sources = append(sources, source)
+			}
+		}
+		return sources, nil
+	}
+
+	var sources []string
+	for _, source := range requested {
+		if _, ok := s.sources[source]; !ok {
+			return nil, &domain.QueryError{
+				Code:    domain.CodeInvalidQuery,
+				Message: fmt.Sprintf("unknown log source %q (known sources: %s)", source, strings.Join(s.sourceNames, ", ")),
+			}
+		}
+		if roles, ok := allowed(source); !ok {
+			return nil, forbidden("source", source, roles)
+		}
+		if !slices.Contains(sources, source) {
+			sources = append(sources, source)
+		}
+	}
+	return sources, nil
+}
+
+func forbidden(kind, name string, roles []string) *domain.QueryError {
+	return &domain.QueryError{
+		Code:    domain.CodeForbidden,
+		Message: fmt.Sprintf("%s %s requires one of the roles %s", kind, name, strings.Join(roles, ", ")),
+		Details: map[string]any{kind: name, "roles": roles},
+	}
+}
@@ -0,0 +1,202 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"mangle-service/internal/core/domain"
+	"mangle-service/internal/core/ports"
+	"math"
+	"sync"
+	"time"
+)
+
+var _ ports.AdmissionService = (*AdmissionController)(nil)
+
+// overloadedRetryAfter is suggested to clients turned away because every
+// slot is taken; queries usually finish within seconds.
+const overloadedRetryAfter = time.Second
+
+// bucketSweepInterval is how often rate limit state of idle clients is dropped.
+const bucketSweepInterval = time.Minute
+
+// AdmissionController decides whether a query may run. Every client has a
+// token bucket that refills at ClientRate; a global semaphore bounds the
+// queries evaluated at once, and a bounded queue holds those waiting for it.
+type AdmissionController struct {
+	config domain.AdmissionConfig
+	slots  chan struct{}
+	now    func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+	queued    int
+	stats     domain.AdmissionStats
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewAdmissionController creates an AdmissionController with the given limits.
+func NewAdmissionController(config domain.AdmissionConfig) *AdmissionController {
+	c := &AdmissionController{
+		config:  config,
+		now:     time.Now,
+		buckets: make(map[string]*tokenBucket),
+	}
+	if config.MaxConcurrent > 0 {
+		c.slots = make(chan struct{}, config.MaxConcurrent)
+	}
+	if c.config.ClientBurst < 1 {
+		c.config.ClientBurst = 1
+	}
+	return c
+}
+
+// Admit waits until the query of the client in ctx may run and returns a
+// function that must be called when it is done. Rejections are reported as
+// *domain.QueryError with RetryAfter set.
+func (c *AdmissionController) Admit(ctx context.Context) (func(), error) {
+	if qerr := c.takeToken(clientKey(ctx)); qerr != nil {
+		return nil, qerr
+	}
+	if c.slots == nil {
+		c.count(func(s *domain.AdmissionStats) { s.Admitted++ })
+		return func() {}, nil
+	}
+
+	select {
+	case c.slots <- struct{}{}:
+	default:
+		if err := c.wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+	c.count(func(s *domain.AdmissionStats) { s.Admitted++ })
+	var once sync.Once
+	return func() { once.Do(func() { <-c.slots }) }, nil
+}
+
+// wait queues for a slot until one frees up, the queue timeout passes or ctx
+// is done. It returns ctx.Err() in the latter case.
+func (c *AdmissionController) wait(ctx context.Context) error {
+	c.mu.Lock()
+	if c.queued >= c.config.MaxQueue {
+		c.stats.QueueFull++
+		c.mu.Unlock()
+		return overloaded("queue_full", "too many queries are running; try again later")
+	}
+	c.queued++
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.queued--
+		c.mu.Unlock()
+	}()
+
+	var timeout <-chan time.Time
+	if c.config.QueueTimeout > 0 {
+		timer := time.NewTimer(c.config.QueueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case c.slots <- struct{}{}:
+		return nil
+	case <-timeout:
+		c.count(func(s *domain.AdmissionStats) { s.QueueTimeouts++ })
+		return overloaded("queue_timeout", fmt.Sprintf("no query slot became free within %s; try again later", c.config.QueueTimeout))
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// takeToken takes a token from the client's bucket, refilling it first.
+func (c *AdmissionController) takeToken(client string) *domain.QueryError {
+	if c.config.ClientRate <= 0 {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	c.sweep(now)
+
+	burst := float64(c.config.ClientBurst)
+	bucket, ok := c.buckets[client]
+	if !ok {
+		bucket = &tokenBucket{tokens: burst, last: now}
+		c.buckets[client] = bucket
+	}
+	bucket.tokens = math.Min(burst, bucket.tokens+now.Sub(bucket.last).Seconds()*c.config.ClientRate)
+	bucket.last = now
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return nil
+	}
+
+	c.stats.RateLimited++
+	wait := time.Duration((1 - bucket.tokens) / c.config.ClientRate * float64(time.Second))
+	return &domain.QueryError{
+		Code:       domain.CodeRateLimited,
+		Message:    fmt.Sprintf("rate limit of %g queries per second exceeded", c.config.ClientRate),
+		Details:    map[string]any{"rate": c.config.ClientRate, "burst": c.config.ClientBurst},
+		RetryAfter: wait,
+	}
+}
+
+// sweep drops the buckets of clients that have been idle long enough for
+// their bucket to be full again, which is the state a new bucket starts in.
+func (c *AdmissionController) sweep(now time.Time) {
+	if now.Sub(c.lastSweep) < bucketSweepInterval {
+		return
+	}
+	c.lastSweep = now
+	refill := time.Duration(float64(c.config.ClientBurst) / c.config.ClientRate * float64(time.Second))
+	for client, bucket := range c.buckets {
+		if now.Sub(bucket.last) > refill {
+			delete(c.buckets, client)
+		}
+	}
+}
+
+func (c *AdmissionController) count(update func(*domain.AdmissionStats)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	update(&c.stats)
+}
+
+// Stats describes the current load and the rejections so far.
+func (c *AdmissionController) Stats() domain.AdmissionStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.MaxConcurrent = c.config.MaxConcurrent
+	stats.MaxQueue = c.config.MaxQueue
+	stats.Running = len(c.slots)
+	stats.Queued = c.queued
+	stats.Clients = len(c.buckets)
+	return stats
+}
+
+// clientKey identifies the client for rate limiting: the authenticated
+// subject if there is one, else the network address.
+func clientKey(ctx context.Context) string {
+	if principal := domain.PrincipalFromContext(ctx); principal != nil {
+		return "subject:" + principal.Subject
+	}
+	if addr := domain.ClientAddressFromContext(ctx); addr != "" {
+		return "address:" + addr
+	}
+	return "anonymous"
+}
+
+func overloaded(reason, message string) *domain.QueryError {
+	return &domain.QueryError{
+		Code:       domain.CodeOverloaded,
+		Message:    message,
+		Details:    map[string]any{"reason": reason},
+		RetryAfter: overloadedRetryAfter,
+	}
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"mangle-service/internal/core/domain"
+	"mangle-service/internal/core/ports"
+	"time"
+)
+
+// WithAuditSink writes a record of every query execution to sink,
+// whether the query succeeds or not.
+func WithAuditSink(sink ports.AuditSink) QueryOption {
+	return func(s *queryService) {
+		s.audit = sink
+	}
+}
+
+// execution collects what a single query run read and returned.
+type execution struct {
+	start     time.Time
+	sources   []string
+	factsRead int
+	results   int
+	profile   domain.QueryProfile
+}
+
+// writeAudit records the execution of req. A record that cannot be written
+// is logged; the query's result stands.
+func (s *queryService) writeAudit(ctx context.Context, req domain.QueryRequest, exec *execution, err error) {
+	if s.audit == nil {
+		return
+	}
+	record := domain.AuditRecord{
+		ID:            newAuditID(),
+		Time:          exec.start,
+		DurationMS:    time.Since(exec.start).Milliseconds(),
+		RequestID:     domain.RequestIDFromContext(ctx),
+		Channel:       domain.ChannelFromContext(ctx),
+		ClientAddress: domain.ClientAddressFromContext(ctx),
+		Name:          req.Name,
+		Query:         req.Query,
+		QueryHash:     req.Hash(),
+		TimeRange:     req.TimeRange,
+		Sources:       exec.sources,
+		FactsRead:     exec.factsRead,
+		Results:       exec.results,
+		Outcome:       domain.AuditSucceeded,
+	}
+	if principal := domain.PrincipalFromContext(ctx); principal != nil {
+		record.Subject = principal.Subject
+		record.AuthMethod = principal.Method
+	}
+	if record.Sources == nil {
+		record.Sources = append([]string{}, req.Sources...)
+	}
+	if err != nil {
+		record.Outcome = domain.AuditFailed
+		var qerr *domain.QueryError
+		if errors.As(err, &qerr) {
+			record.ErrorCode = qerr.Code
+		}
+	}
+	// The record is written even if the caller has gone away.
+	if err := s.audit.Write(context.WithoutCancel(ctx), record); err != nil {
+		s.logger.ErrorContext(ctx, "failed to write audit record", "error", err)
+	}
+}
+
+func newAuditID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
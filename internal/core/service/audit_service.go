@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"mangle-service/internal/core/domain"
+	"mangle-service/internal/core/ports"
+)
+
+// AuditService searches the audit log written by the query service.
+type AuditService struct {
+	sink   ports.AuditSink
+	config domain.AuditConfig
+}
+
+var _ ports.AuditService = (*AuditService)(nil)
+
+// NewAuditService creates an AuditService that searches sink.
+func NewAuditService(sink ports.AuditSink, config domain.AuditConfig) *AuditService {
+	return &AuditService{sink: sink, config: config}
+}
+
+// Search returns the records the filter selects. Callers without one of the
+// configured reader roles only see their own queries, whatever subject the
+// filter names, and anonymous callers are refused since records carry the
+// query text. The limit defaults to domain.DefaultAuditLimit and is capped
+// at domain.MaxAuditLimit.
+func (s *AuditService) Search(ctx context.Context, filter domain.AuditFilter) (*domain.AuditSearchResult, error) {
+	principal := domain.PrincipalFromContext(ctx)
+	if principal == nil {
+		return nil, &domain.QueryError{Code: domain.CodeForbidden, Message: "searching the audit log requires authentication"}
+	}
+	if !principal.HasAnyRole(s.config.ReaderRoles) {
+		filter.Subject = principal.Subject
+	}
+	switch {
+	case filter.Limit <= 0:
+		filter.Limit = domain.DefaultAuditLimit
+	case filter.Limit > domain.MaxAuditLimit:
+		filter.Limit = domain.MaxAuditLimit
+	}
+	records, err := s.sink.Search(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search the audit log: %w", err)
+	}
+	if records == nil {
+		records = []domain.AuditRecord{}
+	}
+	return &domain.AuditSearchResult{Records: records, Count: len(records)}, nil
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"mangle-service/internal/core/domain"
+	"mangle-service/internal/core/ports"
+	"slices"
+	"strings"
+
+	"github.com/google/mangle/ast"
+)
+
+// relationshipPredicates documents the predicates derived from the
+// relationship graph; see RelationshipService.GetMangleFacts and
+// GetMangleRulesAsString.
+var relationshipPredicates = map[string]domain.PredicateInfo{
+	"calls": {
+		Args:        []string{"Service", "Dependency"},
+		Description: "Service calls Dependency directly, as declared in the relationship configuration.",
+	},
+	"depends_on": {
+		Args:        []string{"Upstream", "Downstream"},
+		Description: "Upstream calls Downstream directly or through other services.",
+	},
+}
+
+// originOrder sorts the catalog by where predicates come from.
+var originOrder = []string{domain.OriginLogs, domain.OriginRelationships, domain.OriginModule}
+
+// Catalog lists the predicates of the caller's log sources, of the
+// relationship graph and of the rule modules. Predicates and sources the
+// access policy denies the caller are left out.
+func (s *queryService) Catalog(ctx context.Context) (*domain.PredicateCatalog, error) {
+	principal := domain.PrincipalFromContext(ctx)
+	sources, qerr := s.selectSources(principal, nil)
+	if qerr != nil {
+		return nil, qerr
+	}
+	catalog := &domain.PredicateCatalog{
+		Predicates: []domain.PredicateInfo{},
+		Sources:    append([]string{}, sources...),
+	}
+	seen := make(map[string]bool)
+	add := func(info domain.PredicateInfo) {
+		key := predicateKey(info.Name, info.Arity)
+		if seen[key] {
+			return
+		}
+		if roles, restricted := restrictedRoles(s.policy, info.Name, rulePredicates); restricted && !principal.HasAnyRole(roles) {
+			return
+		}
+		seen[key] = true
+		catalog.Predicates = append(catalog.Predicates, info)
+	}
+
+	for _, source := range sources {
+		describer, ok := s.sources[source].(ports.PredicateDescriber)
+		if !ok {
+			continue
+		}
+		for _, info := range describer.Predicates() {
+			info.Origin = domain.OriginLogs
+			info.Source = source
+			add(info)
+		}
+	}
+
+	relationshipFacts, err := s.relationshipService.GetMangleFacts()
+	if err != nil {
+		return nil, &domain.QueryError{Code: domain.CodeInternal, Message: "failed to get relationship facts", Err: err}
+	}
+	relationshipRules, err := s.relationshipRules()
+	if err != nil {
+		return nil, err
+	}
+	relationshipAtoms := make([]ast.Atom, 0, len(relationshipFacts)+len(relationshipRules))
+	relationshipAtoms = append(relationshipAtoms, relationshipFacts...)
+	for _, clause := range relationshipRules {
+		relationshipAtoms = append(relationshipAtoms, clause.Head)
+	}
+	for _, atom := range relationshipAtoms {
+		info := relationshipPredicates[atom.Predicate.Symbol]
+		info.Name = atom.Predicate.Symbol
+		info.Arity = atom.Predicate.Arity
+		info.Origin = domain.OriginRelationships
+		if len(info.Args) != info.Arity {
+			info.Args = argNames(atom)
+		}
+		add(info)
+	}
+
+	if s.ruleModules != nil {
+		modules := make(map[string]string)
+		for _, module := range s.ruleModules.GetModules().Modules {
+			for _, predicate := range module.Predicates {
+				modules[predicate] = module.Name
+			}
+		}
+		rules := s.ruleModules.GetMangleRules()
+		// Declarations come first, as they name the arguments and may
+		// document the predicate.
+		for _, decl := range rules.Decls {
+			add(moduleInfo(modules, decl.DeclaredAtom, docString(decl)))
+		}
+		for _, clause := range rules.Clauses {
+			add(moduleInfo(modules, clause.Head, ""))
+		}
+		catalog.Predicates = slices.DeleteFunc(catalog.Predicates, func(info domain.PredicateInfo) bool {
+			return info.Origin == domain.OriginModule && info.Source == ""
+		})
+	}
+
+	slices.SortStableFunc(catalog.Predicates, func(a, b domain.PredicateInfo) int {
+		return cmp.Or(
+			cmp.Compare(slices.Index(originOrder, a.Origin), slices.Index(originOrder, b.Origin)),
+			cmp.Compare(a.Name, b.Name),
+			cmp.Compare(a.Arity, b.Arity),
+		)
+	})
+	return catalog, nil
+}
+
+// moduleInfo describes a predicate defined by a rule module. Source is empty
+// if no module defines it, as for the declarations of packages and uses.
+func moduleInfo(modules map[string]string, head ast.Atom, description string) domain.PredicateInfo {
+	return domain.PredicateInfo{
+		Name:        head.Predicate.Symbol,
+		Arity:       head.Predicate.Arity,
+		Args:        argNames(head),
+		Origin:      domain.OriginModule,
+		Source:      modules[predicateKey(head.Predicate.Symbol, head.Predicate.Arity)],
+		Description: description,
+	}
+}
+
+// argNames returns the variable names of the atom's arguments, or nil unless
+// every argument is a named variable.
+func argNames(atom ast.Atom) []string {
+	names := make([]string, 0, len(atom.Args))
+	for _, arg := range atom.Args {
+		v, ok := arg.(ast.Variable)
+		if !ok || v.Symbol == "_" {
+			return nil
+		}
+		names = append(names, v.Symbol)
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	return names
+}
+
+// docString returns the text of a declaration's doc descriptor, if it has one.
+func docString(decl ast.Decl) string {
+	for _, descr := range decl.Descr {
+		if descr.Predicate.Symbol != "doc" {
+			continue
+		}
+		var parts []string
+		for _, arg := range descr.Args {
+			parts = append(parts, strings.Trim(arg.String(), `"`))
+		}
+		return strings.Join(parts, " ")
+	}
+	return ""
+}
+
+func predicateKey(name string, arity int) string {
+	return fmt.Sprintf("%s/%d", name, arity)
+}
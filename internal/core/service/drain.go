@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// errShuttingDown is the cause of the cancellation of queries still running
+// when the drain deadline passes.
+var errShuttingDown = errors.New("the service is shutting down")
+
+// cancelGrace bounds the wait for cancelled queries to return, so that a
+// fetch that ignores its context cannot hold up the shutdown.
+const cancelGrace = 5 * time.Second
+
+// DrainController shuts the query path down in order. Shutdown makes the
+// service unready so that load balancers stop sending it queries; Drain
+// then refuses new queries, waits for those in flight and cancels the ones
+// still running at its deadline.
+type DrainController struct {
+	mu       sync.Mutex
+	stopping bool
+	draining bool
+	next     int
+	inFlight map[int]context.CancelCauseFunc
+	idle     chan struct{}
+}
+
+// NewDrainController creates a DrainController for a running service.
+func NewDrainController() *DrainController {
+	return &DrainController{inFlight: make(map[int]context.CancelCauseFunc)}
+}
+
+// Begin registers a query that is about to run. The query must run with
+// the returned context, which is cancelled if the query outlives the drain,
+// and call done when it has returned. Once draining has started, Begin
+// refuses the query with a *domain.QueryError.
+func (d *DrainController) Begin(ctx context.Context) (context.Context, func(), error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.draining {
+		return nil, nil, overloaded("shutting_down", "the service is shutting down")
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	id := d.next
+	d.next++
+	d.inFlight[id] = cancel
+	var once sync.Once
+	return ctx, func() { once.Do(func() { d.end(id) }) }, nil
+}
+
+func (d *DrainController) end(id int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.inFlight[id](nil)
+	delete(d.inFlight, id)
+	if len(d.inFlight) == 0 && d.idle != nil {
+		close(d.idle)
+		d.idle = nil
+	}
+}
+
+// Shutdown marks the service as shutting down. Queries are still accepted
+// until Drain is called.
+func (d *DrainController) Shutdown() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stopping = true
+}
+
+// ShuttingDown reports whether Shutdown or Drain has been called.
+func (d *DrainController) ShuttingDown() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.stopping
+}
+
+// Draining reports whether new queries are refused.
+func (d *DrainController) Draining() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.draining
+}
+
+// InFlight returns the number of queries running.
+func (d *DrainController) InFlight() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.inFlight)
+}
+
+// Drain refuses new queries and waits for those in flight to return. When
+// ctx is done, the queries still running are cancelled and Drain waits up to
+// cancelGrace for them to stop; it returns how many were cancelled.
+func (d *DrainController) Drain(ctx context.Context) int {
+	d.mu.Lock()
+	d.stopping = true
+	d.draining = true
+	if len(d.inFlight) == 0 {
+		d.mu.Unlock()
+		return 0
+	}
+	if d.idle == nil {
+		d.idle = make(chan struct{})
+	}
+	idle := d.idle
+	d.mu.Unlock()
+
+	select {
+	case <-idle:
+		return 0
+	case <-ctx.Done():
+	}
+	d.mu.Lock()
+	canceled := len(d.inFlight)
+	for _, cancel := range d.inFlight {
+		cancel(errShuttingDown)
+	}
+	d.mu.Unlock()
+	// Evaluation and fetches watch their context, so the queries return
+	// promptly once cancelled.
+	grace := time.NewTimer(cancelGrace)
+	defer grace.Stop()
+	select {
+	case <-idle:
+	case <-grace.C:
+	}
+	return canceled
+}
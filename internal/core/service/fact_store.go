@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+
+	"github.com/google/mangle/ast"
+	"github.com/google/mangle/factstore"
+)
+
+// guardedStore is a fact store that lets the query service stop an
+// evaluation when its context is done, which the Mangle engine has no hook
+// for. Once stopped, the store pretends to be empty and refuses new facts, so
+// the engine reaches a fixpoint quickly. Callers check the context after the
+// evaluation returns.
+//
+// The store also profiles the evaluation. The engine reads the store while it
+// evaluates the rules of an iteration and adds the new facts at its end, so
+// every run of additions after a read is an iteration.
+type guardedStore struct {
+	factstore.FactStore
+	ctx      context.Context
+	counting bool
+	derived  int
+	// derivedBy counts the derived facts of each predicate.
+	derivedBy map[ast.PredicateSym]int
+	// iterations holds the predicate of the first fact each iteration added.
+	iterations []ast.PredicateSym
+	read       bool
+}
+
+func newGuardedStore(ctx context.Context, store factstore.FactStore) *guardedStore {
+	return &guardedStore{FactStore: store, ctx: ctx, derivedBy: make(map[ast.PredicateSym]int)}
+}
+
+// startCounting makes every fact added from now on count as derived.
+func (s *guardedStore) startCounting() {
+	s.counting = true
+}
+
+func (s *guardedStore) stopped() bool {
+	return s.ctx.Err() != nil
+}
+
+func (s *guardedStore) Add(atom ast.Atom) bool {
+	if s.stopped() {
+		return false
+	}
+	if !s.FactStore.Add(atom) {
+		return false
+	}
+	if s.counting {
+		s.derived++
+		s.derivedBy[atom.Predicate]++
+		if s.read || len(s.iterations) == 0 {
+			s.iterations = append(s.iterations, atom.Predicate)
+			s.read = false
+		}
+	}
+	return true
+}
+
+func (s *guardedStore) GetFacts(query ast.Atom, fn func(ast.Atom) error) error {
+	s.read = true
+	if s.stopped() {
+		return nil
+	}
+	return s.FactStore.GetFacts(query, fn)
+}
+
+// Contains reports every fact as present once stopped, so that the engine
+// does not consider anything new.
+func (s *guardedStore) Contains(atom ast.Atom) bool {
+	s.read = true
+	if s.stopped() {
+		return true
+	}
+	return s.FactStore.Contains(atom)
+}
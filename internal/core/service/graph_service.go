@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"mangle-service/internal/core/domain"
+	"mangle-service/internal/core/ports"
+)
+
+var _ ports.GraphService = (*GraphService)(nil)
+
+// GraphService builds the relationship graph for visualisation.
+type GraphService struct {
+	relationships ports.RelationshipService
+	queries       ports.QueryService
+}
+
+// NewGraphService creates a new GraphService.
+func NewGraphService(relationships ports.RelationshipService, queries ports.QueryService) *GraphService {
+	return &GraphService{
+		relationships: relationships,
+		queries:       queries,
+	}
+}
+
+// GetGraph returns the relationship graph. If highlightQuery is not empty, it
+// is executed and every service that appears in a result is highlighted, as is
+// every edge whose two services appear in the same result.
+func (s *GraphService) GetGraph(ctx context.Context, highlightQuery string) (*domain.Graph, error) {
+	graph := BuildRelationshipGraph(s.relationships.GetRelationships())
+	if highlightQuery == "" {
+		return graph, nil
+	}
+
+	result, err := s.queries.ExecuteQuery(ctx, domain.QueryRequest{Query: highlightQuery})
+	if err != nil {
+		return nil, err
+	}
+	HighlightGraph(graph, result)
+	return graph, nil
+}
+
+// BuildRelationshipGraph turns relationships into a graph. Nodes and edges are
+// in order of first appearance; dependencies that are not declared as services
+// become nodes too.
+func BuildRelationshipGraph(rels []domain.ServiceRelationship) *domain.Graph {
+	graph := &domain.Graph{Nodes: []domain.GraphNode{}, Edges: []domain.GraphEdge{}}
+	seen := make(map[string]bool)
+	addNode := func(id string) {
+		if !seen[id] {
+			seen[id] = true
+			graph.Nodes = append(graph.Nodes, domain.GraphNode{ID: id})
+		}
+	}
+	for _, rel := range rels {
+		addNode(rel.Service)
+	}
+	for _, rel := range rels {
+		for _, dep := range rel.DependsOn {
+			addNode(dep)
+			graph.Edges = append(graph.Edges, domain.GraphEdge{From: rel.Service, To: dep})
+		}
+	}
+	return graph
+}
+
+// HighlightGraph marks the nodes and edges of graph that appear in result.
+func HighlightGraph(graph *domain.Graph, result *domain.QueryResult) {
+	var rows []map[string]bool
+	for _, entry := range result.Results {
+		row := make(map[string]bool)
+		for _, v := range entry {
+			if s, ok := v.(string); ok {
+				row[s] = true
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	for i := range graph.Nodes {
+		for _, row := range rows {
+			if row[graph.Nodes[i].ID] {
+				graph.Nodes[i].Highlighted = true
+				break
+			}
+		}
+	}
+	for i := range graph.Edges {
+		for _, row := range rows {
+			if row[graph.Edges[i].From] && row[graph.Edges[i].To] {
+				graph.Edges[i].Highlighted = true
+				break
+			}
+		}
+	}
+}
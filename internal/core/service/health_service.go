@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"mangle-service/internal/core/domain"
+	"mangle-service/internal/core/ports"
+	"sync"
+	"time"
+)
+
+var _ ports.HealthService = (*HealthService)(nil)
+
+// defaultHealthCheckTimeout bounds each check if the configuration does not.
+const defaultHealthCheckTimeout = 2 * time.Second
+
+// HealthService checks the log sources, the relationship configuration and
+// the query queue. The service is ready unless one of the checks fails.
+type HealthService struct {
+	relationships ports.RelationshipService
+	config        domain.HealthConfig
+	sources       []namedSource
+	admission     ports.AdmissionService
+	drain         *DrainController
+	now           func() time.Time
+}
+
+type namedSource struct {
+	name   string
+	source ports.LogDataPort
+}
+
+// HealthOption configures optional checks of the HealthService.
+type HealthOption func(*HealthService)
+
+// WithSourceCheck checks the log source with the given name. Sources whose
+// adapter does not implement ports.HealthChecker always pass.
+func WithSourceCheck(name string, source ports.LogDataPort) HealthOption {
+	return func(s *HealthService) {
+		s.sources = append(s.sources, namedSource{name: name, source: source})
+	}
+}
+
+// WithQueueCheck fails while every query slot is taken and the wait queue is
+// full, so that new queries would be turned away.
+func WithQueueCheck(admission ports.AdmissionService) HealthOption {
+	return func(s *HealthService) {
+		s.admission = admission
+	}
+}
+
+// WithShutdownCheck fails once the service starts shutting down, so that
+// load balancers stop sending it queries before it refuses them.
+func WithShutdownCheck(controller *DrainController) HealthOption {
+	return func(s *HealthService) {
+		s.drain = controller
+	}
+}
+
+// NewHealthService creates a HealthService that checks the relationship
+// configuration and whatever the options add.
+func NewHealthService(relationships ports.RelationshipService, config domain.HealthConfig, opts ...HealthOption) *HealthService {
+	if config.Timeout <= 0 {
+		config.Timeout = defaultHealthCheckTimeout
+	}
+	s := &HealthService{
+		relationships: relationships,
+		config:        config,
+		now:           time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Check runs every check concurrently and combines their results.
+func (s *HealthService) Check(ctx context.Context) *domain.HealthReport {
+	checks := []func(context.Context) domain.HealthCheck{s.checkRelationships}
+	for _, source := range s.sources {
+		checks = append(checks, func(ctx context.Context) domain.HealthCheck {
+			return s.checkSource(ctx, source)
+		})
+	}
+	if s.admission != nil {
+		checks = append(checks, s.checkQueue)
+	}
+	if s.drain != nil {
+		checks = append(checks, s.checkShutdown)
+	}
+
+	report := &domain.HealthReport{
+		Status:    domain.HealthPass,
+		CheckedAt: s.now().UTC(),
+		Checks:    make([]domain.HealthCheck, len(checks)),
+	}
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+			defer cancel()
+			start := time.Now()
+			report.Checks[i] = check(ctx)
+			report.Checks[i].DurationMS = time.Since(start).Milliseconds()
+		}()
+	}
+	wg.Wait()
+
+	for _, check := range report.Checks {
+		if healthRank(check.Status) > healthRank(report.Status) {
+			report.Status = check.Status
+		}
+	}
+	return report
+}
+
+func (s *HealthService) checkSource(ctx context.Context, source namedSource) domain.HealthCheck {
+	check := domain.HealthCheck{
+		Name:   domain.HealthCheckLogSource + ":" + source.name,
+		Kind:   domain.HealthCheckLogSource,
+		Status: domain.HealthPass,
+	}
+	checker, ok := source.source.(ports.HealthChecker)
+	if !ok {
+		check.Message = "the log source cannot be checked"
+		return check
+	}
+	if err := checker.CheckHealth(ctx); err != nil {
+		check.Status = domain.HealthFail
+		check.Message = err.Error()
+	}
+	return check
+}
+
+// checkRelationships fails if the relationships are not loaded or the last
+// reload failed, since queries would then see an outdated graph.
+func (s *HealthService) checkRelationships(context.Context) domain.HealthCheck {
+	check := domain.HealthCheck{
+		Name:   domain.HealthCheckRelationships,
+		Kind:   domain.HealthCheckRelationships,
+		Status: domain.HealthPass,
+	}
+	status := s.relationships.Status()
+	if !status.Loaded {
+		check.Status = domain.HealthFail
+		check.Message = "the relationships are not loaded"
+		return check
+	}
+	check.Details = map[string]any{
+		"version":     status.Version,
+		"updated_at":  status.UpdatedAt.UTC(),
+		"age_seconds": int64(s.now().Sub(status.UpdatedAt).Seconds()),
+	}
+	if status.ReloadError != "" {
+		check.Status = domain.HealthFail
+		check.Message = fmt.Sprintf("reloading the relationships failed, version %d is still active: %s", status.Version, status.ReloadError)
+		check.Details["reload_failed_at"] = status.ReloadFailedAt.UTC()
+	}
+	return check
+}
+
+func (s *HealthService) checkQueue(context.Context) domain.HealthCheck {
+	stats := s.admission.Stats()
+	check := domain.HealthCheck{
+		Name:   domain.HealthCheckQueryQueue,
+		Kind:   domain.HealthCheckQueryQueue,
+		Status: domain.HealthPass,
+		Details: map[string]any{
+			"running":        stats.Running,
+			"max_concurrent": stats.MaxConcurrent,
+			"queued":         stats.Queued,
+			"max_queue":      stats.MaxQueue,
+		},
+	}
+	switch {
+	case stats.MaxConcurrent > 0 && stats.Running >= stats.MaxConcurrent && stats.Queued >= stats.MaxQueue:
+		check.Status = domain.HealthFail
+		check.Message = "every query slot is taken and the queue is full"
+	case stats.Queued > 0:
+		check.Status = domain.HealthWarn
+		check.Message = fmt.Sprintf("%d queries are waiting for a slot", stats.Queued)
+	}
+	return check
+}
+
+func (s *HealthService) checkShutdown(context.Context) domain.HealthCheck {
+	check := domain.HealthCheck{
+		Name:    domain.HealthCheckShutdown,
+		Kind:    domain.HealthCheckShutdown,
+		Status:  domain.HealthPass,
+		Details: map[string]any{"in_flight": s.drain.InFlight()},
+	}
+	if s.drain.ShuttingDown() {
+		check.Status = domain.HealthFail
+		check.Message = "the service is shutting down"
+	}
+	return check
+}
+
+func healthRank(status domain.HealthStatus) int {
+	switch status {
+	case domain.HealthPass:
+		return 0
+	case domain.HealthWarn:
+		return 1
+	default:
+		return 2
+	}
+}
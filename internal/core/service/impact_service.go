@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"mangle-service/internal/core/domain"
+	"mangle-service/internal/core/ports"
+	"slices"
+	"sort"
+)
+
+// maxPathsPerService caps the number of shortest paths reported per impacted
+// service, since densely connected graphs can have very many of them.
+const maxPathsPerService = 10
+
+var _ ports.ImpactService = (*ImpactService)(nil)
+
+// ImpactService computes the blast radius of a failing service: every
+// upstream service that transitively depends on it.
+type ImpactService struct {
+	relationships ports.RelationshipService
+	queries       ports.QueryService
+}
+
+// NewImpactService creates a new ImpactService.
+func NewImpactService(relationships ports.RelationshipService, queries ports.QueryService) *ImpactService {
+	return &ImpactService{
+		relationships: relationships,
+		queries:       queries,
+	}
+}
+
+// AnalyzeImpact walks the relationship graph upstream from the failing service.
+// Impacted services are ordered by hop distance, then by criticality, then by name.
+func (s *ImpactService) AnalyzeImpact(ctx context.Context, req domain.ImpactRequest) (*domain.ImpactResult, error) {
+	dependents := make(map[string][]string)
+	criticality := make(map[string]string)
+	known := make(map[string]bool)
+	for _, rel := range s.relationships.GetRelationships() {
+		known[rel.Service] = true
+		criticality[rel.Service] = rel.Criticality
+		for _, dep := range rel.DependsOn {
+			known[dep] = true
+			if !slices.Contains(dependents[dep], rel.Service) {
+				dependents[dep] = append(dependents[dep], rel.Service)
+			}
+		}
+	}
+	if !known[req.Service] {
+		return nil, fmt.Errorf("%w: %q", domain.ErrServiceNotFound, req.Service)
+	}
+
+	// Breadth-first search over the reversed graph. For every service, next
+	// holds its neighbours one hop closer to the failing service, which are
+	// exactly the first steps of its shortest paths.
+	distance := map[string]int{req.Service: 0}
+	next := make(map[string][]string)
+	frontier := []string{req.Service}
+	for depth := 1; len(frontier) > 0 && (req.MaxDepth <= 0 || depth <= req.MaxDepth); depth++ {
+		var nextFrontier []string
+		for _, v := range frontier {
+			for _, u := range dependents[v] {
+				if _, seen := distance[u]; !seen {
+					distance[u] = depth
+					nextFrontier = append(nextFrontier, u)
+				}
+				if distance[u] == depth {
+					next[u] = append(next[u], v)
+				}
+			}
+		}
+		frontier = nextFrontier
+	}
+
+	var erroring map[string]bool
+	if req.ErrorsQuery != "" {
+		var err error
+		erroring, err = s.erroringServices(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	memo := make(map[string][][]string)
+	impacted := []domain.ImpactedService{}
+	for service, d := range distance {
+		if service == req.Service || (erroring != nil && !erroring[service]) {
+			continue
+		}
+		impacted = append(impacted, domain.ImpactedService{
+			Service:     service,
+			Distance:    d,
+			Criticality: criticality[service],
+			Paths:       shortestPaths(service, req.Service, next, memo),
+		})
+	}
+	sort.Slice(impacted, func(i, j int) bool {
+		a, b := impacted[i], impacted[j]
+		if a.Distance != b.Distance {
+			return a.Distance < b.Distance
+		}
+		if ra, rb := domain.CriticalityRank(a.Criticality), domain.CriticalityRank(b.Criticality); ra != rb {
+			return ra < rb
+		}
+		return a.Service < b.Service
+	})
+
+	return &domain.ImpactResult{
+		Service:  req.Service,
+		Impacted: impacted,
+		Count:    len(impacted),
+	}, nil
+}
+
+// erroringServices runs the errors query and collects the services it returns.
+func (s *ImpactService) erroringServices(ctx context.Context, req domain.ImpactRequest) (map[string]bool, error) {
+	result, err := s.queries.ExecuteQuery(ctx, domain.QueryRequest{Query: req.ErrorsQuery, TimeRange: req.TimeRange})
+	if err != nil {
+		return nil, fmt.Errorf("failed to run errors query: %w", err)
+	}
+	erroring := make(map[string]bool)
+	for _, row := range result.Results {
+		if service, ok := row["Service"].(string); ok {
+			erroring[service] = true
+			continue
+		}
+		for _, v := range row {
+			if value, ok := v.(string); ok {
+				erroring[value] = true
+			}
+		}
+	}
+	return erroring, nil
+}
+
+// shortestPaths enumerates up to maxPathsPerService shortest paths from
+// service to target by following next.
+func shortestPaths(service, target string, next map[string][]string, memo map[string][][]string) [][]string {
+	if service == target {
+		return [][]string{{target}}
+	}
+	if paths, ok := memo[service]; ok {
+		return paths
+	}
+	var paths [][]string
+	for _, v := range next[service] {
+		for _, tail := range shortestPaths(v, target, next, memo) {
+			if len(paths) == maxPathsPerService {
+				break
+			}
+			paths = append(paths, append([]string{service}, tail...))
+		}
+	}
+	memo[service] = paths
+	return paths
+}
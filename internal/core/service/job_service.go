@@ -0,0 +1,291 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"mangle-service/internal/core/domain"
+	"mangle-service/internal/core/ports"
+	"sync"
+	"time"
+)
+
+var _ ports.JobService = (*JobService)(nil)
+
+// jobSweepInterval is how often expired jobs are dropped.
+const jobSweepInterval = time.Minute
+
+// JobService runs queries in the background on a fixed number of workers.
+// Jobs are cancelled through their context, like queries whose client has
+// gone away, and finished jobs are kept until their result expires.
+type JobService struct {
+	queries ports.QueryService
+	logger  *slog.Logger
+	config  domain.JobConfig
+	queue   chan *job
+	drain   *DrainController
+	now     func() time.Time
+	workers sync.WaitGroup
+
+	mu        sync.Mutex
+	jobs      map[string]*job
+	lastSweep time.Time
+	closed    bool
+}
+
+// job is the state of a single job. Its fields are guarded by JobService.mu.
+type job struct {
+	domain.Job
+	// owner is the subject of the caller that submitted the job, or empty
+	// if authentication is disabled.
+	owner  string
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// JobOption configures optional parts of the JobService.
+type JobOption func(*JobService)
+
+// WithJobDrainController refuses new jobs once the controller drains. The
+// jobs' queries should be registered with the same controller.
+func WithJobDrainController(controller *DrainController) JobOption {
+	return func(s *JobService) {
+		s.drain = controller
+	}
+}
+
+// NewJobService creates a JobService and starts its workers. queries should
+// have a timeout suited to long-running investigations.
+func NewJobService(queries ports.QueryService, logger *slog.Logger, config domain.JobConfig, opts ...JobOption) *JobService {
+	if config.Workers < 1 {
+		config.Workers = 1
+	}
+	s := &JobService{
+		queries: queries,
+		logger:  logger,
+		config:  config,
+		queue:   make(chan *job, config.MaxQueued),
+		now:     time.Now,
+		jobs:    make(map[string]*job),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.workers.Add(config.Workers)
+	for range config.Workers {
+		go func() {
+			defer s.workers.Done()
+			for j := range s.queue {
+				s.run(j)
+			}
+		}()
+	}
+	return s
+}
+
+// Submit checks the query as a synchronous query would be checked and
+// queues it. Queries that cannot run are rejected here rather than failing
+// as a job.
+func (s *JobService) Submit(ctx context.Context, req domain.QueryRequest) (*domain.Job, error) {
+	if _, err := s.queries.ValidateQuery(ctx, req); err != nil {
+		return nil, err
+	}
+
+	// The job outlives the request that submitted it but keeps the caller's
+	// identity, which the query service needs for access control and audit.
+	jobCtx, cancel := context.WithCancel(domain.ContextWithChannel(context.WithoutCancel(ctx), domain.ChannelJob))
+	j := &job{
+		Job: domain.Job{
+			ID:        newJobID(),
+			Status:    domain.JobQueued,
+			Request:   req,
+			CreatedAt: s.now(),
+		},
+		owner:  subject(ctx),
+		ctx:    jobCtx,
+		cancel: cancel,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(j.CreatedAt)
+	if s.closed || s.drain != nil && s.drain.Draining() {
+		cancel()
+		return nil, overloaded("shutting_down", "the service is shutting down")
+	}
+	select {
+	case s.queue <- j:
+	default:
+		cancel()
+		return nil, overloaded("job_queue_full", "too many jobs are waiting; try again later")
+	}
+	s.jobs[j.ID] = j
+	s.logger.InfoContext(ctx, "submitted job", "job_id", j.ID)
+	return s.snapshot(j), nil
+}
+
+// GetJob returns the caller's job with the given ID.
+func (s *JobService) GetJob(ctx context.Context, id string) (*domain.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, err := s.find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.snapshot(j), nil
+}
+
+// CancelJob cancels the caller's job if it has not finished yet. A finished
+// job is discarded with its result instead. The returned job is in the state
+// it was in when CancelJob returned; a running job is marked as cancelled
+// once its query has stopped.
+func (s *JobService) CancelJob(ctx context.Context, id string) (*domain.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, err := s.find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case j.Status.Done():
+		delete(s.jobs, id)
+	case j.Status == domain.JobQueued:
+		s.finish(j, domain.JobCanceled)
+	}
+	j.cancel()
+	s.logger.InfoContext(ctx, "cancelled job", "job_id", id, "status", j.Status)
+	return s.snapshot(j), nil
+}
+
+// Close cancels every unfinished job and waits for the workers to stop.
+func (s *JobService) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	for _, j := range s.jobs {
+		j.cancel()
+	}
+	close(s.queue)
+	s.mu.Unlock()
+	s.workers.Wait()
+}
+
+func (s *JobService) run(j *job) {
+	s.mu.Lock()
+	if j.Status != domain.JobQueued {
+		s.mu.Unlock()
+		return
+	}
+	if j.ctx.Err() != nil {
+		s.finish(j, domain.JobCanceled)
+		s.mu.Unlock()
+		return
+	}
+	j.Status = domain.JobRunning
+	j.StartedAt = s.now()
+	s.mu.Unlock()
+
+	var results []domain.LogEntry
+	summary, err := s.queries.StreamQuery(j.ctx, j.Request, func(entry domain.LogEntry) error {
+		s.mu.Lock()
+		j.Progress.Results++
+		s.mu.Unlock()
+		results = append(results, entry)
+		return nil
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var qerr *domain.QueryError
+	switch {
+	case err == nil:
+		if results == nil {
+			results = []domain.LogEntry{}
+		}
+		j.Result = &domain.QueryResult{Columns: summary.Columns, Results: results, Count: summary.Count}
+		s.finish(j, domain.JobSucceeded)
+	case j.ctx.Err() != nil && errors.Is(j.ctx.Err(), context.Canceled):
+		s.finish(j, domain.JobCanceled)
+	case errors.As(err, &qerr):
+		j.Error = &domain.JobError{Code: qerr.Code, Message: qerr.Error(), Details: qerr.Details}
+		if qerr.Code == domain.CodeInternal || qerr.Code == domain.CodeSourceUnavailable || qerr.Code == domain.CodeTimeout {
+			j.Error.Message = qerr.Message
+		}
+		s.finish(j, domain.JobFailed)
+	default:
+		j.Error = &domain.JobError{Code: domain.CodeInternal, Message: "internal server error"}
+		s.finish(j, domain.JobFailed)
+	}
+	if j.Status == domain.JobFailed {
+		s.logger.WarnContext(j.ctx, "job failed", "job_id", j.ID, "error", err)
+	}
+	j.cancel()
+}
+
+// finish moves j into a final state. s.mu must be held.
+func (s *JobService) finish(j *job, status domain.JobStatus) {
+	j.Status = status
+	j.FinishedAt = s.now()
+	j.ExpiresAt = j.FinishedAt.Add(s.config.ResultTTL)
+}
+
+// find returns the caller's job with the given ID. Jobs of other callers are
+// reported as missing so that their IDs cannot be probed. s.mu must be held.
+func (s *JobService) find(ctx context.Context, id string) (*job, error) {
+	now := s.now()
+	s.sweep(now)
+	j, ok := s.jobs[id]
+	if !ok || j.owner != subject(ctx) || s.expired(j, now) {
+		return nil, domain.ErrJobNotFound
+	}
+	return j, nil
+}
+
+func (s *JobService) expired(j *job, now time.Time) bool {
+	return j.Status.Done() && !now.Before(j.ExpiresAt)
+}
+
+// sweep drops expired jobs. s.mu must be held.
+func (s *JobService) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < jobSweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for id, j := range s.jobs {
+		if s.expired(j, now) {
+			delete(s.jobs, id)
+		}
+	}
+}
+
+// snapshot copies j for the caller. s.mu must be held.
+func (s *JobService) snapshot(j *job) *domain.Job {
+	out := j.Job
+	switch {
+	case j.StartedAt.IsZero():
+	case j.FinishedAt.IsZero():
+		out.Progress.ElapsedMS = s.now().Sub(j.StartedAt).Milliseconds()
+	default:
+		out.Progress.ElapsedMS = j.FinishedAt.Sub(j.StartedAt).Milliseconds()
+	}
+	return &out
+}
+
+// subject returns the subject of the authenticated caller in ctx, if any.
+func subject(ctx context.Context) string {
+	if principal := domain.PrincipalFromContext(ctx); principal != nil {
+		return principal.Subject
+	}
+	return ""
+}
+
+func newJobID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"mangle-service/internal/core/domain"
 	"mangle-service/internal/core/ports"
 )
@@ -18,6 +19,15 @@ func NewLogService(logDataPort ports.LogDataPort) *LogService {
 }
 
 // FetchLogs fetches logs based on the provided criteria.
-func (s *LogService) FetchLogs(queryCriteria map[string]string) ([]domain.Fact, error) {
-	return s.logDataPort.FetchLogs(queryCriteria)
+func (s *LogService) FetchLogs(ctx context.Context, queryCriteria map[string]string) ([]domain.Fact, error) {
+	return s.logDataPort.FetchLogs(ctx, queryCriteria)
+}
+
+// Predicates describes the predicates of the underlying adapter, if it
+// describes them.
+func (s *LogService) Predicates() []domain.PredicateInfo {
+	if describer, ok := s.logDataPort.(ports.PredicateDescriber); ok {
+		return describer.Predicates()
+	}
+	return nil
 }
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"errors"
+	"mangle-service/internal/core/domain"
+	"mangle-service/internal/core/ports"
+	"time"
+)
+
+// nopMetrics is used when no metrics are configured.
+type nopMetrics struct{}
+
+var (
+	_ ports.QueryMetrics        = nopMetrics{}
+	_ ports.RelationshipMetrics = nopMetrics{}
+)
+
+func (nopMetrics) QueryStarted(string)                                   {}
+func (nopMetrics) QueryDone(string, time.Duration)                       {}
+func (nopMetrics) QueryFailed(string, domain.ErrorCode)                  {}
+func (nopMetrics) ObservePhase(string, domain.QueryPhase, time.Duration) {}
+func (nopMetrics) FactsFetched(string, int)                              {}
+func (nopMetrics) FactsDerived(string, int)                              {}
+func (nopMetrics) RelationshipsReloaded(error)                           {}
+
+// WithMetrics records the duration of every query phase, the facts fetched
+// and derived, and failures in metrics.
+func WithMetrics(metrics ports.QueryMetrics) QueryOption {
+	return func(s *queryService) {
+		s.metrics = metrics
+	}
+}
+
+// recordFailure counts err if it is a query error. Other errors come from the
+// caller, e.g. failing to write a streamed result.
+func (s *queryService) recordFailure(name string, err error) {
+	var qerr *domain.QueryError
+	if errors.As(err, &qerr) {
+		s.metrics.QueryFailed(name, qerr.Code)
+	}
+}
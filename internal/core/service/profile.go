@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"mangle-service/internal/core/domain"
+	"slices"
+	"time"
+
+	"github.com/google/mangle/engine"
+)
+
+// WithSlowQueryThreshold logs the profile of every query that runs for at
+// least threshold, whether it succeeds or not. Zero disables the log.
+func WithSlowQueryThreshold(threshold time.Duration) QueryOption {
+	return func(s *queryService) {
+		s.slowQueryThreshold = threshold
+	}
+}
+
+// profileFacts counts the input facts of each predicate.
+func (e *execution) profileFacts(facts []domain.Fact) {
+	e.profile.Facts = make(map[string]int)
+	for _, fact := range facts {
+		e.profile.Facts[fact.Predicate.Symbol]++
+	}
+}
+
+// profileEvaluation records what the evaluation derived, per predicate and
+// per stratum. The strata are unknown if the evaluation failed early.
+func (e *execution) profileEvaluation(store *guardedStore, stats engine.Stats) {
+	e.profile.Derived = make(map[string]int, len(store.derivedBy))
+	for sym, count := range store.derivedBy {
+		e.profile.Derived[sym.Symbol] += count
+	}
+	e.profile.Iterations = len(store.iterations)
+	e.profile.Strata = make([]domain.StratumProfile, len(stats.Strata))
+	for i, predicates := range stats.Strata {
+		stratum := domain.StratumProfile{Predicates: make([]string, 0, len(predicates))}
+		for _, sym := range predicates {
+			stratum.Predicates = append(stratum.Predicates, sym.Symbol)
+			stratum.Derived += store.derivedBy[sym]
+		}
+		slices.Sort(stratum.Predicates)
+		if i < len(stats.Duration) {
+			stratum.DurationMS = milliseconds(stats.Duration[i])
+		}
+		e.profile.Strata[i] = stratum
+	}
+	for _, sym := range store.iterations {
+		if i, ok := stats.PredToStratum[sym]; ok && i < len(e.profile.Strata) {
+			e.profile.Strata[i].Iterations++
+		}
+	}
+}
+
+// profilePhase records the duration of a phase that has ended.
+func (e *execution) profilePhase(phase domain.QueryPhase, duration time.Duration) {
+	e.profile.Phases = append(e.profile.Phases, domain.PhaseProfile{Phase: phase, DurationMS: milliseconds(duration)})
+}
+
+// finishProfile returns the profile of the execution, which has ended.
+func (e *execution) finishProfile() *domain.QueryProfile {
+	profile := e.profile
+	profile.DurationMS = milliseconds(time.Since(e.start))
+	return &profile
+}
+
+// logSlowQuery logs the profile of an execution that ran for at least the
+// slow query threshold.
+func (s *queryService) logSlowQuery(ctx context.Context, req domain.QueryRequest, profile *domain.QueryProfile, err error) {
+	if s.slowQueryThreshold <= 0 || profile.DurationMS < milliseconds(s.slowQueryThreshold) {
+		return
+	}
+	attrs := []any{"name", req.Name, "duration_ms", profile.DurationMS, "profile", profile}
+	if err != nil {
+		code := domain.CodeInternal
+		var qerr *domain.QueryError
+		if errors.As(err, &qerr) {
+			code = qerr.Code
+		}
+		attrs = append(attrs, "error_code", code)
+	}
+	s.logger.WarnContext(ctx, "slow query", attrs...)
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"mangle-service/internal/core/domain"
+	"slices"
+
+	"github.com/google/mangle/analysis"
+	"github.com/google/mangle/ast"
+	"github.com/google/mangle/parse"
+)
+
+// Program returns the relationship rules and every rule module rule, as they
+// are given to analysis, and the signatures analysis derives from them.
+// Queries add their own rules and keep only the module rules they use.
+func (s *queryService) Program(ctx context.Context) (*domain.ProgramInfo, error) {
+	relationshipRules, err := s.relationshipRules()
+	if err != nil {
+		return nil, err
+	}
+	var moduleRules domain.RuleSet
+	if s.ruleModules != nil {
+		moduleRules = s.ruleModules.GetMangleRules()
+	}
+	unit := parse.SourceUnit{
+		Clauses: slices.Concat(relationshipRules, moduleRules.Clauses),
+		Decls:   moduleRules.Decls,
+	}
+	program, err := analysis.AnalyzeOneUnit(unit, externalPredicates(unit))
+	if err != nil {
+		return nil, &domain.QueryError{Code: domain.CodeAnalysisError, Message: "failed to analyze rules", Err: err}
+	}
+
+	info := &domain.ProgramInfo{
+		Rules:      make([]string, 0, len(unit.Clauses)),
+		Predicates: []domain.PredicateSignature{},
+		Sources:    append([]string{}, s.sourceNames...),
+	}
+	for _, clause := range unit.Clauses {
+		info.Rules = append(info.Rules, clause.String())
+	}
+	for sym, decl := range program.Decls {
+		if _, ok := program.IdbPredicates[sym]; !ok {
+			if _, ok := program.EdbPredicates[sym]; !ok {
+				// Declarations of packages and uses name no predicate.
+				continue
+			}
+		}
+		info.Predicates = append(info.Predicates, signature(sym, decl, program))
+	}
+	slices.SortFunc(info.Predicates, func(a, b domain.PredicateSignature) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.Arity, b.Arity))
+	})
+	return info, nil
+}
+
+// signature describes a predicate of an analyzed program.
+func signature(sym ast.PredicateSym, decl *ast.Decl, program *analysis.ProgramInfo) domain.PredicateSignature {
+	sig := domain.PredicateSignature{
+		Name:     sym.Symbol,
+		Arity:    sym.Arity,
+		Args:     make([]string, 0, sym.Arity),
+		Kind:     domain.PredicateExtensional,
+		Declared: !decl.IsSynthetic(),
+		Doc:      docString(*decl),
+	}
+	if _, ok := program.IdbPredicates[sym]; ok {
+		sig.Kind = domain.PredicateIntensional
+	}
+	for _, arg := range decl.DeclaredAtom.Args {
+		sig.Args = append(sig.Args, arg.String())
+	}
+	for _, bound := range decl.Bounds {
+		terms := make([]string, 0, len(bound.Bounds))
+		for _, term := range bound.Bounds {
+			terms = append(terms, term.String())
+		}
+		sig.Bounds = append(sig.Bounds, terms)
+	}
+	return sig
+}
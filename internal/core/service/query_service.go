@@ -2,10 +2,13 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"mangle-service/internal/core/domain"
 	"mangle-service/internal/core/ports"
+	"mangle-service/pkg/logger"
+	"slices"
 	"strings"
 	"time"
 
@@ -14,106 +17,370 @@ import (
 	"github.com/google/mangle/engine"
 	"github.com/google/mangle/factstore"
 	"github.com/google/mangle/parse"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type queryService struct {
-	logDataPort         ports.LogDataPort
+	sources             map[string]ports.LogDataPort
+	sourceNames         []string
 	relationshipService ports.RelationshipService
+	ruleModules         ports.RuleModuleService
+	timeout             time.Duration
+	factLimit           int
+	policy              *domain.AccessPolicy
+	admission           *AdmissionController
+	drain               *DrainController
+	metrics             ports.QueryMetrics
+	audit               ports.AuditSink
+	slowQueryThreshold  time.Duration
+	tracer              trace.Tracer
 	logger              *slog.Logger
 }
 
+// QueryOption configures optional parts of the query service.
+type QueryOption func(*queryService)
+
+// WithRuleModules makes the predicates of the shared rule modules available to every query.
+func WithRuleModules(ruleModules ports.RuleModuleService) QueryOption {
+	return func(s *queryService) {
+		s.ruleModules = ruleModules
+	}
+}
+
+// WithTimeout limits how long a single query may run. Zero means no limit.
+func WithTimeout(timeout time.Duration) QueryOption {
+	return func(s *queryService) {
+		s.timeout = timeout
+	}
+}
+
+// WithFactLimit limits how many facts a single query may derive. Zero means no limit.
+func WithFactLimit(limit int) QueryOption {
+	return func(s *queryService) {
+		s.factLimit = limit
+	}
+}
+
+// WithLogSource adds a named log source next to the default one. Queries
+// read from every source unless they name the ones they need.
+func WithLogSource(name string, logDataPort ports.LogDataPort) QueryOption {
+	return func(s *queryService) {
+		if _, ok := s.sources[name]; !ok {
+			s.sourceNames = append(s.sourceNames, name)
+		}
+		s.sources[name] = logDataPort
+	}
+}
+
+// WithAccessPolicy restricts predicates and log sources to callers with
+// certain roles. The caller is taken from the query context.
+func WithAccessPolicy(policy *domain.AccessPolicy) QueryOption {
+	return func(s *queryService) {
+		s.policy = policy
+	}
+}
+
+// WithAdmissionControl makes every query pass the controller's rate and
+// concurrency limits before it runs.
+func WithAdmissionControl(controller *AdmissionController) QueryOption {
+	return func(s *queryService) {
+		s.admission = controller
+	}
+}
+
+// WithDrainController registers every query with the controller, which
+// refuses new queries and cancels running ones when the service shuts down.
+func WithDrainController(controller *DrainController) QueryOption {
+	return func(s *queryService) {
+		s.drain = controller
+	}
+}
+
 // NewQueryService creates a new instance of the query service.
-func NewQueryService(logDataPort ports.LogDataPort, relationshipService ports.RelationshipService, logger *slog.Logger) ports.QueryService {
-	return &queryService{
-		logDataPort:         logDataPort,
+// The log data port is registered as the source domain.DefaultLogSource.
+func NewQueryService(logDataPort ports.LogDataPort, relationshipService ports.RelationshipService, logger *slog.Logger, opts ...QueryOption) ports.QueryService {
+	s := &queryService{
+		sources:             map[string]ports.LogDataPort{domain.DefaultLogSource: logDataPort},
+		sourceNames:         []string{domain.DefaultLogSource},
 		relationshipService: relationshipService,
+		metrics:             nopMetrics{},
+		tracer:              otel.GetTracerProvider().Tracer(tracerName),
 		logger:              logger,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
-// ExecuteQuery orchestrates the query execution.
+// ExecuteQuery runs the query and collects every result.
+// Failures are reported as *domain.QueryError.
 func (s *queryService) ExecuteQuery(ctx context.Context, req domain.QueryRequest) (*domain.QueryResult, error) {
-	s.logger.Info("starting query execution", "query", req.Query)
-	startTime := time.Now()
+	var results []domain.LogEntry
+	var facts []domain.Fact
+	summary, err := s.streamQuery(ctx, req, func(entry domain.LogEntry, fact domain.Fact) error {
+		results = append(results, entry)
+		facts = append(facts, fact)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &domain.QueryResult{
+		Columns: summary.Columns,
+		Results: results,
+		Count:   summary.Count,
+		Facts:   facts,
+		Profile: summary.Profile,
+	}, nil
+}
 
-	// 1. Parse the request query to separate rules from the final query atom.
-	s.logger.Debug("parsing query request")
-	requestUnit, err := parse.Unit(strings.NewReader(req.Query))
+// StreamQuery orchestrates the query execution and passes each result to
+// yield as it is read from the fact store. If yield returns an error, the
+// query stops and that error is returned.
+func (s *queryService) StreamQuery(ctx context.Context, req domain.QueryRequest, yield func(domain.LogEntry) error) (*domain.QuerySummary, error) {
+	return s.streamQuery(ctx, req, func(entry domain.LogEntry, _ domain.Fact) error {
+		return yield(entry)
+	})
+}
+
+// streamQuery runs the query and passes each result to yield together with
+// the fact it was read from.
+func (s *queryService) streamQuery(ctx context.Context, req domain.QueryRequest, yield func(domain.LogEntry, domain.Fact) error) (*domain.QuerySummary, error) {
+	ctx = logger.ContextWithAttrs(ctx, slog.String("query_hash", req.Hash()))
+	ctx, span := s.tracer.Start(ctx, "mangle.query", trace.WithAttributes(
+		attribute.String("mangle.query.hash", req.Hash()),
+		attribute.String("mangle.query.name", req.Name),
+		attribute.StringSlice("mangle.query.sources", req.Sources),
+	))
+	defer span.End()
+	exec := &execution{start: time.Now().UTC()}
+	summary, err := s.runQuery(ctx, req, exec, yield)
+	s.writeAudit(ctx, req, exec, err)
+	profile := exec.finishProfile()
+	s.logSlowQuery(ctx, req, profile, err)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse request query unit: %w", err)
+		s.recordFailure(req.Name, err)
+		recordSpanError(span, err)
+		return nil, err
 	}
-	if len(requestUnit.Clauses) == 0 {
-		return nil, fmt.Errorf("empty query request")
+	span.SetAttributes(attribute.Int("mangle.query.results", summary.Count))
+	if req.Debug {
+		summary.Profile = profile
 	}
-	lastClause := requestUnit.Clauses[len(requestUnit.Clauses)-1]
-	if len(lastClause.Premises) > 0 {
-		return nil, fmt.Errorf("last clause in query must be a simple atom, not a rule")
+	return summary, nil
+}
+
+// runQuery runs the query and records its sources, the log facts it read and
+// the results it found in exec.
+func (s *queryService) runQuery(ctx context.Context, req domain.QueryRequest, exec *execution, yield func(domain.LogEntry, domain.Fact) error) (*domain.QuerySummary, error) {
+	if s.drain != nil {
+		var done func()
+		var err error
+		ctx, done, err = s.drain.Begin(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer done()
+	}
+	if s.admission != nil {
+		release, err := s.admission.Admit(ctx)
+		if err != nil {
+			if qerr := s.contextError(ctx); qerr != nil {
+				return nil, qerr
+			}
+			return nil, err
+		}
+		defer release()
+	}
+
+	// The query text may be large and sensitive; only debug logs carry it.
+	s.logger.InfoContext(ctx, "starting query execution", "name", req.Name)
+	s.logger.DebugContext(ctx, "query text", "query", req.Query)
+	startTime := time.Now()
+	s.metrics.QueryStarted(req.Name)
+	defer func() { s.metrics.QueryDone(req.Name, time.Since(startTime)) }()
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
 	}
-	queryAtom := lastClause.Head
-	requestRules := requestUnit.Clauses[:len(requestUnit.Clauses)-1]
 
-	// 2. Fetch log facts
-	s.logger.Debug("fetching log facts")
-	logFacts, err := s.logDataPort.FetchLogs(make(map[string]string))
+	// 1. Parse the request query and check that the caller may run it.
+	phaseCtx, phase := s.startPhase(ctx, exec, req.Name, domain.PhaseParse)
+	query, err := s.prepareQuery(phaseCtx, req)
+	phase.end(err)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch logs: %w", err)
+		return nil, err
 	}
-	s.logger.Debug("fetched log facts", "count", len(logFacts))
+	exec.sources = query.sources
 
-	// 3. Fetch relationship facts and rules
-	s.logger.Debug("fetching relationship facts and rules")
-	relationshipFacts, err := s.relationshipService.GetMangleFacts()
+	// 2. Fetch the log facts and the relationship graph.
+	phaseCtx, phase = s.startPhase(ctx, exec, req.Name, domain.PhaseFetch)
+	facts, relationshipRules, err := s.fetchFacts(phaseCtx, req, exec)
+	phase.end(err, attribute.Int("mangle.facts.fetched", len(facts)))
 	if err != nil {
-		return nil, fmt.Errorf("failed to get relationship facts: %w", err)
+		return nil, err
+	}
+
+	// 3. Combine facts and rules and analyze the program.
+	phaseCtx, phase = s.startPhase(ctx, exec, req.Name, domain.PhaseAnalysis)
+	allRules := slices.Concat(relationshipRules, query.rules, query.moduleRules.Clauses)
+	s.logger.DebugContext(ctx, "combined facts and rules", "total_facts", len(facts), "total_rules", len(allRules))
+	sourceUnit := parse.SourceUnit{
+		Clauses: append(allRules, domain.FactsToClauses(facts)...),
+		Decls:   query.moduleRules.Decls,
 	}
-	relationshipRulesStr, err := s.relationshipService.GetMangleRulesAsString()
+	program, err := analysis.AnalyzeOneUnit(sourceUnit, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get relationship rules: %w", err)
+		err = &domain.QueryError{Code: domain.CodeAnalysisError, Message: "failed to analyze query", Err: err}
 	}
-	s.logger.Debug("fetched relationship info", "fact_count", len(relationshipFacts), "rule_char_count", len(relationshipRulesStr))
-	relationshipRulesUnit, err := parse.Unit(strings.NewReader(relationshipRulesStr))
+	phase.end(err, attribute.Int("mangle.rules", len(allRules)))
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse relationship rules: %w", err)
+		return nil, err
 	}
 
-	// 4. Combine facts and rules
-	allFacts := append(logFacts, relationshipFacts...)
-	allRules := append(relationshipRulesUnit.Clauses, requestRules...)
-	s.logger.Debug("combined facts and rules", "total_facts", len(allFacts), "total_rules", len(allRules))
+	// 4. Evaluate the program.
+	phaseCtx, phase = s.startPhase(ctx, exec, req.Name, domain.PhaseEvaluation)
+	store, err := s.evaluate(phaseCtx, req.Name, program, exec)
+	phase.end(err, attribute.Int("mangle.facts.derived", store.derived))
+	if err != nil {
+		return nil, err
+	}
 
-	// 5. Initialize Mangle engine
-	s.logger.Debug("initializing mangle engine")
-	store := factstore.NewSimpleInMemoryStore()
-	sourceUnit := parse.SourceUnit{
-		Clauses: append(allRules, domain.FactsToClauses(allFacts)...),
+	// 5. Read the results of the query atom.
+	phaseCtx, phase = s.startPhase(ctx, exec, req.Name, domain.PhaseExtraction)
+	count, err := s.extract(phaseCtx, store.FactStore, query, yield)
+	exec.results = count
+	phase.end(err, attribute.Int("mangle.query.results", count))
+	if err != nil {
+		return nil, err
 	}
-	program, err := analysis.AnalyzeOneUnit(sourceUnit, nil)
+
+	duration := time.Since(startTime)
+	s.logger.InfoContext(ctx, "query execution complete", "duration", duration, "results", count)
+
+	return &domain.QuerySummary{
+		Columns:    query.columns,
+		Count:      count,
+		DurationMS: duration.Milliseconds(),
+	}, nil
+}
+
+// fetchFacts reads the log facts of the execution's sources and the
+// relationship facts, and returns them together with the relationship rules.
+func (s *queryService) fetchFacts(ctx context.Context, req domain.QueryRequest, exec *execution) ([]domain.Fact, []ast.Clause, error) {
+	s.logger.DebugContext(ctx, "fetching log facts", "sources", exec.sources)
+	var facts []domain.Fact
+	for _, source := range exec.sources {
+		sourceFacts, err := s.fetchSource(ctx, req, source)
+		if err != nil {
+			return nil, nil, err
+		}
+		facts = append(facts, sourceFacts...)
+		exec.factsRead += len(sourceFacts)
+	}
+	s.logger.DebugContext(ctx, "fetched log facts", "count", len(facts))
+
+	s.logger.DebugContext(ctx, "fetching relationship facts and rules")
+	relationshipFacts, err := s.relationshipService.GetMangleFacts()
+	if err != nil {
+		return nil, nil, &domain.QueryError{Code: domain.CodeInternal, Message: "failed to get relationship facts", Err: err}
+	}
+	relationshipRules, err := s.relationshipRules()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create program: %w", err)
+		return nil, nil, err
 	}
+	s.logger.DebugContext(ctx, "fetched relationship info", "fact_count", len(relationshipFacts), "rule_count", len(relationshipRules))
+	facts = append(facts, relationshipFacts...)
+	exec.profileFacts(facts)
+	return facts, relationshipRules, nil
+}
 
-	s.logger.Debug("evaluating program")
-	if err := engine.EvalProgram(program, store); err != nil {
-		return nil, fmt.Errorf("program evaluation failed: %w", err)
+// fetchSource reads the log facts of a single source.
+func (s *queryService) fetchSource(ctx context.Context, req domain.QueryRequest, source string) ([]domain.Fact, error) {
+	ctx, span := s.tracer.Start(ctx, "mangle.log_source.fetch", trace.WithAttributes(
+		attribute.String("mangle.log_source", source),
+	))
+	defer span.End()
+	facts, err := s.sources[source].FetchLogs(ctx, req.Criteria())
+	if err != nil {
+		if qerr := s.contextError(ctx); qerr != nil {
+			err = qerr
+		} else {
+			err = &domain.QueryError{
+				Code:    domain.CodeSourceUnavailable,
+				Message: "failed to fetch logs",
+				Details: map[string]any{"source": source},
+				Err:     err,
+			}
+		}
+		recordSpanError(span, err)
+		return nil, err
 	}
-	s.logger.Debug("program evaluation complete")
+	span.SetAttributes(attribute.Int("mangle.facts.fetched", len(facts)))
+	s.metrics.FactsFetched(source, len(facts))
+	return facts, nil
+}
 
-	// 6. Execute query
-	s.logger.Debug("retrieving facts from store for query", "query_atom", queryAtom.String())
-	var results []domain.LogEntry
-	// Extract variable names from the query, ignoring wildcards.
-	varNames := make(map[int]string)
-	for i, arg := range queryAtom.Args {
-		if v, ok := arg.(ast.Variable); ok && v.Symbol != "_" {
-			varNames[i] = v.Symbol
+// factLimitMessage is part of the errors of the Mangle engine when an
+// evaluation exceeds its created-fact limit.
+const factLimitMessage = "fact size limit reached"
+
+// evaluate derives facts from the program until it reaches a fixpoint, the
+// fact limit is exceeded or ctx is done, and profiles the evaluation in exec.
+func (s *queryService) evaluate(ctx context.Context, name string, program *analysis.ProgramInfo, exec *execution) (*guardedStore, error) {
+	s.logger.DebugContext(ctx, "evaluating program")
+	store := newGuardedStore(ctx, factstore.NewSimpleInMemoryStore())
+	// Input facts are added up front so that only derived facts count against the limit.
+	for _, fact := range program.InitialFacts {
+		store.Add(fact)
+	}
+	store.startCounting()
+	var opts []engine.EvalOption
+	if s.factLimit > 0 {
+		opts = append(opts, engine.WithCreatedFactLimit(s.factLimit))
+	}
+	stats, err := engine.EvalProgramWithStats(program, store, opts...)
+	exec.profileEvaluation(store, stats)
+	s.metrics.FactsDerived(name, store.derived)
+	if qerr := s.contextError(ctx); qerr != nil {
+		return store, qerr
+	}
+	// The engine reports the limit with a plain error, so it is told apart
+	// from other failures by its message, or by the count if the message
+	// changes; TestEngineFactLimitMessage pins the message.
+	if err != nil && (strings.Contains(err.Error(), factLimitMessage) || s.factLimit > 0 && store.derived > s.factLimit) {
+		return store, &domain.QueryError{
+			Code:    domain.CodeBudgetExceeded,
+			Message: fmt.Sprintf("query derived more than %d facts", s.factLimit),
+			Details: map[string]any{"fact_limit": s.factLimit},
+			Err:     err,
 		}
 	}
+	if err != nil {
+		return store, &domain.QueryError{Code: domain.CodeEvaluationError, Message: "program evaluation failed", Err: err}
+	}
+	s.logger.DebugContext(ctx, "program evaluation complete")
+	return store, nil
+}
 
-	store.GetFacts(queryAtom, func(a ast.Atom) error {
+// extract passes the facts matching the query atom to yield as results and
+// returns how many there were.
+func (s *queryService) extract(ctx context.Context, store factstore.FactStore, query *preparedQuery, yield func(domain.LogEntry, domain.Fact) error) (int, error) {
+	s.logger.DebugContext(ctx, "retrieving facts from store for query", "query_atom", query.atom.String())
+	count := 0
+	err := store.GetFacts(query.atom, func(a ast.Atom) error {
+		// Stop early if the caller has gone away.
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		resultMap := make(domain.LogEntry)
 		// This assumes that the bound atom `a` has the same structure as the query atom.
 		for i, term := range a.Args {
-			varName, ok := varNames[i]
+			varName, ok := query.varNames[i]
 			if !ok {
 				// This case handles results for parts of the query that were not variables (e.g. constants)
 				// or wildcards. We skip them in the output.
@@ -123,16 +390,168 @@ func (s *queryService) ExecuteQuery(ctx context.Context, req domain.QueryRequest
 			// For ast.String, it includes quotes, which we need to remove for clean output.
 			resultMap[varName] = strings.Trim(term.String(), `"`)
 		}
-		results = append(results, resultMap)
-		return nil
+		count++
+		return yield(resultMap, a)
 	})
-	s.logger.Debug("retrieved facts", "count", len(results))
+	if err != nil {
+		if qerr := s.contextError(ctx); qerr != nil {
+			return count, qerr
+		}
+		return count, err
+	}
+	s.logger.DebugContext(ctx, "retrieved facts", "count", count)
+	return count, nil
+}
 
-	duration := time.Since(startTime)
-	s.logger.Info("query execution complete", "duration", duration, "results", len(results))
+// preparedQuery is a parsed request that the caller is allowed to run.
+type preparedQuery struct {
+	// atom is the query atom, the request's last clause.
+	atom ast.Atom
+	// rules are the request's clauses before the query atom.
+	rules       []ast.Clause
+	moduleRules domain.RuleSet
+	sources     []string
+	// columns are the query atom's variables in query order, without
+	// wildcards; varNames maps argument positions to them.
+	columns  []string
+	varNames map[int]string
+}
 
-	return &domain.QueryResult{
-		Results: results,
-		Count:   len(results),
-	}, nil
+// prepareQuery parses the request, separates the rules from the final query
+// atom and checks that the caller may use every predicate and source the
+// query needs.
+func (s *queryService) prepareQuery(ctx context.Context, req domain.QueryRequest) (*preparedQuery, error) {
+	s.logger.DebugContext(ctx, "parsing query request")
+	requestUnit, err := parse.Unit(strings.NewReader(req.Query))
+	if err != nil {
+		// Mangle reports one syntax error per line, with a trailing newline.
+		err = errors.New(strings.TrimSpace(err.Error()))
+		return nil, &domain.QueryError{Code: domain.CodeParseError, Message: "failed to parse query", Err: err}
+	}
+	if len(requestUnit.Clauses) == 0 {
+		return nil, &domain.QueryError{Code: domain.CodeInvalidQuery, Message: "empty query request"}
+	}
+	lastClause := requestUnit.Clauses[len(requestUnit.Clauses)-1]
+	if len(lastClause.Premises) > 0 {
+		return nil, &domain.QueryError{Code: domain.CodeInvalidQuery, Message: "last clause in query must be a simple atom, not a rule"}
+	}
+	query := &preparedQuery{
+		atom:     lastClause.Head,
+		rules:    requestUnit.Clauses[:len(requestUnit.Clauses)-1],
+		columns:  []string{},
+		varNames: make(map[int]string),
+	}
+	if s.ruleModules != nil {
+		query.moduleRules = usedModuleRules(s.ruleModules.GetMangleRules(), requestUnit.Clauses)
+	}
+
+	principal := domain.PrincipalFromContext(ctx)
+	if qerr := s.checkPredicates(principal, append(slices.Clone(requestUnit.Clauses), query.moduleRules.Clauses...)); qerr != nil {
+		return nil, qerr
+	}
+	sources, qerr := s.selectSources(principal, req.Sources)
+	if qerr != nil {
+		return nil, qerr
+	}
+	query.sources = sources
+
+	// Columns keeps the variables in query order, which result maps do not.
+	for i, arg := range query.atom.Args {
+		if v, ok := arg.(ast.Variable); ok && v.Symbol != "_" {
+			query.varNames[i] = v.Symbol
+			if !slices.Contains(query.columns, v.Symbol) {
+				query.columns = append(query.columns, v.Symbol)
+			}
+		}
+	}
+	return query, nil
+}
+
+// relationshipRules parses the rules derived from the relationship configuration.
+func (s *queryService) relationshipRules() ([]ast.Clause, error) {
+	rules, err := s.relationshipService.GetMangleRulesAsString()
+	if err != nil {
+		return nil, &domain.QueryError{Code: domain.CodeInternal, Message: "failed to get relationship rules", Err: err}
+	}
+	unit, err := parse.Unit(strings.NewReader(rules))
+	if err != nil {
+		return nil, &domain.QueryError{Code: domain.CodeInternal, Message: "failed to parse relationship rules", Err: err}
+	}
+	return unit.Clauses, nil
+}
+
+// ValidateQuery checks a query without fetching logs or evaluating it. The
+// log facts are only known once they are fetched, so predicates that nothing
+// defines are assumed to come from the logs.
+func (s *queryService) ValidateQuery(ctx context.Context, req domain.QueryRequest) (*domain.QueryValidation, error) {
+	query, err := s.prepareQuery(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	relationshipRules, err := s.relationshipRules()
+	if err != nil {
+		return nil, err
+	}
+	unit := parse.SourceUnit{
+		Clauses: slices.Concat(relationshipRules, query.rules, query.moduleRules.Clauses),
+		Decls:   query.moduleRules.Decls,
+	}
+	if _, err := analysis.AnalyzeOneUnit(unit, externalPredicates(unit)); err != nil {
+		return nil, &domain.QueryError{Code: domain.CodeAnalysisError, Message: "failed to analyze query", Err: err}
+	}
+	return &domain.QueryValidation{Columns: query.columns, Sources: query.sources}, nil
+}
+
+// contextError converts a done context into a timeout or cancellation error.
+// Queries cancelled by a shutdown are reported as retryable elsewhere.
+func (s *queryService) contextError(ctx context.Context) *domain.QueryError {
+	switch {
+	case errors.Is(context.Cause(ctx), errShuttingDown):
+		qerr := overloaded("shutting_down", "the query was cancelled because the service is shutting down")
+		qerr.Err = context.Cause(ctx)
+		return qerr
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		qerr := &domain.QueryError{Code: domain.CodeTimeout, Message: "query timed out", Err: ctx.Err()}
+		if s.timeout > 0 {
+			qerr.Details = map[string]any{"timeout": s.timeout.String()}
+		}
+		return qerr
+	case ctx.Err() != nil:
+		return &domain.QueryError{Code: domain.CodeCanceled, Message: "query canceled", Err: ctx.Err()}
+	}
+	return nil
+}
+
+// usedModuleRules keeps the module rules that the request refers to, directly
+// or through other module rules. Leaving out the rest keeps programs small and
+// avoids failures in modules whose input predicates the query does not fetch.
+func usedModuleRules(rules domain.RuleSet, request []ast.Clause) domain.RuleSet {
+	byHead := make(map[ast.PredicateSym][]ast.Clause)
+	for _, clause := range rules.Clauses {
+		byHead[clause.Head.Predicate] = append(byHead[clause.Head.Predicate], clause)
+	}
+
+	used := make(map[ast.PredicateSym]bool)
+	var pending []ast.PredicateSym
+	for _, clause := range request {
+		pending = append(pending, clause.Head.Predicate)
+	}
+	pending = append(pending, premisePredicates(request)...)
+	var result domain.RuleSet
+	for len(pending) > 0 {
+		sym := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if used[sym] {
+			continue
+		}
+		used[sym] = true
+		result.Clauses = append(result.Clauses, byHead[sym]...)
+		pending = append(pending, premisePredicates(byHead[sym])...)
+	}
+	for _, decl := range rules.Decls {
+		if used[decl.DeclaredAtom.Predicate] {
+			result.Decls = append(result.Decls, decl)
+		}
+	}
+	return result
 }
@@ -0,0 +1,290 @@
+package service
+
+import (
+	"fmt"
+	"mangle-service/internal/core/domain"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// LintRelationships checks a relationship configuration for mistakes that
+// unmarshalling alone does not catch: empty names, duplicate services,
+// self-dependencies, duplicate or unknown dependencies and cycles.
+// Issues are sorted by line.
+func LintRelationships(config *domain.RelationshipConfig) []domain.LintIssue {
+	if config == nil {
+		return nil
+	}
+
+	var issues []domain.LintIssue
+	declared := make(map[string]domain.ServiceRelationship)
+	var names []string
+	for _, rel := range config.Relationships {
+		if rel.Service == "" {
+			issues = append(issues, domain.LintIssue{
+				Severity: domain.LintError,
+				Code:     "empty-service",
+				Message:  "service name must not be empty",
+				Line:     rel.Line,
+			})
+			continue
+		}
+		if first, ok := declared[rel.Service]; ok {
+			issues = append(issues, domain.LintIssue{
+				Severity: domain.LintError,
+				Code:     "duplicate-service",
+				Message:  fmt.Sprintf("service %q is declared more than once%s", rel.Service, firstDeclaredAt(first.Line)),
+				Service:  rel.Service,
+				Line:     rel.Line,
+			})
+			continue
+		}
+		declared[rel.Service] = rel
+		names = append(names, rel.Service)
+		if rel.Criticality != "" && domain.CriticalityRank(rel.Criticality) == domain.CriticalityRank("") {
+			issues = append(issues, domain.LintIssue{
+				Severity: domain.LintWarning,
+				Code:     "unknown-criticality",
+				Message:  fmt.Sprintf("service %q has unknown criticality %q, expected critical, high, medium or low", rel.Service, rel.Criticality),
+				Service:  rel.Service,
+				Line:     rel.Line,
+			})
+		}
+	}
+
+	for _, rel := range config.Relationships {
+		seen := make(map[string]bool)
+		for i, dep := range rel.DependsOn {
+			line := rel.DependencyLine(i)
+			switch {
+			case dep == "":
+				issues = append(issues, domain.LintIssue{
+					Severity: domain.LintError,
+					Code:     "empty-dependency",
+					Message:  fmt.Sprintf("service %q has an empty dependency", rel.Service),
+					Service:  rel.Service,
+					Line:     line,
+				})
+			case dep == rel.Service:
+				issues = append(issues, domain.LintIssue{
+					Severity: domain.LintError,
+					Code:     "self-dependency",
+					Message:  fmt.Sprintf("service %q depends on itself", rel.Service),
+					Service:  rel.Service,
+					Line:     line,
+				})
+			case seen[dep]:
+				issues = append(issues, domain.LintIssue{
+					Severity: domain.LintWarning,
+					Code:     "duplicate-dependency",
+					Message:  fmt.Sprintf("service %q lists dependency %q more than once", rel.Service, dep),
+					Service:  rel.Service,
+					Line:     line,
+				})
+			default:
+				if _, ok := declared[dep]; !ok {
+					issues = append(issues, unknownDependencyIssue(rel.Service, dep, line, names))
+				}
+			}
+			seen[dep] = true
+		}
+	}
+
+	issues = append(issues, cycleIssues(config.Relationships, declared)...)
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Line < issues[j].Line
+	})
+	return issues
+}
+
+// unknownDependencyIssue reports a dependency that is not declared as a
+// service. Close matches to a declared service are likely typos; anything else
+// is usually a leaf such as a database and only reported for information.
+func unknownDependencyIssue(service, dep string, line int, declared []string) domain.LintIssue {
+	if suggestion := closestName(dep, declared); suggestion != "" {
+		return domain.LintIssue{
+			Severity: domain.LintWarning,
+			Code:     "unknown-service",
+			Message:  fmt.Sprintf("service %q depends on unknown service %q, did you mean %q?", service, dep, suggestion),
+			Service:  service,
+			Line:     line,
+		}
+	}
+	return domain.LintIssue{
+		Severity: domain.LintInfo,
+		Code:     "undeclared-service",
+		Message:  fmt.Sprintf("service %q depends on %q, which is not declared as a service", service, dep),
+		Service:  service,
+		Line:     line,
+	}
+}
+
+// cycleIssues reports one warning per strongly connected component with more
+// than one service. Self-dependencies are reported separately.
+func cycleIssues(rels []domain.ServiceRelationship, declared map[string]domain.ServiceRelationship) []domain.LintIssue {
+	graph := make(map[string][]string)
+	var order []string
+	for _, rel := range rels {
+		if _, ok := graph[rel.Service]; ok || rel.Service == "" {
+			continue
+		}
+		order = append(order, rel.Service)
+		for _, dep := range rel.DependsOn {
+			if _, ok := declared[dep]; ok && dep != rel.Service {
+				graph[rel.Service] = append(graph[rel.Service], dep)
+			}
+		}
+		if graph[rel.Service] == nil {
+			graph[rel.Service] = []string{}
+		}
+	}
+
+	var issues []domain.LintIssue
+	for _, component := range stronglyConnectedComponents(order, graph) {
+		if len(component) < 2 {
+			continue
+		}
+		path := cyclePath(component, graph)
+		issues = append(issues, domain.LintIssue{
+			Severity: domain.LintWarning,
+			Code:     "cycle",
+			Message:  fmt.Sprintf("dependency cycle %s makes depends_on/2 hold for every pair in it", strings.Join(path, " -> ")),
+			Service:  path[0],
+			Line:     declared[path[0]].Line,
+		})
+	}
+	return issues
+}
+
+// stronglyConnectedComponents implements Tarjan's algorithm. Components are
+// returned in discovery order of their first node in order.
+func stronglyConnectedComponents(order []string, graph map[string][]string) [][]string {
+	index := make(map[string]int)
+	lowlink := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var components [][]string
+	next := 0
+
+	var visit func(v string)
+	visit = func(v string) {
+		index[v] = next
+		lowlink[v] = next
+		next++
+		stack = append(stack, v)
+		onStack[v] = true
+
+		for _, w := range graph[v] {
+			if _, ok := index[w]; !ok {
+				visit(w)
+				lowlink[v] = min(lowlink[v], lowlink[w])
+			} else if onStack[w] {
+				lowlink[v] = min(lowlink[v], index[w])
+			}
+		}
+
+		if lowlink[v] == index[v] {
+			var component []string
+			for {
+				w := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[w] = false
+				component = append(component, w)
+				if w == v {
+					break
+				}
+			}
+			components = append(components, component)
+		}
+	}
+
+	for _, v := range order {
+		if _, ok := index[v]; !ok {
+			visit(v)
+		}
+	}
+	return components
+}
+
+// cyclePath finds a concrete cycle inside a strongly connected component,
+// starting and ending at its alphabetically first service so that the output
+// is stable.
+func cyclePath(component []string, graph map[string][]string) []string {
+	inComponent := make(map[string]bool)
+	for _, v := range component {
+		inComponent[v] = true
+	}
+	start := slices.Min(component)
+
+	// Breadth-first search back to start gives the shortest cycle through it.
+	parent := map[string]string{}
+	queue := []string{start}
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		for _, w := range graph[v] {
+			if !inComponent[w] {
+				continue
+			}
+			if w == start {
+				path := []string{start}
+				for u := v; u != start; u = parent[u] {
+					path = append(path, u)
+				}
+				slices.Reverse(path[1:])
+				return append(path, start)
+			}
+			if _, ok := parent[w]; !ok {
+				parent[w] = v
+				queue = append(queue, w)
+			}
+		}
+	}
+	return append(component, component[0])
+}
+
+// closestName returns the declared name with the smallest edit distance to
+// name, if that distance is small enough to suggest a typo.
+func closestName(name string, declared []string) string {
+	best, bestDistance := "", -1
+	for _, candidate := range declared {
+		d := levenshtein(name, candidate)
+		if bestDistance < 0 || d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	if bestDistance < 0 || bestDistance > max(1, len(name)/4) {
+		return ""
+	}
+	return best
+}
+
+func levenshtein(a, b string) int {
+	ar, br := []rune(a), []rune(b)
+	prev := make([]int, len(br)+1)
+	curr := make([]int, len(br)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ar); i++ {
+		curr[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(br)]
+}
+
+func firstDeclaredAt(line int) string {
+	if line <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (first declared on line %d)", line)
+}
@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"mangle-service/internal/core/domain"
 	"mangle-service/internal/core/ports"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/google/mangle/ast"
 )
@@ -13,36 +16,259 @@ var _ ports.RelationshipService = (*RelationshipService)(nil)
 // RelationshipService is a service for managing service relationships.
 type RelationshipService struct {
 	configLoader ports.ConfigLoaderPort
-	config       *domain.RelationshipConfig
+	store        ports.RelationshipStorePort
+	metrics      ports.RelationshipMetrics
+
+	mu             sync.RWMutex
+	config         *domain.RelationshipConfig
+	loadIssues     []domain.LintIssue
+	path           string
+	updatedAt      time.Time
+	reloadErr      error
+	reloadFailedAt time.Time
+}
+
+// RelationshipOption configures optional parts of the RelationshipService.
+type RelationshipOption func(*RelationshipService)
+
+// WithRelationshipMetrics counts reloads of the configuration in metrics.
+func WithRelationshipMetrics(metrics ports.RelationshipMetrics) RelationshipOption {
+	return func(s *RelationshipService) {
+		s.metrics = metrics
+	}
 }
 
 // NewRelationshipService creates a new RelationshipService.
-func NewRelationshipService(configLoader ports.ConfigLoaderPort) *RelationshipService {
-	return &RelationshipService{
+// The store is optional; without it, changes made through the management
+// methods are kept in memory only.
+func NewRelationshipService(configLoader ports.ConfigLoaderPort, store ports.RelationshipStorePort, opts ...RelationshipOption) *RelationshipService {
+	s := &RelationshipService{
 		configLoader: configLoader,
+		store:        store,
+		metrics:      nopMetrics{},
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
 
-// LoadRelationships loads the service relationships from the given path.
+// LoadRelationships loads the service relationships.
+// If a store is configured and already holds a configuration, the store wins
+// and the file at path is ignored; otherwise the file seeds the store.
+// The configuration is linted first and rejected if it has errors.
 func (s *RelationshipService) LoadRelationships(path string) error {
+	s.mu.Lock()
+	s.path = path
+	s.mu.Unlock()
+	if s.store != nil {
+		stored, err := s.store.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load relationship store: %w", err)
+		}
+		if stored != nil {
+			issues := LintRelationships(stored)
+			if domain.HasLintErrors(issues) {
+				return &domain.LintFailedError{Issues: issues}
+			}
+			s.setConfig(stored, issues)
+			return nil
+		}
+	}
+
 	config, err := s.configLoader.Load(path)
 	if err != nil {
 		return err
 	}
-	s.config = config
+	issues := LintRelationships(config)
+	if domain.HasLintErrors(issues) {
+		return &domain.LintFailedError{Issues: issues}
+	}
+	if s.store != nil {
+		config, err = s.store.Save(*config, 0)
+		if err != nil {
+			return fmt.Errorf("failed to seed relationship store: %w", err)
+		}
+	} else {
+		// A reload replaces the configuration like any other change.
+		config.Version = s.Status().Version + 1
+	}
+	s.setConfig(config, issues)
 	return nil
 }
 
+// Reload loads the relationships from the last path passed to
+// LoadRelationships again, or from the store if it holds a configuration.
+// On failure the previous configuration stays active and the error is
+// reported by Status until a reload succeeds. It does nothing if the
+// relationships were never loaded.
+func (s *RelationshipService) Reload() error {
+	s.mu.RLock()
+	path := s.path
+	s.mu.RUnlock()
+	if path == "" {
+		return nil
+	}
+	err := s.LoadRelationships(path)
+	s.metrics.RelationshipsReloaded(err)
+	if err != nil {
+		s.mu.Lock()
+		s.reloadErr = err
+		s.reloadFailedAt = time.Now()
+		s.mu.Unlock()
+	}
+	return err
+}
+
+// Status describes when the configuration was last loaded and whether
+// reloading it failed.
+func (s *RelationshipService) Status() domain.RelationshipStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	status := domain.RelationshipStatus{
+		Loaded:         s.config != nil,
+		UpdatedAt:      s.updatedAt,
+		ReloadFailedAt: s.reloadFailedAt,
+	}
+	if s.config != nil {
+		status.Version = s.config.Version
+	}
+	if s.reloadErr != nil {
+		status.ReloadError = s.reloadErr.Error()
+	}
+	return status
+}
+
 // GetRelationships returns the loaded service relationships.
 func (s *RelationshipService) GetRelationships() []domain.ServiceRelationship {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	if s.config == nil {
 		return nil
 	}
-	return s.config.Relationships
+	return s.config.Clone().Relationships
+}
+
+// GetConfig returns a snapshot of the relationship configuration.
+func (s *RelationshipService) GetConfig() domain.RelationshipConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.config == nil {
+		return domain.RelationshipConfig{}
+	}
+	return s.config.Clone()
+}
+
+// LoadIssues returns the lint issues of the configuration the last successful
+// load read. Unlike those of Lint, they carry the lines of the source file if
+// the configuration was read from one rather than from the store.
+func (s *RelationshipService) LoadIssues() []domain.LintIssue {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]domain.LintIssue(nil), s.loadIssues...)
+}
+
+// Lint checks the active configuration and returns every issue found. The
+// issues carry no lines: the active configuration may come from the store or
+// have been changed since it was loaded, so they would not match any file.
+func (s *RelationshipService) Lint() []domain.LintIssue {
+	config := s.GetConfig()
+	for i := range config.Relationships {
+		config.Relationships[i].Line = 0
+		config.Relationships[i].DependsOnLines = nil
+	}
+	return LintRelationships(&config)
+}
+
+// GetService returns the relationship entry for a single service and the
+// version of the configuration it was read from.
+func (s *RelationshipService) GetService(name string) (domain.ServiceRelationship, int64, error) {
+	config := s.GetConfig()
+	i := indexOfService(config.Relationships, name)
+	if i < 0 {
+		return domain.ServiceRelationship{}, 0, fmt.Errorf("%w: %q", domain.ErrServiceNotFound, name)
+	}
+	return config.Relationships[i], config.Version, nil
+}
+
+// PutService creates or replaces a service and its dependencies.
+func (s *RelationshipService) PutService(rel domain.ServiceRelationship, expectedVersion int64) (int64, error) {
+	if rel.Service == "" {
+		return 0, fmt.Errorf("%w: service name must not be empty", domain.ErrInvalidRelationship)
+	}
+	if rel.Criticality != "" && domain.CriticalityRank(rel.Criticality) == domain.CriticalityRank("") {
+		return 0, fmt.Errorf("%w: unknown criticality %q", domain.ErrInvalidRelationship, rel.Criticality)
+	}
+	for _, dep := range rel.DependsOn {
+		if err := validateEdge(rel.Service, dep); err != nil {
+			return 0, err
+		}
+	}
+	return s.update(expectedVersion, func(config *domain.RelationshipConfig) error {
+		rel.DependsOn = append([]string(nil), rel.DependsOn...)
+		if i := indexOfService(config.Relationships, rel.Service); i >= 0 {
+			config.Relationships[i] = rel
+		} else {
+			config.Relationships = append(config.Relationships, rel)
+		}
+		return nil
+	})
+}
+
+// DeleteService removes a service and every edge pointing to it.
+func (s *RelationshipService) DeleteService(name string, expectedVersion int64) (int64, error) {
+	return s.update(expectedVersion, func(config *domain.RelationshipConfig) error {
+		i := indexOfService(config.Relationships, name)
+		if i < 0 {
+			return fmt.Errorf("%w: %q", domain.ErrServiceNotFound, name)
+		}
+		config.Relationships = slices.Delete(config.Relationships, i, i+1)
+		for j := range config.Relationships {
+			config.Relationships[j].DependsOn = slices.DeleteFunc(config.Relationships[j].DependsOn, func(dep string) bool {
+				return dep == name
+			})
+		}
+		return nil
+	})
+}
+
+// AddEdge adds a dependency from one service to another.
+func (s *RelationshipService) AddEdge(from, to string, expectedVersion int64) (int64, error) {
+	if err := validateEdge(from, to); err != nil {
+		return 0, err
+	}
+	return s.update(expectedVersion, func(config *domain.RelationshipConfig) error {
+		i := indexOfService(config.Relationships, from)
+		if i < 0 {
+			return fmt.Errorf("%w: %q", domain.ErrServiceNotFound, from)
+		}
+		if !slices.Contains(config.Relationships[i].DependsOn, to) {
+			config.Relationships[i].DependsOn = append(config.Relationships[i].DependsOn, to)
+		}
+		return nil
+	})
+}
+
+// RemoveEdge removes a dependency from one service to another.
+func (s *RelationshipService) RemoveEdge(from, to string, expectedVersion int64) (int64, error) {
+	return s.update(expectedVersion, func(config *domain.RelationshipConfig) error {
+		i := indexOfService(config.Relationships, from)
+		if i < 0 {
+			return fmt.Errorf("%w: %q", domain.ErrServiceNotFound, from)
+		}
+		j := slices.Index(config.Relationships[i].DependsOn, to)
+		if j < 0 {
+			return fmt.Errorf("%w: %q -> %q", domain.ErrEdgeNotFound, from, to)
+		}
+		config.Relationships[i].DependsOn = slices.Delete(config.Relationships[i].DependsOn, j, j+1)
+		return nil
+	})
 }
 
 // GetMangleFacts transforms the loaded service relationships into Mangle facts.
 func (s *RelationshipService) GetMangleFacts() ([]domain.Fact, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	if s.config == nil {
 		return nil, fmt.Errorf("relationships not loaded")
 	}
@@ -75,3 +301,62 @@ func (s *RelationshipService) createCallsFact(service, dependency string) (domai
 		ast.String(dependency),
 	), nil
 }
+
+func (s *RelationshipService) setConfig(config *domain.RelationshipConfig, issues []domain.LintIssue) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config = config
+	s.loadIssues = issues
+	s.updatedAt = time.Now()
+	s.reloadErr = nil
+	s.reloadFailedAt = time.Time{}
+}
+
+// update applies mutate to a copy of the current configuration and, if the
+// expected version matches, persists and activates the result.
+func (s *RelationshipService) update(expectedVersion int64, mutate func(*domain.RelationshipConfig) error) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.config == nil {
+		return 0, fmt.Errorf("relationships not loaded")
+	}
+	current := s.config.Version
+	if expectedVersion != domain.AnyVersion && expectedVersion != current {
+		return 0, fmt.Errorf("%w: expected version %d, current version is %d", domain.ErrVersionConflict, expectedVersion, current)
+	}
+
+	next := s.config.Clone()
+	if err := mutate(&next); err != nil {
+		return 0, err
+	}
+
+	if s.store == nil {
+		next.Version = current + 1
+		s.config = &next
+		s.updatedAt = time.Now()
+		return next.Version, nil
+	}
+	saved, err := s.store.Save(next, current)
+	if err != nil {
+		return 0, err
+	}
+	s.config = saved
+	s.updatedAt = time.Now()
+	return saved.Version, nil
+}
+
+func validateEdge(from, to string) error {
+	if from == "" || to == "" {
+		return fmt.Errorf("%w: service names must not be empty", domain.ErrInvalidRelationship)
+	}
+	if from == to {
+		return fmt.Errorf("%w: service %q cannot depend on itself", domain.ErrInvalidRelationship, from)
+	}
+	return nil
+}
+
+func indexOfService(rels []domain.ServiceRelationship, name string) int {
+	return slices.IndexFunc(rels, func(rel domain.ServiceRelationship) bool {
+		return rel.Service == name
+	})
+}
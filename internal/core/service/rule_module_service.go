@@ -0,0 +1,261 @@
+package service
+
+import (
+	"fmt"
+	"mangle-service/internal/core/domain"
+	"mangle-service/internal/core/ports"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/mangle/analysis"
+	"github.com/google/mangle/ast"
+	"github.com/google/mangle/packages"
+	"github.com/google/mangle/parse"
+	"github.com/google/mangle/symbols"
+)
+
+var _ ports.RuleModuleService = (*RuleModuleService)(nil)
+
+var moduleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*(\.[a-z][a-z0-9_]*)*$`)
+
+// RuleModuleService manages shared Mangle rule modules. Every module lives in
+// its own namespace: a predicate p defined in module m is available to queries
+// as m.p. The namespace is taken from the module's Package declaration, or
+// from its name if it has none.
+type RuleModuleService struct {
+	loader ports.RuleModuleLoaderPort
+
+	mu      sync.RWMutex
+	path    string
+	modules domain.RuleModuleSet
+	rules   domain.RuleSet
+}
+
+// NewRuleModuleService creates a new RuleModuleService.
+func NewRuleModuleService(loader ports.RuleModuleLoaderPort) *RuleModuleService {
+	return &RuleModuleService{loader: loader}
+}
+
+// LoadModules parses and analyzes every module at path and activates them.
+// Nothing is activated if any module fails to load.
+func (s *RuleModuleService) LoadModules(path string) error {
+	sources, err := s.loader.LoadModules(path)
+	if err != nil {
+		return fmt.Errorf("failed to read rule modules: %w", err)
+	}
+	modules, rules, err := compileRuleModules(sources)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.path = path
+	s.modules = domain.RuleModuleSet{Modules: modules, LoadedAt: time.Now()}
+	s.rules = rules
+	return nil
+}
+
+// Reload loads the modules from the last path passed to LoadModules again.
+// It does nothing if no modules were loaded before.
+func (s *RuleModuleService) Reload() error {
+	s.mu.RLock()
+	path := s.path
+	s.mu.RUnlock()
+	if path == "" {
+		return nil
+	}
+	return s.LoadModules(path)
+}
+
+// GetModules describes the active modules.
+func (s *RuleModuleService) GetModules() domain.RuleModuleSet {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	set := s.modules
+	set.Modules = append([]domain.RuleModule{}, s.modules.Modules...)
+	return set
+}
+
+// GetMangleRules returns the clauses and declarations of every active module.
+// The result is a copy that callers may hand to the Mangle analyzer, which
+// rewrites premises in place.
+func (s *RuleModuleService) GetMangleRules() domain.RuleSet {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	clauses := make([]ast.Clause, len(s.rules.Clauses))
+	for i, clause := range s.rules.Clauses {
+		clause.Premises = append([]ast.Term(nil), clause.Premises...)
+		clauses[i] = clause
+	}
+	return domain.RuleSet{
+		Clauses: clauses,
+		Decls:   append([]ast.Decl(nil), s.rules.Decls...),
+	}
+}
+
+type parsedModule struct {
+	name   string
+	source domain.RuleModuleSource
+	unit   parse.SourceUnit
+}
+
+// compileRuleModules parses every module, rewrites its predicates into the
+// module namespace and analyzes the result as a whole.
+func compileRuleModules(sources []domain.RuleModuleSource) ([]domain.RuleModule, domain.RuleSet, error) {
+	var parsed []parsedModule
+	paths := make(map[string]string)
+	for _, source := range sources {
+		unit, err := parse.Unit(strings.NewReader(source.Source))
+		if err != nil {
+			return nil, domain.RuleSet{}, fmt.Errorf("%s: %w", source.Path, err)
+		}
+		pkg, err := packages.Extract(unit)
+		if err != nil {
+			return nil, domain.RuleSet{}, fmt.Errorf("%s: %w", source.Path, err)
+		}
+		name := pkg.Name
+		if name == "" {
+			name = strings.NewReplacer("-", "_", " ", "_").Replace(strings.ToLower(source.Name))
+		}
+		if !moduleNamePattern.MatchString(name) {
+			return nil, domain.RuleSet{}, fmt.Errorf("%s: invalid module name %q; rename the file or add a Package declaration", source.Path, name)
+		}
+		if other, ok := paths[name]; ok {
+			return nil, domain.RuleSet{}, fmt.Errorf("%s: module %q is already defined in %s", source.Path, name, other)
+		}
+		paths[name] = source.Path
+		parsed = append(parsed, parsedModule{name: name, source: source, unit: unit})
+	}
+
+	var modules []domain.RuleModule
+	var rules domain.RuleSet
+	for _, m := range parsed {
+		// Predicates with a dotted name that do not belong to a module, such
+		// as log.field, are provided by the log sources. Mangle would require
+		// a Use declaration for them, so one is added implicitly.
+		unit := m.unit
+		for _, prefix := range externalPackages(unit, m.name, paths) {
+			unit.Decls = append(unit.Decls, ast.Decl{
+				DeclaredAtom: ast.NewAtom(symbols.Use.Symbol),
+				Descr:        []ast.Atom{ast.NewAtom("name", ast.String(prefix))},
+			})
+		}
+		pkg, err := packages.Extract(unit)
+		if err != nil {
+			return nil, domain.RuleSet{}, fmt.Errorf("%s: %w", m.source.Path, err)
+		}
+		pkg.Name = m.name
+
+		clauses, err := pkg.Clauses()
+		if err != nil {
+			return nil, domain.RuleSet{}, fmt.Errorf("%s: %w", m.source.Path, err)
+		}
+		decls, err := pkg.Decls()
+		if err != nil {
+			return nil, domain.RuleSet{}, fmt.Errorf("%s: %w", m.source.Path, err)
+		}
+		rules.Clauses = append(rules.Clauses, clauses...)
+		rules.Decls = append(rules.Decls, decls...)
+		modules = append(modules, domain.RuleModule{
+			Name:       m.name,
+			Path:       m.source.Path,
+			Predicates: definedPredicates(clauses, decls),
+		})
+	}
+
+	if _, err := analysis.AnalyzeOneUnit(rules, externalPredicates(rules)); err != nil {
+		return nil, domain.RuleSet{}, fmt.Errorf("failed to analyze rule modules: %w", err)
+	}
+	return modules, rules, nil
+}
+
+// externalPackages returns the package prefixes of dotted premise predicates
+// that are neither defined by the module itself nor by another module.
+func externalPackages(unit parse.SourceUnit, name string, modules map[string]string) []string {
+	defined := make(map[ast.PredicateSym]bool)
+	for _, clause := range unit.Clauses {
+		defined[clause.Head.Predicate] = true
+	}
+	seen := make(map[string]bool)
+	var prefixes []string
+	for _, sym := range premisePredicates(unit.Clauses) {
+		i := strings.LastIndex(sym.Symbol, ".")
+		if i < 0 || defined[sym] {
+			continue
+		}
+		prefix := sym.Symbol[:i]
+		if _, ok := modules[prefix]; ok || prefix == name || seen[prefix] {
+			continue
+		}
+		seen[prefix] = true
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes
+}
+
+// externalPredicates declares every predicate that the modules use but do
+// not define, such as depends_on or the log facts. Those are only known when
+// a query runs, so the analyzer treats them as extensional.
+func externalPredicates(rules domain.RuleSet) map[ast.PredicateSym]ast.Decl {
+	defined := make(map[ast.PredicateSym]bool)
+	for _, clause := range rules.Clauses {
+		defined[clause.Head.Predicate] = true
+	}
+	for _, decl := range rules.Decls {
+		defined[decl.DeclaredAtom.Predicate] = true
+	}
+	extra := make(map[ast.PredicateSym]ast.Decl)
+	for _, sym := range premisePredicates(rules.Clauses) {
+		if !defined[sym] {
+			extra[sym] = ast.NewSyntheticDeclFromSym(sym)
+		}
+	}
+	return extra
+}
+
+// premisePredicates returns the non-builtin predicates used in rule premises.
+func premisePredicates(clauses []ast.Clause) []ast.PredicateSym {
+	var syms []ast.PredicateSym
+	for _, clause := range clauses {
+		for _, premise := range clause.Premises {
+			var sym ast.PredicateSym
+			switch p := premise.(type) {
+			case ast.Atom:
+				sym = p.Predicate
+			case ast.NegAtom:
+				sym = p.Atom.Predicate
+			default:
+				continue
+			}
+			if !sym.IsBuiltin() {
+				syms = append(syms, sym)
+			}
+		}
+	}
+	return syms
+}
+
+// definedPredicates lists the predicates defined by clauses and decls as name/arity.
+func definedPredicates(clauses []ast.Clause, decls []ast.Decl) []string {
+	seen := make(map[string]bool)
+	predicates := []string{}
+	add := func(sym ast.PredicateSym) {
+		key := fmt.Sprintf("%s/%d", sym.Symbol, sym.Arity)
+		if !seen[key] {
+			seen[key] = true
+			predicates = append(predicates, key)
+		}
+	}
+	for _, clause := range clauses {
+		add(clause.Head.Predicate)
+	}
+	for _, decl := range decls {
+		add(decl.DeclaredAtom.Predicate)
+	}
+	sort.Strings(predicates)
+	return predicates
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"mangle-service/internal/core/domain"
+	"mangle-service/internal/core/ports"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation scope of the query service's spans.
+const tracerName = "mangle-service/internal/core/service"
+
+// WithTracerProvider traces every query, its phases and each log source
+// call with spans from tp. The global provider is used otherwise.
+func WithTracerProvider(tp trace.TracerProvider) QueryOption {
+	return func(s *queryService) {
+		s.tracer = tp.Tracer(tracerName)
+	}
+}
+
+// queryPhase is a running phase of a query, traced as a span, timed in
+// metrics and recorded in the execution's profile.
+type queryPhase struct {
+	span    trace.Span
+	metrics ports.QueryMetrics
+	exec    *execution
+	name    string
+	phase   domain.QueryPhase
+	start   time.Time
+}
+
+// startPhase starts a phase of the named query. The returned context
+// carries the phase's span.
+func (s *queryService) startPhase(ctx context.Context, exec *execution, name string, phase domain.QueryPhase) (context.Context, *queryPhase) {
+	ctx, span := s.tracer.Start(ctx, "mangle.query."+string(phase))
+	return ctx, &queryPhase{span: span, metrics: s.metrics, exec: exec, name: name, phase: phase, start: time.Now()}
+}
+
+// end ends the phase with the outcome err. Only phases that succeed are
+// timed in metrics, so that their durations are comparable.
+func (p *queryPhase) end(err error, attrs ...attribute.KeyValue) {
+	duration := time.Since(p.start)
+	p.exec.profilePhase(p.phase, duration)
+	p.span.SetAttributes(attrs...)
+	if err != nil {
+		recordSpanError(p.span, err)
+	} else {
+		p.metrics.ObservePhase(p.name, p.phase, duration)
+	}
+	p.span.End()
+}
+
+// recordSpanError marks span as failed, with the error code of query errors.
+func recordSpanError(span trace.Span, err error) {
+	var qerr *domain.QueryError
+	if errors.As(err, &qerr) {
+		span.SetAttributes(attribute.String("mangle.error.code", string(qerr.Code)))
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,354 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mangle-service/internal/core/domain"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Request and response types of the API.
+type (
+	QueryRequest        = domain.QueryRequest
+	TimeRange           = domain.TimeRange
+	QueryResult         = domain.QueryResult
+	QuerySummary        = domain.QuerySummary
+	LogEntry            = domain.LogEntry
+	ImpactRequest       = domain.ImpactRequest
+	ImpactResult        = domain.ImpactResult
+	ImpactedService     = domain.ImpactedService
+	Graph               = domain.Graph
+	RelationshipConfig  = domain.RelationshipConfig
+	ServiceRelationship = domain.ServiceRelationship
+	RuleModuleSet       = domain.RuleModuleSet
+	RuleModule          = domain.RuleModule
+	Job                 = domain.Job
+	JobStatus           = domain.JobStatus
+	JobProgress         = domain.JobProgress
+	JobError            = domain.JobError
+	PredicateCatalog    = domain.PredicateCatalog
+	PredicateInfo       = domain.PredicateInfo
+	HealthReport        = domain.HealthReport
+	HealthCheck         = domain.HealthCheck
+	HealthStatus        = domain.HealthStatus
+	AuditRecord         = domain.AuditRecord
+	AuditFilter         = domain.AuditFilter
+	AuditSearchResult   = domain.AuditSearchResult
+	AuditOutcome        = domain.AuditOutcome
+	LogLevel            = domain.LogLevel
+	ProgramInfo         = domain.ProgramInfo
+	PredicateSignature  = domain.PredicateSignature
+	SourceState         = domain.SourceState
+	RelationshipState   = domain.RelationshipState
+)
+
+// Audited query outcomes.
+const (
+	AuditSucceeded = domain.AuditSucceeded
+	AuditFailed    = domain.AuditFailed
+)
+
+// Health check outcomes.
+const (
+	HealthPass = domain.HealthPass
+	HealthWarn = domain.HealthWarn
+	HealthFail = domain.HealthFail
+)
+
+// Job states.
+const (
+	JobQueued    = domain.JobQueued
+	JobRunning   = domain.JobRunning
+	JobSucceeded = domain.JobSucceeded
+	JobFailed    = domain.JobFailed
+	JobCanceled  = domain.JobCanceled
+)
+
+// Query runs a query and returns every result.
+func (c *Client) Query(ctx context.Context, req QueryRequest) (*QueryResult, error) {
+	var result QueryResult
+	if err := c.getJSON(ctx, request{method: http.MethodPost, path: "/query", body: req, readOnly: true}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// StreamQuery runs a query and passes each result to fn as the service
+// finds it, so that large results need not be held in memory. If fn returns
+// an error, the query is abandoned and that error is returned.
+func (c *Client) StreamQuery(ctx context.Context, req QueryRequest, fn func(LogEntry) error) (*QuerySummary, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	resp, err := c.do(ctx, request{
+		method:   http.MethodPost,
+		path:     "/query",
+		query:    url.Values{"format": {"ndjson"}},
+		body:     req,
+		accept:   "application/x-ndjson",
+		readOnly: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64<<10), 16<<20)
+	for scanner.Scan() {
+		var record struct {
+			Result  LogEntry      `json:"result"`
+			Summary *QuerySummary `json:"summary"`
+			Error   *struct {
+				Code      string `json:"code"`
+				Message   string `json:"message"`
+				RequestID string `json:"request_id"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("failed to decode stream record: %w", err)
+		}
+		switch {
+		case record.Summary != nil:
+			return record.Summary, nil
+		case record.Error != nil:
+			return nil, &Error{
+				StatusCode: resp.StatusCode,
+				Code:       record.Error.Code,
+				Message:    record.Error.Message,
+				RequestID:  record.Error.RequestID,
+			}
+		case record.Result != nil:
+			if err := fn(record.Result); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read stream: %w", err)
+	}
+	return nil, errors.New("query stream ended without a summary")
+}
+
+// SubmitJob runs a query in the background and returns the queued job. Use
+// it for queries that may run longer than proxies between the client and the
+// service wait for a response.
+func (c *Client) SubmitJob(ctx context.Context, req QueryRequest) (*Job, error) {
+	var job Job
+	if err := c.getJSON(ctx, request{method: http.MethodPost, path: "/jobs", body: req}, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Job returns a job, with its result once it has succeeded.
+func (c *Client) Job(ctx context.Context, id string) (*Job, error) {
+	var job Job
+	if err := c.getJSON(ctx, request{method: http.MethodGet, path: "/jobs/" + id}, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// CancelJob cancels a job that has not finished, or discards a finished job
+// and its result.
+func (c *Client) CancelJob(ctx context.Context, id string) (*Job, error) {
+	var job Job
+	if err := c.getJSON(ctx, request{method: http.MethodDelete, path: "/jobs/" + id}, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// WaitJob polls a job every interval until it has finished and returns it
+// in its final state, whether it succeeded or not.
+func (c *Client) WaitJob(ctx context.Context, id string, interval time.Duration) (*Job, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		job, err := c.Job(ctx, id)
+		if err != nil || job.Status.Done() {
+			return job, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// AnalyzeImpact lists the services affected when req.Service fails.
+func (c *Client) AnalyzeImpact(ctx context.Context, req ImpactRequest) (*ImpactResult, error) {
+	var result ImpactResult
+	if err := c.getJSON(ctx, request{method: http.MethodPost, path: "/impact", body: req, readOnly: true}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Graph returns the relationship graph. If highlightQuery is not empty, the
+// services and edges in its results are highlighted.
+func (c *Client) Graph(ctx context.Context, highlightQuery string) (*Graph, error) {
+	query := url.Values{"format": {"json"}}
+	if highlightQuery != "" {
+		query.Set("query", highlightQuery)
+	}
+	var graph Graph
+	if err := c.getJSON(ctx, request{method: http.MethodGet, path: "/graph", query: query}, &graph); err != nil {
+		return nil, err
+	}
+	return &graph, nil
+}
+
+// Relationships returns every service and its dependencies.
+func (c *Client) Relationships(ctx context.Context) (*RelationshipConfig, error) {
+	var config RelationshipConfig
+	if err := c.getJSON(ctx, request{method: http.MethodGet, path: "/relationships"}, &config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// Service returns a single service and its dependencies.
+func (c *Client) Service(ctx context.Context, name string) (*ServiceRelationship, error) {
+	var rel ServiceRelationship
+	if err := c.getJSON(ctx, request{method: http.MethodGet, path: "/relationships/" + name}, &rel); err != nil {
+		return nil, err
+	}
+	return &rel, nil
+}
+
+// Predicates lists the predicates and log sources the caller may use.
+func (c *Client) Predicates(ctx context.Context) (*PredicateCatalog, error) {
+	var catalog PredicateCatalog
+	if err := c.getJSON(ctx, request{method: http.MethodGet, path: "/predicates"}, &catalog); err != nil {
+		return nil, err
+	}
+	return &catalog, nil
+}
+
+// RuleModules lists the loaded rule modules and their predicates.
+func (c *Client) RuleModules(ctx context.Context) (*RuleModuleSet, error) {
+	var modules RuleModuleSet
+	if err := c.getJSON(ctx, request{method: http.MethodGet, path: "/rules"}, &modules); err != nil {
+		return nil, err
+	}
+	return &modules, nil
+}
+
+// Health returns nil if the service is up.
+func (c *Client) Health(ctx context.Context) error {
+	resp, err := c.do(ctx, request{method: http.MethodGet, path: "/healthz"})
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// HealthDetails returns the result of every health check of the service. The
+// report is returned whether or not the service is ready; see its Ready method.
+func (c *Client) HealthDetails(ctx context.Context) (*HealthReport, error) {
+	var report HealthReport
+	req := request{method: http.MethodGet, path: "/health/details", okStatuses: []int{http.StatusServiceUnavailable}}
+	if err := c.getJSON(ctx, req, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// SearchAudit returns the audit records of past query executions that the
+// filter selects, newest first. Callers without an audit reader role only
+// see their own queries.
+func (c *Client) SearchAudit(ctx context.Context, filter AuditFilter) (*AuditSearchResult, error) {
+	query := url.Values{}
+	for key, value := range map[string]string{
+		"subject":    filter.Subject,
+		"name":       filter.Name,
+		"query_hash": filter.QueryHash,
+		"source":     filter.Source,
+		"outcome":    string(filter.Outcome),
+	} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	if !filter.Since.IsZero() {
+		query.Set("since", filter.Since.Format(time.RFC3339Nano))
+	}
+	if !filter.Until.IsZero() {
+		query.Set("until", filter.Until.Format(time.RFC3339Nano))
+	}
+	if filter.Limit > 0 {
+		query.Set("limit", strconv.Itoa(filter.Limit))
+	}
+	var result AuditSearchResult
+	if err := c.getJSON(ctx, request{method: http.MethodGet, path: "/audit", query: query}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// LogLevel returns the level below which the service drops log lines. It
+// requires one of the admin roles.
+func (c *Client) LogLevel(ctx context.Context) (string, error) {
+	var level LogLevel
+	if err := c.getJSON(ctx, request{method: http.MethodGet, path: "/admin/log-level"}, &level); err != nil {
+		return "", err
+	}
+	return level.Level, nil
+}
+
+// SetLogLevel changes the log level of the service until it restarts. It
+// requires one of the admin roles.
+func (c *Client) SetLogLevel(ctx context.Context, level string) error {
+	var result LogLevel
+	return c.getJSON(ctx, request{method: http.MethodPut, path: "/admin/log-level", body: LogLevel{Level: level}}, &result)
+}
+
+// Program returns the rules and predicate signatures every query starts
+// from. It requires one of the admin roles.
+func (c *Client) Program(ctx context.Context) (*ProgramInfo, error) {
+	var program ProgramInfo
+	if err := c.getJSON(ctx, request{method: http.MethodGet, path: "/admin/program"}, &program); err != nil {
+		return nil, err
+	}
+	return &program, nil
+}
+
+// Sources lists the configured log sources and the outcome of their health
+// checks, the default source first. It requires one of the admin roles.
+func (c *Client) Sources(ctx context.Context) ([]SourceState, error) {
+	var sources []SourceState
+	if err := c.getJSON(ctx, request{method: http.MethodGet, path: "/admin/sources"}, &sources); err != nil {
+		return nil, err
+	}
+	return sources, nil
+}
+
+// RelationshipState returns the active relationship configuration and
+// whether its last reload failed. It requires one of the admin roles.
+func (c *Client) RelationshipState(ctx context.Context) (*RelationshipState, error) {
+	var state RelationshipState
+	if err := c.getJSON(ctx, request{method: http.MethodGet, path: "/admin/relationships"}, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// EffectiveConfig returns the configuration the service runs with, keyed as
+// in the configuration file, with secrets redacted. It requires one of the
+// admin roles.
+func (c *Client) EffectiveConfig(ctx context.Context) (map[string]any, error) {
+	var config map[string]any
+	if err := c.getJSON(ctx, request{method: http.MethodGet, path: "/admin/config"}, &config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
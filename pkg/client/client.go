@@ -0,0 +1,321 @@
+// Package client is a Go client for the mangle-service HTTP API.
+//
+// Request and response types are those of the service itself, so queries can
+// be built and results read without hand-written JSON:
+//
+//	c, err := client.New("http://mangle-service:8080", client.WithAPIKey(key))
+//	result, err := c.Query(ctx, client.QueryRequest{Query: `failed(S) :- logs(_, S, 500, _).
+//	failed(S).`})
+//
+// Failed requests return an *Error carrying the service's error code.
+// Requests rejected because of load, and those that fail with a network
+// error, are retried with exponential backoff, honouring Retry-After.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Defaults for the retry behaviour; see WithRetries.
+const (
+	DefaultMaxRetries = 2
+	DefaultBackoff    = 200 * time.Millisecond
+	// maxBackoff caps the exponential backoff between attempts.
+	maxBackoff = 5 * time.Second
+	// maxRetryAfter is the longest Retry-After the client waits for; longer
+	// waits are left to the caller.
+	maxRetryAfter = 30 * time.Second
+)
+
+// Client calls a mangle-service instance. It is safe for concurrent use.
+type Client struct {
+	baseURL     *url.URL
+	httpClient  *http.Client
+	apiKey      string
+	bearerToken string
+	userAgent   string
+	maxRetries  int
+	backoff     time.Duration
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client used for requests, for example to
+// configure TLS or a timeout. http.DefaultClient is used otherwise.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithTLSConfig connects with config, for example to trust a private CA or
+// to present a client certificate. It replaces the HTTP client set by
+// WithHTTPClient.
+func WithTLSConfig(config *tls.Config) Option {
+	return func(c *Client) {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = config
+		c.httpClient = &http.Client{Transport: transport}
+	}
+}
+
+// WithAPIKey authenticates every request with an API key.
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// WithBearerToken authenticates every request with a bearer token, such as a JWT.
+func WithBearerToken(token string) Option {
+	return func(c *Client) {
+		c.bearerToken = token
+	}
+}
+
+// WithUserAgent sets the User-Agent header of every request.
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithRetries sets how often a failed request is retried and the initial
+// backoff between attempts, which doubles after each one. Zero maxRetries
+// disables retries. Requests that create something, such as SubmitJob, are
+// only retried when the service answers 429 or 503.
+func WithRetries(maxRetries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.backoff = backoff
+	}
+}
+
+// New creates a Client for the service at baseURL, such as "http://localhost:8080".
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid base URL %q: scheme must be http or https", baseURL)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	c := &Client{
+		baseURL:    u,
+		httpClient: http.DefaultClient,
+		userAgent:  "mangle-service-go-client",
+		maxRetries: DefaultMaxRetries,
+		backoff:    DefaultBackoff,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// Error is returned when the service answers with an error status.
+type Error struct {
+	StatusCode int
+	// Code is the machine-readable error code, such as "parse_error".
+	Code      string
+	Message   string
+	Details   map[string]any
+	RequestID string
+	// RetryAfter is how long the service asked the client to wait, if it did.
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("mangle-service: %s (status %d", e.Message, e.StatusCode)
+	if e.Code != "" {
+		msg += ", code " + e.Code
+	}
+	if e.RequestID != "" {
+		msg += ", request " + e.RequestID
+	}
+	return msg + ")"
+}
+
+// Temporary reports whether the request may succeed if it is sent again later.
+func (e *Error) Temporary() bool {
+	switch e.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// request describes a single API call.
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	body   any
+	accept string
+	// okStatuses are error statuses whose response is returned rather than
+	// turned into an *Error, because their body is a regular response.
+	okStatuses []int
+	// readOnly marks POST requests that change nothing, such as queries, so
+	// that they are retried like GET requests.
+	readOnly bool
+}
+
+// idempotent reports whether sending req twice has the effect of sending it
+// once. Other requests are only retried if the service refused them, since a
+// failed connection or a gateway error leaves open whether they took effect.
+func (r request) idempotent() bool {
+	return r.method != http.MethodPost || r.readOnly
+}
+
+// do sends req, retrying temporary failures, and returns the successful
+// response. The caller must close its body.
+func (c *Client) do(ctx context.Context, req request) (*http.Response, error) {
+	var body []byte
+	if req.body != nil {
+		var err error
+		if body, err = json.Marshal(req.body); err != nil {
+			return nil, fmt.Errorf("failed to encode request: %w", err)
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, req, body)
+		if err == nil {
+			return resp, nil
+		}
+		if attempt >= c.maxRetries || ctx.Err() != nil {
+			return nil, err
+		}
+
+		wait := c.backoff << attempt
+		if wait <= 0 || wait > maxBackoff {
+			wait = maxBackoff
+		}
+		wait = wait/2 + rand.N(wait/2+1)
+		var apiErr *Error
+		if errors.As(err, &apiErr) {
+			if !apiErr.Temporary() || apiErr.RetryAfter > maxRetryAfter {
+				return nil, err
+			}
+			refused := apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode == http.StatusServiceUnavailable
+			if !refused && !req.idempotent() {
+				return nil, err
+			}
+			if apiErr.RetryAfter > 0 {
+				wait = apiErr.RetryAfter
+			}
+		} else if !req.idempotent() {
+			return nil, err
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		case <-timer.C:
+		}
+	}
+}
+
+// send makes a single attempt at req. Error statuses are returned as *Error.
+func (c *Client) send(ctx context.Context, req request, body []byte) (*http.Response, error) {
+	u := *c.baseURL
+	u.Path += req.path
+	u.RawQuery = req.query.Encode()
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, u.String(), reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	accept := req.accept
+	if accept == "" {
+		accept = "application/json"
+	}
+	httpReq.Header.Set("Accept", accept)
+	httpReq.Header.Set("User-Agent", c.userAgent)
+	if c.apiKey != "" {
+		httpReq.Header.Set("X-API-Key", c.apiKey)
+	}
+	if c.bearerToken != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.bearerToken)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 || slices.Contains(req.okStatuses, resp.StatusCode) {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	return nil, decodeError(resp)
+}
+
+// decodeError reads the error response of the service. Bodies that are not
+// service errors, such as those of a proxy, become the message.
+func decodeError(resp *http.Response) *Error {
+	apiErr := &Error{
+		StatusCode: resp.StatusCode,
+		RequestID:  resp.Header.Get("X-Request-ID"),
+	}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	var body struct {
+		Code      string         `json:"code"`
+		Message   string         `json:"message"`
+		Details   map[string]any `json:"details"`
+		RequestID string         `json:"request_id"`
+	}
+	if err := json.Unmarshal(data, &body); err == nil && body.Message != "" {
+		apiErr.Code = body.Code
+		apiErr.Message = body.Message
+		apiErr.Details = body.Details
+		if body.RequestID != "" {
+			apiErr.RequestID = body.RequestID
+		}
+		return apiErr
+	}
+	apiErr.Message = strings.TrimSpace(string(data))
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+	return apiErr
+}
+
+// getJSON sends req and decodes the JSON response into v.
+func (c *Client) getJSON(ctx context.Context, req request, v any) error {
+	resp, err := c.do(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
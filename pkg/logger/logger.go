@@ -1,13 +1,103 @@
 package logger
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"slices"
 )
 
-// New creates a new slog logger.
-func New(level slog.Level) *slog.Logger {
-	return slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-		Level: level,
-	}))
+// Log line formats.
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+type options struct {
+	format string
+	output io.Writer
+}
+
+// Option configures a logger created by New.
+type Option func(*options)
+
+// WithFormat writes log lines in format, FormatJSON by default.
+func WithFormat(format string) Option {
+	return func(o *options) {
+		o.format = format
+	}
+}
+
+// WithOutput writes log lines to w, os.Stdout by default.
+func WithOutput(w io.Writer) Option {
+	return func(o *options) {
+		o.output = w
+	}
+}
+
+// New creates a new slog logger that drops records below level. Pass a
+// *slog.LevelVar to change the level at runtime. Attributes added to a
+// context with ContextWithAttrs are included in every record logged with
+// that context.
+func New(level slog.Leveler, opts ...Option) *slog.Logger {
+	o := options{format: FormatJSON, output: os.Stdout}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	handlerOpts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	if o.format == FormatText {
+		handler = slog.NewTextHandler(o.output, handlerOpts)
+	} else {
+		handler = slog.NewJSONHandler(o.output, handlerOpts)
+	}
+	return slog.New(contextHandler{handler})
+}
+
+// ParseLevel parses a level name such as "debug" or "warn".
+func ParseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return 0, fmt.Errorf("invalid log level %q", name)
+	}
+	return level, nil
+}
+
+type attrsKey struct{}
+
+// ContextWithAttrs returns a copy of ctx whose log records carry attrs in
+// addition to those already added to ctx. An attribute replaces an earlier
+// one with the same key.
+func ContextWithAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	existing, _ := ctx.Value(attrsKey{}).([]slog.Attr)
+	combined := make([]slog.Attr, 0, len(existing)+len(attrs))
+	for _, attr := range existing {
+		if !slices.ContainsFunc(attrs, func(a slog.Attr) bool { return a.Key == attr.Key }) {
+			combined = append(combined, attr)
+		}
+	}
+	return context.WithValue(ctx, attrsKey{}, append(combined, attrs...))
+}
+
+// contextHandler adds the attributes of the record's context to the record.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if attrs, ok := ctx.Value(attrsKey{}).([]slog.Attr); ok {
+		r = r.Clone()
+		r.AddAttrs(attrs...)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
 }